@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"oomterminator/pkg/terminator"
+)
+
+// explain prints how the pod of the argument, like pod/api-7d9f, is
+// evaluated on each cluster.
+func explain(ctx *cli.Context) error {
+	name := strings.TrimPrefix(ctx.Args().First(), "pod/")
+	if name == "" || ctx.NArg() != 1 {
+		return fmt.Errorf("explain needs a pod, like pod/<name>")
+	}
+	namespace := ctx.String("namespace")
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	printer, err := newPrinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	options := terminator.Options{StateConfigMap: ctx.String("state-configmap")}
+	if err := decisionOptions(ctx, &options); err != nil {
+		return err
+	}
+
+	terminators, err := setup(ctx, options)
+	if err != nil {
+		return err
+	}
+
+	targets, err := targetsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	for cluster, t := range terminators {
+		explanation, err := t.Explain(ctx.Context, targets, namespace, name, ctx.Int("limit"), ctx.Int("kill-after"))
+		if err != nil {
+			return err
+		}
+		if len(terminators) > 1 && printer.format == outputTable {
+			fmt.Printf("[%s]\n", cluster)
+		}
+		if err := printer.print(explanation, nil, explanationRows(ctx, explanation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// explanationRows are the fields of explanation, with the units of the
+// output.
+func explanationRows(ctx *cli.Context, explanation *terminator.Explanation) [][]string {
+	units := ctx.String("output-units")
+	bytes := func(value *int64) string {
+		if value == nil {
+			return "none"
+		}
+		return terminator.FormatBytes(units, *value)
+	}
+
+	targets := "none"
+	if len(explanation.Targets) > 0 {
+		targets = strings.Join(explanation.Targets, ", ")
+	}
+
+	containers := make([]string, 0, len(explanation.Containers))
+	for _, container := range explanation.Containers {
+		limit := "no limit"
+		if container.Limit != nil {
+			limit = "limit " + bytes(container.Limit)
+		}
+		containers = append(containers, fmt.Sprintf("%s (%s)", container.Name, limit))
+	}
+
+	usage := "unknown"
+	if explanation.Usage != nil {
+		state := "under"
+		if explanation.OverLimit {
+			state = "over"
+		}
+		usage = fmt.Sprintf("%s/%s = %s, %s the limit", bytes(explanation.Usage), bytes(explanation.Limit), terminator.FormatPercent(ctx.Int("percentage-precision"), explanation.Percentage), state)
+	}
+
+	over := "no"
+	if explanation.OverSince != nil {
+		over = fmt.Sprintf("for %d checks since %s", explanation.Counter, explanation.OverSince.Format(time.RFC3339))
+	}
+
+	rows := [][]string{
+		{"Pod:", fmt.Sprintf("%s/%s is %s on node %s", explanation.Namespace, explanation.Pod, explanation.Phase, explanation.Node)},
+		{"Workload:", explanation.Workload},
+		{"Targets:", targets},
+		{"Policy:", explanation.Policy},
+		{"Containers counted:", strings.Join(containers, ", ")},
+		{"Usage:", usage},
+		{"Over the limit:", over},
+	}
+	for i, note := range explanation.Notes {
+		field := ""
+		if i == 0 {
+			field = "Notes:"
+		}
+		rows = append(rows, []string{field, note})
+	}
+	for i, protection := range explanation.Protections {
+		field := ""
+		if i == 0 {
+			field = "Protections:"
+		}
+		rows = append(rows, []string{field, protection})
+	}
+	return rows
+}
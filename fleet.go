@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"oomterminator/pkg/terminator"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	fleetMinBackoff = 5 * time.Second
+	fleetMaxBackoff = 5 * time.Minute
+)
+
+// Fleet is the inventory of clusters checked by a single terminator.
+type Fleet struct {
+	Clusters []FleetCluster `json:"clusters"`
+}
+
+// FleetCluster is a cluster of a fleet, how to reach it and its policy. Unset
+// fields of the policy fall back to the flags.
+type FleetCluster struct {
+	Name string `json:"name"`
+	// Kubeconfig is the path of the kube config file with the credentials of
+	// the cluster, empty with no Context means incluster config
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	Context    string `json:"context,omitempty"`
+
+	Namespace    string   `json:"namespace,omitempty"`
+	Services     []string `json:"services,omitempty"`
+	Deployments  []string `json:"deployments,omitempty"`
+	StatefulSets []string `json:"statefulsets,omitempty"`
+
+	Limit     *int  `json:"limit,omitempty"`
+	KillAfter *int  `json:"killAfter,omitempty"`
+	DryRun    *bool `json:"dryRun,omitempty"`
+}
+
+func loadFleet(path string) (*Fleet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fleet := new(Fleet)
+	if err := yaml.UnmarshalStrict(data, fleet); err != nil {
+		return nil, fmt.Errorf("invalid fleet file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool, len(fleet.Clusters))
+	for _, cluster := range fleet.Clusters {
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("invalid fleet file %s: cluster without name", path)
+		}
+		if names[cluster.Name] {
+			return nil, fmt.Errorf("invalid fleet file %s: cluster %s is repeated", path, cluster.Name)
+		}
+		names[cluster.Name] = true
+	}
+
+	if len(fleet.Clusters) == 0 {
+		return nil, fmt.Errorf("invalid fleet file %s: no clusters", path)
+	}
+
+	return fleet, nil
+}
+
+// terminateFleet checks every cluster of the fleet file independently. A
+// cluster that fails is marked down and restarted with an exponential backoff
+// instead of stopping the others.
+func terminateFleet(ctx *cli.Context, path string, options terminator.Options, opts []terminator.Option, limit, killAfter int, sleep, killSleep time.Duration) error {
+	fleet, err := loadFleet(path)
+	if err != nil {
+		return err
+	}
+
+	options.Workers = ctx.Int("workers")
+	options.Scope = ctx.String("scope")
+	options.SelectorTTL = time.Millisecond * time.Duration(ctx.Int("selector-ttl"))
+	options.OutputUnits = ctx.String("output-units")
+	options.PercentagePrecision = ctx.Int("percentage-precision")
+	options.Quiet, options.Color = ctx.Bool("quiet"), colored(ctx)
+	setupOutput(ctx)
+
+	var wg sync.WaitGroup
+	var running []terminator.Terminator
+	var maxKills int32
+	for _, cluster := range fleet.Clusters {
+		config, err := getConfig(cluster.Kubeconfig, cluster.Context)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", cluster.Name, err)
+		}
+
+		clusterOptions := options
+		clusterOptions.Cluster = cluster.Name
+		if cluster.DryRun != nil {
+			clusterOptions.DryRun = *cluster.DryRun
+		}
+
+		t, err := terminator.New(config, clusterOptions, opts...)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", cluster.Name, err)
+		}
+
+		targets := terminator.Targets{
+			Namespace:    cluster.Namespace,
+			Services:     cluster.Services,
+			Deployments:  cluster.Deployments,
+			StatefulSets: cluster.StatefulSets,
+		}
+		clusterLimit, clusterKillAfter := limit, killAfter
+		if cluster.Limit != nil {
+			clusterLimit = *cluster.Limit
+		}
+		if cluster.KillAfter != nil {
+			clusterKillAfter = *cluster.KillAfter
+		}
+
+		running = append(running, t)
+		watchReadiness(t)
+		info("[%s] checking for pods%s", cluster.Name, targets)
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			err := superviseCluster(ctx.Context, name, func() error {
+				return t.Terminate(ctx.Context, targets, clusterLimit, clusterKillAfter, sleep, killSleep)
+			})
+			if errors.Is(err, terminator.ErrMaxKills) {
+				atomic.AddInt32(&maxKills, 1)
+			}
+		}(cluster.Name)
+	}
+
+	go handleSignals(ctx.Context, ctx.String("policy-file"), running)
+	wg.Wait()
+	if ctx.Err() == nil && atomic.LoadInt32(&maxKills) > 0 {
+		return terminator.ErrMaxKills
+	}
+	return ctx.Err()
+}
+
+// superviseCluster calls run until ctx is done, tracking the health of the
+// cluster and backing off exponentially while it keeps failing. It stops once
+// run is done, with its error when the cluster reached its max kills.
+func superviseCluster(ctx context.Context, name string, run func() error) error {
+	backoff := fleetMinBackoff
+	for ctx.Err() == nil {
+		clusterUp.WithLabelValues(name).Set(1)
+		started := time.Now()
+		err := run()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil {
+			clusterUp.WithLabelValues(name).Set(0)
+			info("[%s] done", name)
+			return nil
+		}
+		if errors.Is(err, terminator.ErrMaxKills) {
+			clusterUp.WithLabelValues(name).Set(0)
+			log.Printf("[%s] stopped: %s", name, err)
+			return err
+		}
+
+		clusterUp.WithLabelValues(name).Set(0)
+		clusterRestarts.WithLabelValues(name).Inc()
+		if time.Since(started) > fleetMaxBackoff {
+			backoff = fleetMinBackoff
+		}
+		log.Printf("[%s] is down, restarting in %s: %s", name, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > fleetMaxBackoff {
+			backoff = fleetMaxBackoff
+		}
+	}
+	return nil
+}
@@ -2,264 +2,794 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
 	"path"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli/v2"
-	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/client-go/kubernetes"
+	"k8s.io/apimachinery/pkg/api/resource"
+	// the exec credential plugins are built into client-go, the oidc auth
+	// provider of kubeconfigs needs to be registered
+	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
-	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+	"oomterminator/pkg/terminator"
 )
 
+// exitMaxKills is the exit code once max-kills pods were killed.
+const exitMaxKills = 3
+
 func main() {
 	app := cli.App{
 		Name: "OOM Terminator",
 		Commands: []*cli.Command{
 			{
 				Name: "terminate",
-				Flags: []cli.Flag{
-					&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "kube config file path, default is incluster config"},
-					&cli.BoolFlag{Name: "local", Value: false, Usage: "use local config .kube/config file"},
+				Flags: append(append(commonFlags(), decisionFlags()...),
 					&cli.BoolFlag{Name: "dry-run", Value: false, Usage: "will not delete pods, only print when it reaches limit"},
+					&cli.StringFlag{Name: "plan-file", Usage: "JSON file listing the pods the dry run would have killed, when and why"},
+					&cli.DurationFlag{Name: "duration", Usage: "how long to check for pods before exiting cleanly, default is forever"},
+					&cli.IntFlag{Name: "iterations", Usage: "amount of checks to run before exiting, 0 never stops"},
+					&cli.StringFlag{Name: "fake-cluster", Usage: "YAML scenario of a synthetic in-memory cluster to check instead of a real one, with scripted memory timelines"},
+					&cli.IntFlag{Name: "max-kills", Usage: "stop with exit code 3 after killing this amount of pods in a cluster, 0 never stops"},
+					&cli.StringSliceFlag{Name: "namespace-interval", Usage: `namespaces checked concurrently each with its own interval between checks instead of sleep, like "payments=5s"`},
+					&cli.StringFlag{Name: "metrics-source", Value: terminator.MetricsSourceMetricsServer, Usage: "where the memory usage of pods comes from: metrics-server, cadvisor, datadog, cloudwatch or gcm"},
+					&cli.DurationFlag{Name: "max-metrics-age", Value: time.Minute, Usage: "age from which the metrics of metrics-server are stale, skipping the pod, 0 disables it"},
+					&cli.StringFlag{Name: "cadvisor-node-selector", Usage: "label selector of the nodes whose cadvisor is scraped, the others use metrics-server"},
+					&cli.StringFlag{Name: "datadog-site", Value: "datadoghq.com", Usage: "datadog site of the datadog metrics source"},
+					&cli.StringFlag{Name: "datadog-api-key", EnvVars: []string{"DD_API_KEY"}, Usage: "datadog API key of the datadog metrics source"},
+					&cli.StringFlag{Name: "datadog-app-key", EnvVars: []string{"DD_APP_KEY"}, Usage: "datadog application key of the datadog metrics source"},
+					&cli.StringFlag{Name: "datadog-query", Value: terminator.DefaultDatadogQuery, Usage: "template of the datadog query of the memory usage of a pod"},
+					&cli.StringFlag{Name: "cloudwatch-region", Usage: "aws region of the cloudwatch metrics source, defaults to the region of the aws config"},
+					&cli.StringFlag{Name: "cloudwatch-cluster-name", Usage: "eks cluster name of the container insights metrics of the cloudwatch metrics source"},
+					&cli.StringFlag{Name: "gcm-project", Usage: "google cloud project of the gcm metrics source"},
+					&cli.StringFlag{Name: "gcm-location", Usage: "only read the gcm series of clusters in this location"},
+					&cli.StringFlag{Name: "gcm-cluster-name", Usage: "only read the gcm series of the gke cluster with this name"},
+					&cli.StringFlag{Name: "memory-metric", Value: terminator.MemoryMetricWorkingSet, Usage: "memory figure of pods compared against their limit: working_set, rss or usage"},
+					&cli.StringFlag{Name: "no-limit-basis", Usage: "what to compare pods without memory limit against (node-allocatable), default is nothing"},
+					&cli.StringFlag{Name: "kill-action", Value: terminator.KillActionDelete, Usage: "what is done to the pods that are killed: delete, or exec to signal the PID 1 of the container using the most of its limit, restarting only that container"},
+					&cli.StringFlag{Name: "kill-signal", Value: "TERM", Usage: "signal sent to the container by the exec kill-action: TERM, INT or QUIT"},
+					&cli.BoolFlag{Name: "metadata-only", Usage: "only cache the metadata of pods, fetching the ones using at least candidate-percentage of their limit on each check"},
+					&cli.BoolFlag{Name: "two-phase", Usage: "only evaluate the policies and protections of pods using at least candidate-percentage of their limit"},
+					&cli.IntFlag{Name: "candidate-percentage", Value: 80, Usage: "memory usage percentage from which pods are fetched with metadata-only and evaluated with two-phase"},
+					&cli.BoolFlag{Name: "sidecars", Usage: "compare native sidecars against their own limit, restarting only the sidecar with the exec kill-action"},
+					&cli.StringFlag{Name: "no-limit-action", Value: terminator.NoLimitActionSkip, Usage: "what to do with pods without memory limit: skip, warn or use-absolute"},
+					&cli.StringFlag{Name: "absolute-limit", Usage: "memory quantity (e.g. 2Gi) pods without memory limit are compared against when no-limit-action is use-absolute"},
+					&cli.StringFlag{Name: "notify-webhook", Usage: "URL to POST notifications to as JSON, like pods that got OOMKilled"},
+					&cli.StringFlag{Name: "cloudevents-url", Usage: "URL to POST notifications to as structured CloudEvents, like a Knative broker"},
+					&cli.StringSliceFlag{Name: "kafka-brokers", Usage: "kafka brokers to publish notifications to"},
+					&cli.StringFlag{Name: "kafka-topic", Value: "oomterminator", Usage: "kafka topic to publish notifications to"},
+					&cli.StringFlag{Name: "kafka-sasl-mechanism", Usage: "SASL mechanism of the kafka brokers: plain, scram-sha-256 or scram-sha-512, default is no SASL"},
+					&cli.StringFlag{Name: "kafka-username", Usage: "SASL username of the kafka brokers"},
+					&cli.StringFlag{Name: "kafka-password", EnvVars: []string{"KAFKA_PASSWORD"}, Usage: "SASL password of the kafka brokers"},
+					&cli.BoolFlag{Name: "kafka-tls", Usage: "connect to the kafka brokers with TLS"},
+					&cli.BoolFlag{Name: "kafka-cloudevents", Usage: "publish notifications to kafka as structured CloudEvents"},
+					&cli.StringFlag{Name: "nats-url", Usage: "NATS server to publish notifications to, like nats://localhost:4222"},
+					&cli.StringFlag{Name: "nats-subject", Value: "oomterminator", Usage: "prefix of the NATS subjects of notifications, followed by their type"},
+					&cli.StringFlag{Name: "nats-creds", Usage: "credentials file of the NATS user"},
+					&cli.BoolFlag{Name: "nats-jetstream", Usage: "publish notifications to a JetStream stream, waiting for them to be persisted"},
+					&cli.StringFlag{Name: "sns-topic-arn", Usage: "ARN of an SNS topic to publish notifications to"},
+					&cli.StringFlag{Name: "sqs-queue-url", Usage: "URL of an SQS queue to send notifications to"},
+					&cli.StringFlag{Name: "gitops-provider", Usage: "github or gitlab, to open pull requests raising the memory limits of repeat offenders"},
+					&cli.StringFlag{Name: "gitops-repository", Usage: "owner/name of the GitHub repository or path of the GitLab project pull requests are opened on"},
+					&cli.StringFlag{Name: "gitops-token", EnvVars: []string{"GITOPS_TOKEN"}, Usage: "token opening the pull requests"},
+					&cli.StringFlag{Name: "gitops-branch", Value: "main", Usage: "branch pull requests are opened against"},
+					&cli.StringFlag{Name: "gitops-path", Value: "{namespace}/{kind}-{name}-memory.yaml", Usage: "where the patches of workloads are written in the repository, with {cluster}, {namespace}, {kind} and {name} replaced by the ones of the workload"},
+					&cli.StringFlag{Name: "gitops-url", Usage: "address of the API of a self-hosted GitHub or GitLab, default is github.com or gitlab.com"},
+					&cli.StringFlag{Name: "issue-tracker", Usage: "github or jira, to open an issue for each repeat offender, assigned to the assignees of the tenant owning its namespace"},
+					&cli.StringFlag{Name: "issue-url", Usage: "address of the Jira site, or of the API of a self-hosted GitHub, issues are opened on"},
+					&cli.StringFlag{Name: "issue-repository", Usage: "owner/name of the GitHub repository issues are opened on"},
+					&cli.StringFlag{Name: "issue-project", Usage: "key of the Jira project issues are opened on"},
+					&cli.StringFlag{Name: "issue-type", Value: "Task", Usage: "type of the Jira issues"},
+					&cli.StringFlag{Name: "issue-user", Usage: "email authenticating with issue-token to Jira Cloud, default is using issue-token as a personal access token"},
+					&cli.StringFlag{Name: "issue-token", EnvVars: []string{"ISSUE_TOKEN"}, Usage: "token opening the issues"},
+					&cli.StringFlag{Name: "issue-title", Usage: "text/template of the title of issues, executed with the notification"},
+					&cli.StringFlag{Name: "issue-body-file", Usage: "file with the text/template of the body of issues, executed with the notification"},
+					&cli.StringSliceFlag{Name: "active-hours", Usage: `windows when pods can be killed, like "Mon-Fri 08:00-20:00", default is always`},
+					&cli.StringSliceFlag{Name: "blackout", Usage: `windows when no pod is killed, like "Sat 00:00-Sun 23:59"`},
+					&cli.BoolFlag{Name: "report-blackout", Usage: "notify the kills deferred by a blackout once it is over"},
+					&cli.StringFlag{Name: "timezone", Value: "Local", Usage: "timezone of the active hours and blackouts"},
+					&cli.DurationFlag{Name: "repeat-window", Usage: "how long kills of a workload are remembered, each one doubling its kill-after and cooldown, default is no backoff"},
+					&cli.DurationFlag{Name: "repeat-cooldown", Value: time.Minute, Usage: "minimum cooldown doubled by the backoff of workloads killed repeatedly"},
+					&cli.IntFlag{Name: "max-repeat-kills", Value: 5, Usage: "kills of a workload within repeat-window after which it is notified instead of killed, 0 never stops"},
+					&cli.IntFlag{Name: "limit-headroom", Value: 20, Usage: "percentage added to the p99 of the usage of a repeat offender suggested as its limit"},
+					&cli.StringFlag{Name: "query", Usage: "PromQL expression from which a pod is over the limit too when it has a non zero value, with $pod and $namespace replaced by its name and namespace"},
+					&cli.StringFlag{Name: "prometheus-url", Usage: "address of the prometheus server evaluating queries"},
+					&cli.BoolFlag{Name: "spread-kills", Usage: "never kill two pods of a workload in the same zone in a row while another pod of it over the limit is in a different one"},
+					&cli.StringFlag{Name: "node-pressure", Usage: "how pods on nodes under memory pressure are handled: prioritize kills them first without waiting for kill-after, pause doesn't kill any pod while there are any, default ignores it"},
+					&cli.StringFlag{Name: "opencost-url", Usage: "address of an OpenCost server adding the cost of workloads to the notifications of kills"},
+					&cli.BoolFlag{Name: "scale-hpa", Usage: "raise the minimum replicas of the HPA of the workload of a pod to one over its current replicas instead of killing it, unless the HPA is at its max replicas or was scaled up within the scale-hpa-cooldown"},
+					&cli.DurationFlag{Name: "scale-hpa-cooldown", Value: time.Hour, Usage: "time after which the minimum replicas of the HPAs scaled up are restored, and before which they aren't scaled up again"},
+					&cli.BoolFlag{Name: "annotate-workloads", Usage: "annotate the deployments and statefulsets of killed pods with the last kill, the total kills and the usage that triggered it"},
+					&cli.StringFlag{Name: "opa-url", Usage: "URL of an OPA decision reviewing every kill, like http://localhost:8181/v1/data/terminator/decision"},
+					&cli.BoolFlag{Name: "opa-fail-open", Usage: "allow kills when OPA can't be queried, default is to deny them"},
+					&cli.StringFlag{Name: "sentry-dsn", EnvVars: []string{"SENTRY_DSN"}, Usage: "DSN of the sentry project to report panics and operational errors to"},
+					&cli.StringFlag{Name: "error-webhook", Usage: "URL to POST panics and operational errors to as JSON"},
+					&cli.StringFlag{Name: "gpu-dcgm-service", Usage: "namespace/name of the dcgm-exporter service to read the GPU memory usage of pods from"},
+					&cli.IntFlag{Name: "gpu-limit", Value: 95, Usage: "GPU memory usage percentage limit, 0 disables it"},
+					&cli.IntFlag{Name: "pid-limit", Usage: "percentage of the pids limit of pods from which they are over the limit, 0 disables it"},
+					&cli.Int64Flag{Name: "pod-pids-limit", Usage: "pids limit of pods on nodes whose kubelet doesn't tell it"},
+					&cli.BoolFlag{Name: "count-swap", Usage: "add the swap used by pods to their memory usage"},
+					&cli.StringFlag{Name: "swap-limit", Usage: "swap usage (e.g. 512Mi) from which pods are over the limit"},
+					&cli.StringSliceFlag{Name: "custom-metric", Usage: `metric of pods from the custom metrics API and the value from which they are over the limit, like "jvm_memory_used_bytes=1500Mi"`},
+					&cli.StringSliceFlag{Name: "external-metric", Usage: `metric from the external metrics API available to the condition, like "queue_depth:queue=orders"`},
+					&cli.StringFlag{Name: "state-configmap", Usage: "namespace/name of a ConfigMap keeping the over limit counters across restarts"},
+					&cli.StringFlag{Name: "export", Usage: "directory, s3://bucket/prefix or gs://bucket/prefix to write the samples and decisions of each check to as CSV"},
+					&cli.BoolFlag{Name: "degrade", Usage: "keep running without killing pods while their metrics can't be fetched, instead of failing the check"},
+					&cli.IntFlag{Name: "error-budget", Usage: "checks that can fail in a row, being retried, before it is notified and /readyz fails, default stops on the first failure"},
+					&cli.StringFlag{Name: "heartbeat-url", Usage: "URL to ping after each successful check, like a healthchecks.io check, with {cluster} replaced by the cluster"},
+					&cli.BoolFlag{Name: "workload-metrics", Usage: "publish the pods of each workload over the limit as metrics, for HPAs to scale out on through an external metrics adapter"},
+					&cli.DurationFlag{Name: "shutdown-timeout", Value: 20 * time.Second, Usage: "how long the check running on SIGTERM has to finish before exiting"},
+					&cli.BoolFlag{Name: "self", Usage: "only check the pod the terminator runs in as a sidecar, by the POD_NAME and POD_NAMESPACE environment variables, instead of the targets"},
+					&cli.StringSliceFlag{Name: "containers", Usage: "containers of the pods whose memory is compared against their limits, default is all of them"},
+					&cli.StringFlag{Name: "fleet", Usage: "YAML file listing the clusters to check with their policies, instead of config, contexts and targets"},
+				),
+				Action: terminate,
+			},
+			{
+				Name:  "analyze",
+				Usage: "report workloads whose memory usage keeps growing, without killing any pod",
+				Flags: append(commonFlags(),
+					&cli.DurationFlag{Name: "report-interval", Value: time.Hour, Usage: "how often to print the leak report"},
+					&cli.DurationFlag{Name: "window", Value: 24 * time.Hour, Usage: "how long samples are kept for the analysis"},
+					&cli.StringFlag{Name: "leak-threshold", Value: "1Mi", Usage: "memory growth per hour from which a pod is considered leaking"},
+					&cli.IntFlag{Name: "min-samples", Value: 10, Usage: "amount of samples a pod needs to be analyzed"},
+					&cli.StringFlag{Name: "opencost-url", Usage: "address of an OpenCost server adding the cost of leaking workloads to the report"},
+					outputFlag(outputTable),
+				),
+				Action: analyze,
+			},
+			{
+				Name:  "record",
+				Usage: "record the targeted pods and their memory usage to a file, without killing any pod",
+				Flags: append(commonFlags(),
+					&cli.StringFlag{Name: "file", Value: "recording.jsonl", Usage: "file to record to, prefixed by the context when there are several"},
+				),
+				Action: record,
+			},
+			{
+				Name:  "replay",
+				Usage: "print which pods of a recording would have been killed and when",
+				Flags: append(decisionFlags(),
+					&cli.StringFlag{Name: "file", Value: "recording.jsonl", Usage: "recording to replay"},
 					&cli.BoolFlag{Name: "debug", Value: false, Usage: "if set will log all steps"},
-
-					&cli.StringFlag{Name: "namespace", Usage: "namespace to look for pods, if empty gets all namespaces"},
-					&cli.StringSliceFlag{Name: "services", Usage: "services to get the pods from"},
-					&cli.StringSliceFlag{Name: "deployments", Usage: "deployments to get pods from"},
-
-					&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Value: 95, Usage: "memory usage percentage limit"},
-					&cli.IntFlag{Name: "sleep", Aliases: []string{"t"}, Value: 1000, Usage: "duration in milliseconds to sleep between checks"},
-					&cli.IntFlag{Name: "kill-sleep", Value: 1000, Usage: "duration in milliseconds to sleep after killing a pod"},
-					&cli.IntFlag{Name: "kill-after", Value: 1, Usage: "amount of checks the pod needs to be over limit to be killed"},
-				},
-				Action: func(ctx *cli.Context) error {
-					configFile := ctx.String("config")
-					namespace := ctx.String("namespace")
-					limit := ctx.Int("limit")
-					services := ctx.StringSlice("services")
-					deployments := ctx.StringSlice("deployments")
-					dryRun := ctx.Bool("dry-run")
-					sleep := time.Millisecond * time.Duration(ctx.Int("sleep"))
-					killSleep := time.Millisecond * time.Duration(ctx.Int("kill-sleep"))
-					killAfter := ctx.Int("kill-after")
-
-					// local
-					if ctx.Bool("local") {
-						if home, err := os.UserHomeDir(); err == nil {
-							configFile = path.Join(home, ".kube/config")
-						}
-					}
-
-					logrus.SetLevel(logrus.ErrorLevel)
-					if ctx.Bool("debug") {
-						logrus.SetLevel(logrus.InfoLevel)
-					}
-
-					config, err := getConfig(configFile)
-					if err != nil {
-						return err
-					}
-
-					terminator, err := NewTerminator(config, dryRun)
-					if err != nil {
-						return err
-					}
-
-					fmt.Printf("Checking for pods")
-					if namespace != "" {
-						fmt.Printf(" at namespace %s", namespace)
-					}
-					if len(services) > 0 {
-						fmt.Printf(" by services: %s", services)
-					}
-					if len(deployments) > 0 {
-						fmt.Printf(" by deployments: %s", deployments)
-					}
-
-					return terminator.Terminate(ctx.Context, namespace, limit, killAfter, services, deployments, sleep, killSleep)
+					&cli.IntFlag{Name: "workers", Value: 10, Usage: "amount of pods evaluated concurrently on each check"},
+					&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "only print kills and errors"},
+					&cli.BoolFlag{Name: "no-color", Usage: "don't color the output, which is colored on terminals unless NO_COLOR is set"},
+					outputFlag(outputTable),
+				),
+				Action: replay,
+			},
+			{
+				Name:      "explain",
+				Usage:     "print how a pod is evaluated and what keeps it from being killed, without killing it",
+				ArgsUsage: "pod/<name>",
+				Flags: append(append(commonFlags(), decisionFlags()...),
+					&cli.StringFlag{Name: "state-configmap", Usage: "namespace/name of the ConfigMap with the over limit counters of the terminator"},
+					outputFlag(outputTable),
+				),
+				Action: explain,
+			},
+			{
+				Name:  "recommend",
+				Usage: "print the suggested memory requests and limits of the workloads of a recording",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "file", Value: "recording.jsonl", Usage: "recording to analyze"},
+					&cli.StringFlag{Name: "aggregation", Value: "p95", Usage: "aggregation of the usage of the pods of a workload suggested as its request: avg, max or a percentile like p95"},
+					&cli.StringFlag{Name: "limit-aggregation", Value: "max", Usage: "aggregation of the usage of the pods of a workload suggested as its limit, plus headroom: avg, max or a percentile like p99"},
+					&cli.IntFlag{Name: "headroom", Value: 20, Usage: "percentage added to the limit-aggregation of the usage of the pods of a workload suggested as its limit"},
+					&cli.StringFlag{Name: "format", Value: "patch", Usage: "objects to print: patch, patches of the workloads, or vpa, VerticalPodAutoscaler objects"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: outputYAML, Usage: "output format: table, json, yaml, or vpa, short for format vpa as yaml"},
 				},
+				Action: recommend,
 			},
 		},
 	}
 
-	if err := app.Run(os.Args); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// stopping on a signal is a clean exit
+	err := app.RunContext(ctx, os.Args)
+	if errors.Is(err, terminator.ErrMaxKills) {
+		log.Print(err)
+		os.Exit(exitMaxKills)
+	}
+	if err != nil && !(errors.Is(err, context.Canceled) && ctx.Err() != nil) {
 		log.Fatal(err)
 	}
 }
 
-type Terminator interface {
-	Terminate(ctx context.Context, namespace string, memoryLimit, killAfter int, serviceNames, deploymentNames []string, sleep, killSleep time.Duration) error
+// commonFlags are the flags shared by all commands.
+func commonFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{Name: "config", Aliases: []string{"c"}, Usage: "kube config file path, default is incluster config"},
+		&cli.BoolFlag{Name: "local", Value: false, Usage: "use local config .kube/config file"},
+		&cli.StringSliceFlag{Name: "contexts", Usage: "kube config contexts of the clusters to check, each one evaluated independently"},
+		&cli.BoolFlag{Name: "debug", Value: false, Usage: "if set will log all steps"},
+
+		&cli.StringFlag{Name: "namespace", Usage: "namespace to look for pods, if empty gets all namespaces"},
+		&cli.StringFlag{Name: "scope", Value: terminator.ScopeCluster, Usage: "cluster, or namespace to only call the APIs of the namespace, so a namespaced Role is enough"},
+		&cli.StringSliceFlag{Name: "services", Usage: "services to get the pods from"},
+		&cli.StringSliceFlag{Name: "deployments", Usage: "deployments to get pods from"},
+		&cli.StringSliceFlag{Name: "statefulsets", Usage: "statefulsets to get pods from"},
+		&cli.StringSliceFlag{Name: "nodes", Usage: "nodes to limit the pods to, default is all of them"},
+		&cli.StringFlag{Name: "node-selector", Usage: "label selector of the nodes to limit the pods to, like pool=batch"},
+		&cli.BoolFlag{Name: "node-local", Usage: "limit the pods to the node of the terminator, by the NODE_NAME environment variable, to run it as a DaemonSet"},
+
+		&cli.IntFlag{Name: "sleep", Aliases: []string{"t"}, Value: 1000, Usage: "duration in milliseconds to sleep between checks"},
+		&cli.IntFlag{Name: "selector-ttl", Value: 60000, Usage: "duration in milliseconds to cache the selectors of services, deployments and statefulsets"},
+		&cli.IntFlag{Name: "workers", Value: 10, Usage: "amount of pods evaluated concurrently on each check"},
+		&cli.StringFlag{Name: "metrics-address", Value: ":9090", Usage: "address to serve prometheus metrics at, empty disables it"},
+		&cli.BoolFlag{Name: "quiet", Aliases: []string{"q"}, Usage: "only print kills and errors"},
+		&cli.BoolFlag{Name: "no-color", Usage: "don't color the output, which is colored on terminals unless NO_COLOR is set"},
+		&cli.StringFlag{Name: "output-units", Value: terminator.UnitsBinary, Usage: "units of the memory in the output: binary (1.5Gi), decimal (1.6G) or bytes"},
+		&cli.IntFlag{Name: "percentage-precision", Usage: "decimals of the percentages in the output"},
+	}
 }
 
-type terminator struct {
-	clientset *kubernetes.Clientset
-	metrics   *metrics.Clientset
-	dryRun    bool
+// decisionFlags are the flags deciding which pods are killed, shared by
+// terminate and replay.
+func decisionFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{Name: "limit", Aliases: []string{"l"}, Value: 95, Usage: "memory usage percentage limit"},
+		&cli.IntFlag{Name: "kill-sleep", Value: 1000, Usage: "duration in milliseconds to sleep after killing a pod"},
+		&cli.IntFlag{Name: "kill-after", Value: 1, Usage: "amount of checks the pod needs to be over limit to be killed"},
+		&cli.StringFlag{Name: "aggregation", Value: terminator.AggregationLast, Usage: "how the memory usage of the last window is compared against the limit: last, avg, max or a percentile like p95"},
+		&cli.DurationFlag{Name: "window", Usage: "how long the memory usage of pods is aggregated over, default is only the last check"},
+		&cli.IntFlag{Name: "max-tracked-pods", Value: 100000, Usage: "maximum pods whose memory usage of the last window is kept, forgetting the least recently seen ones, 0 is unbounded"},
+		&cli.IntFlag{Name: "max-restarts", Usage: "restart count from which a container restarted in the last 10 minutes is considered crash looping, pausing kills of its workload, 0 only considers CrashLoopBackOff, not with metadata-only"},
+		&cli.DurationFlag{Name: "cooldown", Usage: "minimum time between kills of pods of the same workload"},
+		&cli.StringFlag{Name: "max-disruptions-per-workload", Usage: "maximum kills of pods of a workload within a window, like 1/30m, regardless of its PodDisruptionBudgets"},
+		&cli.StringFlag{Name: "condition", Usage: "CEL expression deciding whether a pod is killed instead of limit and kill-after, like \"usage.pct > 90 && history.overCount >= 3\", testing keys pods may not have first, like \"has(pod.labels.tier)\" or \"'tier' in pod.labels\""},
+		&cli.StringFlag{Name: "policy-file", Usage: "YAML file with the policies of namespaces and workloads, overriding limit, kill-after and cooldown"},
+		&cli.BoolFlag{Name: "include-bare-pods", Usage: "allow killing pods without controllers, which are not recreated"},
+		&cli.StringSliceFlag{Name: "qos", Usage: "QoS classes of the pods that can be killed, like BestEffort,Burstable, default is any"},
+		&cli.IntFlag{Name: "max-kills-per-namespace-per-hour", Usage: "maximum kills of pods of a namespace within the last hour, 0 doesn't cap them"},
+		&cli.IntFlag{Name: "max-priority", Usage: "only kill pods with at most this priority, from their PriorityClass, default is any"},
+		&cli.BoolFlag{Name: "allow-system-namespaces", Usage: "allow killing pods of system namespaces like kube-system, protected by default"},
+	}
 }
 
-func NewTerminator(config *rest.Config, dryRun bool) (Terminator, error) {
-	clientset, err := kubernetes.NewForConfig(config)
+// decisionOptions sets the options of the decision flags.
+func decisionOptions(ctx *cli.Context, options *terminator.Options) error {
+	options.MaxRestarts = int32(ctx.Int("max-restarts"))
+	options.Cooldown = ctx.Duration("cooldown")
+	if budget := ctx.String("max-disruptions-per-workload"); budget != "" {
+		parsed, err := terminator.ParseDisruptionBudget(budget)
+		if err != nil {
+			return err
+		}
+		options.MaxDisruptions = parsed
+	}
+	options.IncludeBarePods = ctx.Bool("include-bare-pods")
+	options.QOSClasses = ctx.StringSlice("qos")
+	options.MaxKillsPerNamespacePerHour = ctx.Int("max-kills-per-namespace-per-hour")
+	if ctx.IsSet("max-priority") {
+		maxPriority := int32(ctx.Int("max-priority"))
+		options.MaxPriority = &maxPriority
+	}
+	options.Aggregation = ctx.String("aggregation")
+	options.Window = ctx.Duration("window")
+	options.MaxTrackedPods = ctx.Int("max-tracked-pods")
+	if !ctx.Bool("allow-system-namespaces") {
+		options.SystemPolicies = terminator.SystemPolicies()
+	}
+	if condition := ctx.String("condition"); condition != "" {
+		compiled, err := terminator.CompileCondition(condition)
+		if err != nil {
+			return err
+		}
+		options.Condition = compiled
+	}
+	if policyFile := ctx.String("policy-file"); policyFile != "" {
+		policies, err := terminator.LoadPolicyFile(policyFile)
+		if err != nil {
+			return err
+		}
+		options.Policies = policies
+	}
+	return nil
+}
+
+func terminate(ctx *cli.Context) error {
+	limit := ctx.Int("limit")
+	sleep := time.Millisecond * time.Duration(ctx.Int("sleep"))
+	killSleep := time.Millisecond * time.Duration(ctx.Int("kill-sleep"))
+	killAfter := ctx.Int("kill-after")
+	options := terminator.Options{
+		DryRun:            ctx.Bool("dry-run"),
+		PlanFile:          ctx.String("plan-file"),
+		NoLimitBasis:      ctx.String("no-limit-basis"),
+		NoLimitAction:     ctx.String("no-limit-action"),
+		KillAction:        ctx.String("kill-action"),
+		KillSignal:        ctx.String("kill-signal"),
+		Sidecars:          ctx.Bool("sidecars"),
+		ShutdownTimeout:   ctx.Duration("shutdown-timeout"),
+		MaxKills:          ctx.Int("max-kills"),
+		AnnotateWorkloads: ctx.Bool("annotate-workloads"),
+		ErrorBudget:       ctx.Int("error-budget"),
+		Degrade:           ctx.Bool("degrade"),
+		ScaleHPAs:         ctx.Bool("scale-hpa"),
+		ScaleCooldown:     ctx.Duration("scale-hpa-cooldown"),
+		OpenCostURL:       ctx.String("opencost-url"),
+		HeartbeatURL:      ctx.String("heartbeat-url"),
+		WorkloadMetrics:   ctx.Bool("workload-metrics"),
+		NodePressure:      ctx.String("node-pressure"),
+		SpreadKills:       ctx.Bool("spread-kills"),
+		Iterations:        ctx.Int("iterations"),
+		StateConfigMap:    ctx.String("state-configmap"),
+		ExportURL:         ctx.String("export"),
+		Query:             ctx.String("query"),
+		PrometheusURL:     ctx.String("prometheus-url"),
+		RepeatWindow:      ctx.Duration("repeat-window"),
+		RepeatCooldown:    ctx.Duration("repeat-cooldown"),
+		MaxRepeatKills:    ctx.Int("max-repeat-kills"),
+		LimitHeadroom:     ctx.Int("limit-headroom"),
+		GPULimit:          ctx.Int("gpu-limit"),
+		GPUService:        ctx.String("gpu-dcgm-service"),
+		PIDLimit:          ctx.Int("pid-limit"),
+		PodPidsLimit:      ctx.Int64("pod-pids-limit"),
+		CountSwap:         ctx.Bool("count-swap"),
+		MemoryMetric:      ctx.String("memory-metric"),
+
+		MetadataOnly:        ctx.Bool("metadata-only"),
+		TwoPhase:            ctx.Bool("two-phase"),
+		CandidatePercentage: ctx.Int("candidate-percentage"),
+
+		MetricsSource:        ctx.String("metrics-source"),
+		CAdvisorNodeSelector: ctx.String("cadvisor-node-selector"),
+		MaxMetricsAge:        ctx.Duration("max-metrics-age"),
+		Containers:           ctx.StringSlice("containers"),
+		Datadog: terminator.DatadogOptions{
+			Site:   ctx.String("datadog-site"),
+			APIKey: ctx.String("datadog-api-key"),
+			AppKey: ctx.String("datadog-app-key"),
+			Query:  ctx.String("datadog-query"),
+		},
+		CloudWatch: terminator.CloudWatchOptions{
+			Region:      ctx.String("cloudwatch-region"),
+			ClusterName: ctx.String("cloudwatch-cluster-name"),
+		},
+		GCM: terminator.GCMOptions{
+			Project:     ctx.String("gcm-project"),
+			Location:    ctx.String("gcm-location"),
+			ClusterName: ctx.String("gcm-cluster-name"),
+		},
+	}
+	if err := decisionOptions(ctx, &options); err != nil {
+		return err
+	}
+
+	targets, err := targetsFromContext(ctx)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if ctx.Bool("self") {
+		targets, err = selfTargets()
+		if err != nil {
+			return err
+		}
+		options.Scope = terminator.ScopeNamespace
 	}
 
-	mc, err := metrics.NewForConfig(config)
+	var opts []terminator.Option
+	var notifiers []terminator.Notifier
+	if webhook := ctx.String("notify-webhook"); webhook != "" {
+		notifiers = append(notifiers, terminator.NewWebhookNotifier(webhook))
+	}
+	if cloudEvents := ctx.String("cloudevents-url"); cloudEvents != "" {
+		notifiers = append(notifiers, terminator.NewCloudEventsNotifier(cloudEvents))
+	}
+	if brokers := ctx.StringSlice("kafka-brokers"); len(brokers) > 0 {
+		kafka, err := terminator.NewKafkaNotifier(terminator.KafkaOptions{
+			Brokers:       brokers,
+			Topic:         ctx.String("kafka-topic"),
+			SASLMechanism: ctx.String("kafka-sasl-mechanism"),
+			Username:      ctx.String("kafka-username"),
+			Password:      ctx.String("kafka-password"),
+			TLS:           ctx.Bool("kafka-tls"),
+			CloudEvents:   ctx.Bool("kafka-cloudevents"),
+		})
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, kafka)
+	}
+	if natsURL := ctx.String("nats-url"); natsURL != "" {
+		nats, err := terminator.NewNATSNotifier(terminator.NATSOptions{
+			URL:       natsURL,
+			Subject:   ctx.String("nats-subject"),
+			CredsFile: ctx.String("nats-creds"),
+			JetStream: ctx.Bool("nats-jetstream"),
+		})
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, nats)
+	}
+	if topicARN := ctx.String("sns-topic-arn"); topicARN != "" {
+		sns, err := terminator.NewSNSNotifier(ctx.Context, topicARN)
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, sns)
+	}
+	if queueURL := ctx.String("sqs-queue-url"); queueURL != "" {
+		sqs, err := terminator.NewSQSNotifier(ctx.Context, queueURL)
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, sqs)
+	}
+	if provider := ctx.String("gitops-provider"); provider != "" {
+		pullRequests, err := terminator.NewPullRequestNotifier(terminator.PullRequestOptions{
+			Provider:   provider,
+			Repository: ctx.String("gitops-repository"),
+			BaseURL:    ctx.String("gitops-url"),
+			Token:      ctx.String("gitops-token"),
+			Branch:     ctx.String("gitops-branch"),
+			Path:       ctx.String("gitops-path"),
+		})
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, pullRequests)
+	}
+	if tracker := ctx.String("issue-tracker"); tracker != "" {
+		var body []byte
+		if bodyFile := ctx.String("issue-body-file"); bodyFile != "" {
+			body, err = os.ReadFile(bodyFile)
+			if err != nil {
+				return err
+			}
+		}
+		issues, err := terminator.NewIssueNotifier(terminator.IssueOptions{
+			Tracker:    tracker,
+			BaseURL:    ctx.String("issue-url"),
+			Repository: ctx.String("issue-repository"),
+			Project:    ctx.String("issue-project"),
+			IssueType:  ctx.String("issue-type"),
+			User:       ctx.String("issue-user"),
+			Token:      ctx.String("issue-token"),
+			Title:      ctx.String("issue-title"),
+			Body:       string(body),
+		})
+		if err != nil {
+			return err
+		}
+		notifiers = append(notifiers, issues)
+	}
+	if len(notifiers) > 0 {
+		opts = append(opts, terminator.WithNotifier(terminator.NewMultiNotifier(notifiers...)))
+	}
+	if opaURL := ctx.String("opa-url"); opaURL != "" {
+		opts = append(opts, terminator.WithGuard(terminator.NewOPAGuard(opaURL, ctx.Bool("opa-fail-open"))))
+	}
+	if dsn, webhook := ctx.String("sentry-dsn"), ctx.String("error-webhook"); dsn != "" && webhook != "" {
+		return fmt.Errorf("sentry-dsn and error-webhook can't be used together")
+	} else if dsn != "" {
+		sentry, err := terminator.NewSentryReporter(dsn)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, terminator.WithErrorReporter(sentry))
+	} else if webhook != "" {
+		opts = append(opts, terminator.WithErrorReporter(terminator.NewWebhookErrorReporter(webhook)))
+	}
+
+	location, err := time.LoadLocation(ctx.String("timezone"))
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("invalid timezone: %w", err)
 	}
 
-	return terminator{
-		clientset: clientset,
-		metrics:   mc,
-		dryRun:    dryRun,
-	}, nil
-}
+	options.Location = location
+	options.ActiveHours, err = terminator.ParseSchedule(ctx.StringSlice("active-hours"), location)
+	if err != nil {
+		return fmt.Errorf("invalid active-hours: %w", err)
+	}
 
-type overLimit struct {
-	at    time.Time
-	count int
-}
+	options.Blackout, err = terminator.ParseSchedule(ctx.StringSlice("blackout"), location)
+	if err != nil {
+		return fmt.Errorf("invalid blackout: %w", err)
+	}
+	options.ReportBlackout = ctx.Bool("report-blackout")
 
-func (t terminator) Terminate(ctx context.Context, namespace string, memoryLimit, killAfter int, serviceNames, deploymentNames []string, sleep, killSleep time.Duration) error {
-	podsToKill := make(map[string]*overLimit)
-	for {
-		killed := false
-		pods, err := t.getPods(ctx, namespace, serviceNames, deploymentNames)
+	if absoluteLimit := ctx.String("absolute-limit"); absoluteLimit != "" {
+		quantity, err := resource.ParseQuantity(absoluteLimit)
+		if err != nil {
+			return fmt.Errorf("invalid absolute-limit: %w", err)
+		}
+		options.AbsoluteLimit = &quantity
+	}
+
+	for _, metric := range ctx.StringSlice("custom-metric") {
+		custom, err := terminator.ParseCustomMetric(metric)
 		if err != nil {
 			return err
 		}
+		options.CustomMetrics = append(options.CustomMetrics, custom)
+	}
 
-		logrus.Infof("found %d pods", len(pods.Items))
+	for _, metric := range ctx.StringSlice("external-metric") {
+		external, err := terminator.ParseExternalMetric(metric)
+		if err != nil {
+			return err
+		}
+		options.ExternalMetrics = append(options.ExternalMetrics, external)
+	}
 
-		for _, pod := range pods.Items {
-			if len(pod.Spec.Containers) == 0 || pod.Status.Phase != "Running" || killed {
-				continue
-			}
+	if swapLimit := ctx.String("swap-limit"); swapLimit != "" {
+		quantity, err := resource.ParseQuantity(swapLimit)
+		if err != nil {
+			return fmt.Errorf("invalid swap-limit: %w", err)
+		}
+		options.SwapLimit = &quantity
+	}
 
-			limit := pod.Spec.Containers[0].Resources.Limits.Memory()
-			podMetrics, err := t.metrics.MetricsV1beta1().PodMetricses(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
-			if err != nil {
-				if errors.IsNotFound(err) {
-					logrus.Infof("Pod %s has no metrics", pod.Name)
-					continue
-				}
-				return err
-			}
+	// the loop exits cleanly once the duration passed, like on SIGTERM
+	if duration := ctx.Duration("duration"); duration > 0 {
+		var cancel context.CancelFunc
+		ctx.Context, cancel = context.WithTimeout(ctx.Context, duration)
+		defer cancel()
+	}
 
-			if len(podMetrics.Containers) == 0 {
-				continue
-			}
+	intervals, err := parseIntervals(ctx.StringSlice("namespace-interval"))
+	if err != nil {
+		return err
+	}
 
-			using := podMetrics.Containers[0].Usage.Memory()
-			percentage := float64(using.Value()) / float64(limit.Value()) * 100
-			logrus.Infof("pod < %s > (%s/%s) = %.f%%", pod.Name, using.String(), limit.String(), percentage)
-
-			if percentage >= float64(memoryLimit) {
-				if over, ok := podsToKill[pod.Name]; ok {
-					over.count = over.count + 1
-				} else {
-					podsToKill[pod.Name] = &overLimit{at: time.Now()}
-				}
-
-				log.Printf(" pod < %s > (%s/%s = %.f%% over the memory limit)", pod.Name, using.String(), limit.String(), percentage)
-				if podsToKill[pod.Name].count >= killAfter {
-					log.Printf("Deleting pod < %s > (has exceeded memory limit for %d checks)", pod.Name, killAfter)
-					if !t.dryRun {
-						err := t.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: pod.DeletionGracePeriodSeconds})
-						if err != nil {
-							return err
-						}
-					}
-					time.Sleep(killSleep)
-					delete(podsToKill, pod.Name)
-					killed = true
-				}
-			}
+	if fleet := ctx.String("fleet"); fleet != "" {
+		if len(intervals) > 0 {
+			return fmt.Errorf("namespace-interval can't be used with a fleet")
 		}
+		return ended(ctx, terminateFleet(ctx, fleet, options, opts, limit, killAfter, sleep, killSleep))
+	}
+
+	terminators, err := setup(ctx, options, opts...)
+	if err != nil {
+		return err
+	}
+
+	running := make([]terminator.Terminator, 0, len(terminators))
+	for _, t := range terminators {
+		running = append(running, t)
+	}
+	go handleSignals(ctx.Context, ctx.String("policy-file"), running)
+
+	info("Checking for pods%s", targets)
+	return ended(ctx, runClusters(terminators, func(t terminator.Terminator) error {
+		if len(intervals) > 0 {
+			return t.TerminateNamespaces(ctx.Context, targets, intervals, limit, killAfter, killSleep)
+		}
+		return t.Terminate(ctx.Context, targets, limit, killAfter, sleep, killSleep)
+	}))
+}
+
+// parseIntervals parses the intervals of namespaces like payments=5s.
+func parseIntervals(values []string) (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration, len(values))
+	for _, value := range values {
+		namespace, interval, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid namespace-interval %q, must be like payments=5s", value)
+		}
+		parsed, err := time.ParseDuration(interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid namespace-interval %q: %w", value, err)
+		}
+		intervals[namespace] = parsed
+	}
+	return intervals, nil
+}
+
+// ended returns nil for err when it comes from the end of the duration.
+func ended(ctx *cli.Context, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && errors.Is(ctx.Context.Err(), context.DeadlineExceeded) {
+		info("Stopping after %s", ctx.Duration("duration"))
+		return nil
+	}
+	return err
+}
+
+func analyze(ctx *cli.Context) error {
+	leakThreshold, err := resource.ParseQuantity(ctx.String("leak-threshold"))
+	if err != nil {
+		return fmt.Errorf("invalid leak-threshold: %w", err)
+	}
+
+	analysis := terminator.Analysis{
+		Sleep:          time.Millisecond * time.Duration(ctx.Int("sleep")),
+		ReportInterval: ctx.Duration("report-interval"),
+		Window:         ctx.Duration("window"),
+		LeakThreshold:  leakThreshold,
+		MinSamples:     ctx.Int("min-samples"),
+	}
 
-		// expire old pods that were over limit, but arent anymore or were deleted
-		for pod, over := range podsToKill {
-			if time.Since(over.at) > killSleep*time.Duration(over.count+1) {
-				logrus.Infof("Pod %s is not over limit anymore or has already terminated", pod)
-				delete(podsToKill, pod)
+	printer, err := newPrinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	terminators, err := setup(ctx, terminator.Options{OpenCostURL: ctx.String("opencost-url")})
+	if err != nil {
+		return err
+	}
+
+	units := ctx.String("output-units")
+	analysis.Report = func(reports []terminator.LeakReport) {
+		header := []string{"CLUSTER", "WORKLOAD", "ANALYZED", "GROWING", "GROWTH/HOUR", "LEAKING", "COST"}
+		rows := make([][]string, 0, len(reports))
+		for _, report := range reports {
+			cost := ""
+			if report.Cost != nil {
+				cost = report.Cost.String()
+			}
+			rows = append(rows, []string{report.Cluster, report.Workload, strconv.Itoa(report.Analyzed), strconv.Itoa(report.Growing), terminator.FormatBytes(units, report.GrowthPerHour), strconv.FormatBool(report.Leaking), cost})
+		}
+		if len(terminators) == 1 {
+			header = header[1:]
+			for i := range rows {
+				rows[i] = rows[i][1:]
 			}
 		}
+		if err := printer.print(reports, header, rows); err != nil {
+			log.Printf("could not print the leak report: %s", err)
+		}
+	}
 
-		time.Sleep(sleep)
+	targets, err := targetsFromContext(ctx)
+	if err != nil {
+		return err
 	}
+	info("Analyzing pods%s", targets)
+	return runClusters(terminators, func(t terminator.Terminator) error {
+		return t.Analyze(ctx.Context, targets, analysis)
+	})
 }
 
-func (t terminator) getPods(ctx context.Context, namespace string, serviceNames, deploymentNames []string) (*v1.PodList, error) {
-	if len(serviceNames) == 0 && len(deploymentNames) == 0 {
-		return t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{Limit: 10})
+// setup configures logging and metrics and builds a Terminator for each of the
+// clusters from the common flags and options. Without contexts it is a single
+// unnamed cluster.
+func setup(ctx *cli.Context, options terminator.Options, opts ...terminator.Option) (map[string]terminator.Terminator, error) {
+	configFile := ctx.String("config")
+	options.Workers = ctx.Int("workers")
+	if options.Scope == "" {
+		options.Scope = ctx.String("scope")
+	}
+	options.SelectorTTL = time.Millisecond * time.Duration(ctx.Int("selector-ttl"))
+	options.OutputUnits = ctx.String("output-units")
+	options.PercentagePrecision = ctx.Int("percentage-precision")
+	options.Quiet, options.Color = ctx.Bool("quiet"), colored(ctx)
+
+	// local
+	if ctx.Bool("local") {
+		if home, err := os.UserHomeDir(); err == nil {
+			configFile = path.Join(home, ".kube/config")
+		}
 	}
 
-	deploymentsClient := t.clientset.AppsV1().Deployments(namespace)
-	pods := new(v1.PodList)
-	for _, name := range serviceNames {
-		service, err := t.clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	setupOutput(ctx)
+	if path := ctx.String("fake-cluster"); path != "" {
+		scenario, err := terminator.LoadScenario(path)
 		if err != nil {
-			if errors.IsNotFound(err) {
-				logrus.Errorf("service %s not found", service.Name)
-				continue
-			}
 			return nil, err
 		}
-
-		set := labels.Set(service.Spec.Selector)
-		servicePods, err := t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: set.AsSelector().String()})
+		t, err := terminator.NewFakeCluster(scenario, options, opts...)
 		if err != nil {
 			return nil, err
 		}
+		watchReadiness(t)
+		return map[string]terminator.Terminator{"": t}, nil
+	}
 
-		logrus.Infof("service %s has %d pods", name, len(servicePods.Items))
-		pods.Items = append(pods.Items, servicePods.Items...)
+	contexts := ctx.StringSlice("contexts")
+	if len(contexts) == 0 {
+		contexts = []string{""}
 	}
 
-	for _, name := range deploymentNames {
-		deployment, err := deploymentsClient.Get(ctx, name, metav1.GetOptions{})
+	terminators := make(map[string]terminator.Terminator, len(contexts))
+	for _, context := range contexts {
+		config, err := getConfig(configFile, context)
 		if err != nil {
-			if errors.IsNotFound(err) {
-				logrus.Errorf("deployment %s not found", deployment.Name)
-				continue
-			}
 			return nil, err
 		}
 
-		set := labels.Set(deployment.Spec.Selector.MatchLabels)
-		deploymentPods, err := t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: set.AsSelector().String()})
+		options.Cluster = context
+		t, err := terminator.New(config, options, opts...)
 		if err != nil {
 			return nil, err
 		}
+		terminators[context] = t
+		watchReadiness(t)
+	}
 
-		running := 0
-		for _, pod := range deploymentPods.Items {
-			if pod.Status.Phase == "Running" {
-				running = running + 1
-			}
+	return terminators, nil
+}
+
+// setupOutput configures logging and serves metrics according to the common
+// flags.
+func setupOutput(ctx *cli.Context) {
+	quiet = ctx.Bool("quiet")
+	logrus.SetLevel(logrus.ErrorLevel)
+	if ctx.Bool("debug") {
+		logrus.SetLevel(logrus.InfoLevel)
+	}
+
+	if address := ctx.String("metrics-address"); address != "" {
+		serveMetrics(address)
+	}
+}
+
+// runClusters calls run for each of the terminators concurrently, so clusters
+// are evaluated independently and one failing doesn't stop the others. It
+// returns the first error once all of them stopped.
+func runClusters(terminators map[string]terminator.Terminator, run func(terminator.Terminator) error) error {
+	if len(terminators) == 1 {
+		for _, t := range terminators {
+			return run(t)
 		}
+	}
 
-		if running >= int(*deployment.Spec.Replicas) {
-			logrus.Infof("deployment %s has %d pods", name, len(deploymentPods.Items))
-			pods.Items = append(pods.Items, deploymentPods.Items...)
-		} else {
-			logrus.Infof("skipping %s, not all pods are running", name)
+	errs := make(chan error, len(terminators))
+	for cluster, t := range terminators {
+		go func(cluster string, t terminator.Terminator) {
+			err := run(t)
+			if err != nil {
+				log.Printf("[%s] stopped: %s", cluster, err)
+				err = fmt.Errorf("cluster %s: %w", cluster, err)
+			}
+			errs <- err
+		}(cluster, t)
+	}
+
+	var first error
+	for range terminators {
+		if err := <-errs; err != nil && first == nil {
+			first = err
 		}
 	}
+	return first
+}
+
+func targetsFromContext(ctx *cli.Context) (terminator.Targets, error) {
+	targets := terminator.Targets{
+		Namespace:    ctx.String("namespace"),
+		Services:     ctx.StringSlice("services"),
+		Deployments:  ctx.StringSlice("deployments"),
+		StatefulSets: ctx.StringSlice("statefulsets"),
+		Nodes:        ctx.StringSlice("nodes"),
+		NodeSelector: ctx.String("node-selector"),
+	}
 
-	return pods, nil
+	if ctx.Bool("node-local") {
+		node := os.Getenv("NODE_NAME")
+		if node == "" {
+			return targets, errors.New("node-local needs the NODE_NAME environment variable, set from the Downward API")
+		}
+		targets.Nodes = []string{node}
+		targets.NodeSelector = ""
+	}
+	return targets, nil
 }
 
-func getConfig(configFile string) (*rest.Config, error) {
+func getConfig(configFile, context string) (*rest.Config, error) {
+	if context != "" {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		if configFile != "" {
+			rules.ExplicitPath = configFile
+		}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: context}).ClientConfig()
+	}
+
 	if configFile != "" {
 		return clientcmd.BuildConfigFromFlags("", configFile)
 	}
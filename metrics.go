@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+	"oomterminator/pkg/terminator"
+)
+
+var clusterUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "terminator_cluster_up",
+	Help: "Whether the cluster of a fleet is being checked (1) or failing (0)",
+}, []string{"cluster"})
+
+var clusterRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_cluster_restarts_total",
+	Help: "Amount of times checking the cluster of a fleet failed and was restarted",
+}, []string{"cluster"})
+
+// readiness are the terminators /readyz reports on.
+var readiness struct {
+	sync.Mutex
+	terminators []terminator.Terminator
+}
+
+func watchReadiness(t terminator.Terminator) {
+	readiness.Lock()
+	defer readiness.Unlock()
+	readiness.terminators = append(readiness.terminators, t)
+}
+
+// serveReadiness fails while some terminator ran out of its error budget.
+func serveReadiness(w http.ResponseWriter, _ *http.Request) {
+	readiness.Lock()
+	defer readiness.Unlock()
+	for _, t := range readiness.terminators {
+		if !t.Ready() {
+			http.Error(w, "checks are failing", http.StatusServiceUnavailable)
+			return
+		}
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+// serveStatus writes the status of every terminator as JSON.
+func serveStatus(w http.ResponseWriter, _ *http.Request) {
+	readiness.Lock()
+	statuses := make([]terminator.Status, 0, len(readiness.terminators))
+	for _, t := range readiness.terminators {
+		statuses = append(statuses, t.Status())
+	}
+	readiness.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(statuses); err != nil {
+		logrus.Errorf("could not write the status: %s", err)
+	}
+}
+
+// serveMetrics exposes the prometheus metrics, the readiness at /readyz and the
+// status at /status, at address in the background.
+func serveMetrics(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/readyz", serveReadiness)
+	mux.HandleFunc("/status", serveStatus)
+	go func() {
+		if err := http.ListenAndServe(address, mux); err != nil {
+			logrus.Errorf("metrics server stopped: %s", err)
+		}
+	}()
+}
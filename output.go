@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/urfave/cli/v2"
+)
+
+// quiet only prints kills and errors, set by setupOutput.
+var quiet bool
+
+// info prints what isn't a kill nor an error, unless quiet.
+func info(format string, v ...interface{}) {
+	if !quiet {
+		log.Printf(format, v...)
+	}
+}
+
+// colored tells whether the output is colored: on terminals, unless no-color
+// or NO_COLOR are set.
+func colored(ctx *cli.Context) bool {
+	if ctx.Bool("no-color") || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	stat, err := os.Stderr.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
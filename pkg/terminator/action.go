@@ -0,0 +1,28 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Action is what is done to the pods that are killed.
+type Action interface {
+	// Kill kills pod, with gracePeriod overriding the one of the pod when set.
+	Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error
+}
+
+type deleteAction struct {
+	clientset kubernetes.Interface
+}
+
+// NewDeleteAction returns an Action deleting pods, the default one.
+func NewDeleteAction(clientset kubernetes.Interface) Action {
+	return deleteAction{clientset: clientset}
+}
+
+func (a deleteAction) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	return a.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: gracePeriod})
+}
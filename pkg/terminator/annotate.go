@@ -0,0 +1,139 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Annotations of the workloads of killed pods.
+const (
+	lastKillAnnotation      = "terminator.rubbioli.io/last-kill"
+	killsAnnotation         = "terminator.rubbioli.io/kills"
+	lastKillUsageAnnotation = "terminator.rubbioli.io/last-kill-usage"
+)
+
+// killedLabel and killReasonAnnotation mark the pods right before they are
+// killed, so their deletion can be told apart from any other.
+const (
+	killedLabel          = "terminator.rubbioli.io/killed"
+	killReasonAnnotation = "terminator.rubbioli.io/kill-reason"
+)
+
+// disruptionTarget is the condition set on pods about to be deleted, like the
+// one of the evictions of Kubernetes, with disruptionReason as its reason.
+const (
+	disruptionTarget v1.PodConditionType = "DisruptionTarget"
+	disruptionReason                     = "TerminatedByOOMTerminator"
+)
+
+// markDisrupted sets the disruptionTarget condition on the status of pod, so
+// controllers, autoscalers and post-mortems can tell its deletion apart from a
+// crash or a node failure.
+func (t terminator) markDisrupted(ctx context.Context, pod *v1.Pod, message string) error {
+	return t.setDisrupted(ctx, pod, v1.ConditionTrue, message)
+}
+
+// unmarkDisrupted turns the disruptionTarget condition of pod off, once it
+// couldn't be killed after all.
+func (t terminator) unmarkDisrupted(ctx context.Context, pod *v1.Pod, message string) error {
+	return t.setDisrupted(ctx, pod, v1.ConditionFalse, message)
+}
+
+func (t terminator) setDisrupted(ctx context.Context, pod *v1.Pod, status v1.ConditionStatus, message string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []v1.PodCondition{{
+				Type:               disruptionTarget,
+				Status:             status,
+				Reason:             disruptionReason,
+				Message:            message,
+				LastTransitionTime: metav1.NewTime(t.clock.Now()),
+			}},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.StrategicMergePatchType, patch, metav1.PatchOptions{}, "status")
+	return err
+}
+
+// markKilled labels pod as killed by the terminator, with the reason.
+func (t terminator) markKilled(ctx context.Context, pod *v1.Pod, reason string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels":      map[string]string{killedLabel: "true"},
+			"annotations": map[string]string{killReasonAnnotation: reason},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// unmarkKilled removes the label and the reason of markKilled from pod, once
+// it couldn't be killed after all.
+func (t terminator) unmarkKilled(ctx context.Context, pod *v1.Pod) error {
+	patch := fmt.Sprintf(`{"metadata":{"labels":{%q:null},"annotations":{%q:null}}}`, killedLabel, killReasonAnnotation)
+	_, err := t.clientset.CoreV1().Pods(pod.Namespace).Patch(ctx, pod.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	return err
+}
+
+// annotateWorkload records the kill of pod on its deployment or statefulset:
+// when it happened, the total kills and the usage that triggered it, so owners
+// see the history right on their object.
+func (t terminator) annotateWorkload(ctx context.Context, pod *v1.Pod, s sample, now time.Time) error {
+	kind, name, _ := strings.Cut(workloadName(pod), "/")
+
+	var annotations map[string]string
+	switch kind {
+	case "deployment":
+		deployment, err := t.clientset.AppsV1().Deployments(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		annotations = deployment.Annotations
+	case "statefulset":
+		statefulSet, err := t.clientset.AppsV1().StatefulSets(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		annotations = statefulSet.Annotations
+	default:
+		return nil
+	}
+
+	kills, _ := strconv.Atoi(annotations[killsAnnotation])
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				lastKillAnnotation:      now.UTC().Format(time.RFC3339),
+				killsAnnotation:         strconv.Itoa(kills + 1),
+				lastKillUsageAnnotation: fmt.Sprintf("%s/%s (%.f%%)", s.using.String(), s.limit.String(), s.percentage),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "deployment":
+		_, err = t.clientset.AppsV1().Deployments(pod.Namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "statefulset":
+		_, err = t.clientset.AppsV1().StatefulSets(pod.Namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	}
+	return err
+}
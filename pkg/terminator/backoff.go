@@ -0,0 +1,184 @@
+package terminator
+
+import (
+	"fmt"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const eventRepeatOffender = "repeat_offender"
+
+// maxBackoffDoublings caps how many times kill-after and cooldown are doubled.
+const maxBackoffDoublings = 10
+
+// repeatKills are the recent kills of pods of a workload.
+type repeatKills struct {
+	count     int
+	last      time.Time
+	escalated bool
+	// history are the last maxKillHistory kills
+	history []KillRecord
+	// usage are the last maxUsageSamples memory usages of the pods of the
+	// workload since its first kill, in bytes, and limit their last limit
+	usage []float64
+	limit resource.Quantity
+}
+
+// maxUsageSamples caps the usages of a workload kept to suggest its limit.
+const maxUsageSamples = 1000
+
+// suggestedLimitAggregation is the usage of a workload killed repeatedly
+// its suggested limit is based on.
+const suggestedLimitAggregation = "p99"
+
+// LimitSuggestion is the memory limit suggested for the pods of a workload
+// killed repeatedly: the p99 of their usage plus the LimitHeadroom.
+type LimitSuggestion struct {
+	Limit   resource.Quantity `json:"limit"`
+	Current resource.Quantity `json:"current"`
+	// Samples are the usages the suggestion is based on
+	Samples int `json:"samples"`
+	// Containers are the suggested limits of each container, split by their
+	// current limits
+	Containers map[string]resource.Quantity `json:"containers,omitempty"`
+}
+
+// maxKillHistory caps the kills of a workload kept for its escalation.
+const maxKillHistory = 20
+
+// KillRecord is a kill of a pod of a workload killed repeatedly, with its
+// usage, attached to its repeat_offender notification.
+type KillRecord struct {
+	Time       time.Time `json:"time"`
+	Pod        string    `json:"pod"`
+	Usage      int64     `json:"usage"`
+	Limit      int64     `json:"limit"`
+	Percentage float64   `json:"percentage"`
+	// OverCount are the checks the pod was over the limit for
+	OverCount int `json:"overCount"`
+}
+
+// backoff doubles the kill-after and cooldown of policy for each pod of the
+// workload killed within the RepeatWindow, so workloads that keep going over
+// the limit are recycled less and less often.
+func (t terminator) backoff(c *check, key string, p policy, now time.Time) policy {
+	repeats, ok := c.state.repeatKills[key]
+	if t.options.RepeatWindow == 0 || !ok {
+		return p
+	}
+
+	doublings := repeats.count
+	if doublings > maxBackoffDoublings {
+		doublings = maxBackoffDoublings
+	}
+
+	killAfter := p.killAfter
+	if killAfter < 1 {
+		killAfter = 1
+	}
+	p.killAfter = killAfter << doublings
+
+	cooldown := p.cooldown
+	if cooldown < t.options.RepeatCooldown {
+		cooldown = t.options.RepeatCooldown
+	}
+	p.cooldown = cooldown << doublings
+	for _, setting := range []int{settingKillAfter, settingCooldown} {
+		p.provenance[setting].Name += fmt.Sprintf(", doubled %d times by the repeat-window", doublings)
+	}
+	return p
+}
+
+// escalate tells whether the workload of pod was killed too many times within
+// the RepeatWindow, in which case its pods are not killed anymore and its
+// notification is added to events once.
+func (t terminator) escalate(c *check, pod *v1.Pod, key string, reason *Reason, events *[]Event) bool {
+	repeats, ok := c.state.repeatKills[key]
+	if t.options.RepeatWindow == 0 || t.options.MaxRepeatKills == 0 || !ok || repeats.count < t.options.MaxRepeatKills {
+		return false
+	}
+
+	reason.skip(protectionRepeatOffender)
+	if !repeats.escalated {
+		repeats.escalated = true
+		workload := workloadName(pod)
+		message := fmt.Sprintf("not deleting pods of %s anymore, %d of them were deleted in the last %s, it needs to be fixed", workload, repeats.count, t.options.RepeatWindow)
+		suggestion := t.suggestLimit(pod, repeats)
+		if suggestion != nil {
+			message = fmt.Sprintf("%s, like with a memory limit of %s instead of %s", message, suggestion.Limit.String(), suggestion.Current.String())
+		}
+		t.out.Warnf("%s", message)
+		history := append([]KillRecord(nil), repeats.history...)
+		*events = append(*events, Event{Type: eventRepeatOffender, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Reason: reason.snapshot(), History: history, Suggestion: suggestion})
+	}
+	t.log.Infof("not deleting pod < %s >, its workload is a repeat offender", pod.Name)
+	return true
+}
+
+// suggestLimit returns the limit suggested for the pods of the workload of pod
+// from the usages in repeats, or nil when there are none.
+func (t terminator) suggestLimit(pod *v1.Pod, repeats *repeatKills) *LimitSuggestion {
+	if len(repeats.usage) == 0 {
+		return nil
+	}
+
+	limit := suggestedLimit(repeats.usage, suggestedLimitAggregation, t.options.LimitHeadroom)
+	suggestion := &LimitSuggestion{Limit: roundedQuantity(limit), Current: repeats.limit.DeepCopy(), Samples: len(repeats.usage), Containers: make(map[string]resource.Quantity)}
+	for i, share := range containerShares(pod.Spec.Containers) {
+		suggestion.Containers[pod.Spec.Containers[i].Name] = roundedQuantity(limit * share)
+	}
+	return suggestion
+}
+
+// recordUsage keeps the usage of pod when its workload was killed within the
+// RepeatWindow, to suggest its limit once it is a repeat offender.
+func (t terminator) recordUsage(c *check, pod *v1.Pod, using, limit *resource.Quantity) {
+	if t.options.RepeatWindow == 0 || t.options.MaxRepeatKills == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	repeats, ok := c.state.repeatKills[workloadKey(pod)]
+	if !ok {
+		return
+	}
+	repeats.usage = append(repeats.usage, float64(using.Value()))
+	repeats.limit = limit.DeepCopy()
+	if len(repeats.usage) > maxUsageSamples {
+		repeats.usage = repeats.usage[len(repeats.usage)-maxUsageSamples:]
+	}
+}
+
+// recordRepeatKill counts the kill of a pod of the workload key, keeping its
+// record.
+func (t terminator) recordRepeatKill(c *check, key string, record KillRecord) {
+	if t.options.RepeatWindow == 0 {
+		return
+	}
+
+	repeats, ok := c.state.repeatKills[key]
+	if !ok {
+		repeats = &repeatKills{}
+		c.state.repeatKills[key] = repeats
+	}
+	repeats.count++
+	repeats.last = record.Time
+	repeats.history = append(repeats.history, record)
+	if len(repeats.history) > maxKillHistory {
+		repeats.history = repeats.history[len(repeats.history)-maxKillHistory:]
+	}
+}
+
+// expireRepeatKills forgets the workloads without kills within the
+// RepeatWindow.
+func (t terminator) expireRepeatKills(state *state, now time.Time) {
+	for key, repeats := range state.repeatKills {
+		if now.Sub(repeats.last) > t.options.RepeatWindow {
+			t.log.Infof("Workload %s was not deleted for %s, resetting its backoff", key, t.options.RepeatWindow)
+			delete(state.repeatKills, key)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// blackoutAnnotation on a namespace holds blackout windows for its pods,
+// separated by semicolons, like "Sat 00:00-Sun 23:59; Fri 18:00-22:00".
+const blackoutAnnotation = "terminator.rubbioli.io/blackout"
+
+// inBlackout reports whether kills in namespace are blacked out at now, either
+// by the Blackout flag or by its annotation.
+func (t terminator) inBlackout(ctx context.Context, c *check, namespace string, now time.Time) (bool, error) {
+	if t.options.Blackout != nil && t.options.Blackout.Contains(now) {
+		return true, nil
+	}
+
+	ns, err := t.namespace(ctx, c, namespace)
+	if err != nil {
+		return false, err
+	}
+
+	annotation := ns.Annotations[blackoutAnnotation]
+	if annotation == "" {
+		return false, nil
+	}
+
+	schedule, err := ParseSchedule(strings.Split(annotation, ";"), t.options.Location)
+	if err != nil {
+		t.log.Errorf("ignoring blackout of namespace %s: %s", namespace, err)
+		return false, nil
+	}
+
+	return schedule.Contains(now), nil
+}
+
+// reportBlackout notifies the kills that were deferred by a blackout once it is
+// over, when ReportBlackout is set.
+func (t terminator) reportBlackout(ctx context.Context, c *check) {
+	now := t.clock.Now()
+	for key, event := range c.state.deferredKills {
+		blackout, err := t.inBlackout(ctx, c, event.Namespace, now)
+		if err != nil {
+			t.operationalError(ctx, c, event.Namespace, "", "could not check blackout of namespace %s: %s", event.Namespace, err)
+			continue
+		}
+		if blackout {
+			continue
+		}
+
+		delete(c.state.deferredKills, key)
+		if t.options.ReportBlackout {
+			event.Type = eventKillDeferred
+			event.Message = fmt.Sprintf("pod %s would have been deleted during the blackout at %s", event.Pod, event.Time.Format(time.RFC3339))
+			t.out.Print(event.Message)
+			t.notify(ctx, event)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/common/expfmt"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+const MetricsSourceCAdvisor = "cadvisor"
+
+// cadvisorMaxAge is how long the metrics scraped from a node are used for, so
+// pods of the same node on a check share a scrape.
+const cadvisorMaxAge = time.Second
+
+// cadvisorNodesTTL is how long the nodes matching the selector are cached.
+const cadvisorNodesTTL = time.Minute
+
+// cadvisorScrape is the last scrape of a node. Its mu is held while the node is
+// scraped, so the pods of a node wait for a single scrape while the ones of
+// other nodes scrape theirs.
+type cadvisorScrape struct {
+	mu sync.Mutex
+	at time.Time
+	// usage is the memory of containers, by namespace/pod/container
+	usage map[string]int64
+}
+
+type cadvisorProvider struct {
+	clientset kubernetes.Interface
+	metric    string
+	selector  labels.Selector
+	fallback  MetricsProvider
+
+	mu          sync.Mutex
+	scrapes     map[string]*cadvisorScrape
+	nodes       map[string]bool
+	nodesListed time.Time
+}
+
+// NewCAdvisorProvider returns a MetricsProvider scraping the cAdvisor of the
+// nodes through the API server proxy, without the lag of metrics-server. The
+// memoryMetric of containers is read, and pods on nodes not matching selector
+// get their usage from fallback.
+func NewCAdvisorProvider(clientset kubernetes.Interface, memoryMetric string, selector labels.Selector, fallback MetricsProvider) MetricsProvider {
+	metric := "container_memory_working_set_bytes"
+	switch memoryMetric {
+	case MemoryMetricRSS:
+		metric = "container_memory_rss"
+	case MemoryMetricUsage:
+		metric = "container_memory_usage_bytes"
+	}
+
+	return &cadvisorProvider{
+		clientset: clientset,
+		metric:    metric,
+		selector:  selector,
+		fallback:  fallback,
+		scrapes:   make(map[string]*cadvisorScrape),
+	}
+}
+
+func (p *cadvisorProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	if pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+
+	// the kubelets of Windows nodes serve no cAdvisor metrics
+	selected, err := p.selected(ctx, pod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+	if !selected || windowsSpec(pod) {
+		return p.fallback.PodUsage(ctx, pod)
+	}
+
+	usage, err := p.scrape(ctx, pod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	total := resource.NewQuantity(0, resource.BinarySI)
+	for _, container := range pod.Spec.Containers {
+		if bytes, ok := usage[pod.Namespace+"/"+pod.Name+"/"+container.Name]; ok {
+			found = true
+			total.Add(*resource.NewQuantity(bytes, resource.BinarySI))
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return total, nil
+}
+
+// selected tells whether node matches the node selector.
+func (p *cadvisorProvider) selected(ctx context.Context, node string) (bool, error) {
+	if p.selector == nil || p.selector.Empty() {
+		return true, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.nodes == nil || time.Since(p.nodesListed) > cadvisorNodesTTL {
+		nodes, err := p.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: p.selector.String()})
+		if err != nil {
+			return false, err
+		}
+
+		p.nodes = make(map[string]bool, len(nodes.Items))
+		for _, node := range nodes.Items {
+			p.nodes[node.Name] = true
+		}
+		p.nodesListed = time.Now()
+	}
+	return p.nodes[node], nil
+}
+
+// scrape returns the memory of the containers of node, scraping its cAdvisor
+// unless it was scraped in the last cadvisorMaxAge.
+func (p *cadvisorProvider) scrape(ctx context.Context, node string) (map[string]int64, error) {
+	p.mu.Lock()
+	scrape, ok := p.scrapes[node]
+	if !ok {
+		scrape = &cadvisorScrape{}
+		p.scrapes[node] = scrape
+	}
+	p.mu.Unlock()
+
+	scrape.mu.Lock()
+	defer scrape.mu.Unlock()
+
+	if scrape.usage != nil && time.Since(scrape.at) < cadvisorMaxAge {
+		return scrape.usage, nil
+	}
+
+	data, err := p.clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("metrics", "cadvisor").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid cadvisor metrics of node %s: %w", node, err)
+	}
+
+	usage := make(map[string]int64)
+	if family, ok := families[p.metric]; ok {
+		for _, metric := range family.Metric {
+			var namespace, pod, container string
+			for _, label := range metric.Label {
+				switch label.GetName() {
+				case "namespace":
+					namespace = label.GetValue()
+				case "pod":
+					pod = label.GetValue()
+				case "container":
+					container = label.GetValue()
+				}
+			}
+			if pod == "" || container == "" || container == "POD" {
+				continue
+			}
+
+			var value float64
+			switch {
+			case metric.Gauge != nil:
+				value = metric.Gauge.GetValue()
+			case metric.Untyped != nil:
+				value = metric.Untyped.GetValue()
+			}
+			usage[namespace+"/"+pod+"/"+container] = int64(value)
+		}
+	}
+
+	scrape.at, scrape.usage = time.Now(), usage
+	return usage, nil
+}
@@ -0,0 +1,83 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// cloudEventsContentType is the content type of structured CloudEvents.
+const cloudEventsContentType = "application/cloudevents+json"
+
+// cloudEventTypePrefix prefixes the type of events, like
+// io.oomterminator.pod_killed.
+const cloudEventTypePrefix = "io.oomterminator."
+
+// cloudEvent is an Event in the structured mode of CloudEvents 1.0.
+type cloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Event     `json:"data"`
+}
+
+func newCloudEvent(event Event) cloudEvent {
+	source := "/oomterminator"
+	if event.Cluster != "" {
+		source += "/" + event.Cluster
+	}
+	return cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              string(uuid.NewUUID()),
+		Source:          source,
+		Type:            cloudEventTypePrefix + event.Type,
+		Subject:         event.Namespace + "/" + event.Pod,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            event,
+	}
+}
+
+type cloudEventsNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewCloudEventsNotifier returns a Notifier POSTing events to url as
+// structured CloudEvents, for sinks like Knative brokers and Argo Events.
+func NewCloudEventsNotifier(url string) Notifier {
+	return cloudEventsNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n cloudEventsNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(newCloudEvent(event))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", cloudEventsContentType)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cloudevents sink returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package terminator
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const MetricsSourceCloudWatch = "cloudwatch"
+
+// cloudWatchWindow is how far back the latest datapoint of a pod is looked
+// for. Container Insights publishes once a minute.
+const cloudWatchWindow = 5 * time.Minute
+
+// CloudWatchOptions configures the CloudWatch Container Insights metrics
+// source. Credentials come from the default AWS chain.
+type CloudWatchOptions struct {
+	// Region defaults to the region of the default AWS chain
+	Region string
+	// ClusterName is the EKS cluster name of the Container Insights metrics
+	ClusterName string
+}
+
+type cloudWatchProvider struct {
+	client      *cloudwatch.Client
+	clusterName string
+}
+
+// NewCloudWatchProvider returns a MetricsProvider reading the
+// pod_memory_working_set metric of CloudWatch Container Insights.
+func NewCloudWatchProvider(ctx context.Context, options CloudWatchOptions) (MetricsProvider, error) {
+	var opts []func(*config.LoadOptions) error
+	if options.Region != "" {
+		opts = append(opts, config.WithRegion(options.Region))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return cloudWatchProvider{
+		client:      cloudwatch.NewFromConfig(cfg),
+		clusterName: options.ClusterName,
+	}, nil
+}
+
+func (p cloudWatchProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	now := time.Now()
+	output, err := p.client.GetMetricData(ctx, &cloudwatch.GetMetricDataInput{
+		StartTime: aws.Time(now.Add(-cloudWatchWindow)),
+		EndTime:   aws.Time(now),
+		ScanBy:    types.ScanByTimestampDescending,
+		MetricDataQueries: []types.MetricDataQuery{{
+			Id: aws.String("memory"),
+			MetricStat: &types.MetricStat{
+				Metric: &types.Metric{
+					Namespace:  aws.String("ContainerInsights"),
+					MetricName: aws.String("pod_memory_working_set"),
+					Dimensions: []types.Dimension{
+						{Name: aws.String("ClusterName"), Value: aws.String(p.clusterName)},
+						{Name: aws.String("Namespace"), Value: aws.String(pod.Namespace)},
+						{Name: aws.String("PodName"), Value: aws.String(pod.Name)},
+					},
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Maximum"),
+			},
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range output.MetricDataResults {
+		if len(result.Values) > 0 {
+			return resource.NewQuantity(int64(result.Values[0]), resource.BinarySI), nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,121 @@
+package terminator
+
+import (
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Condition is a CEL expression deciding whether a pod is killed, replacing
+// the limit and kill-after checks. It has access to:
+//
+//	usage.pct, usage.bytes, usage.limit, usage.gpuPct, usage.pids, usage.pidsLimit,
+//	usage.swap
+//	pod.name, pod.namespace, pod.workload, pod.labels, pod.annotations
+//	history.overCount, the amount of checks the pod has been over the limit
+//	custom, the values of the custom metrics of the pod by name
+//	external, the values of the external metrics for its namespace by name
+//
+// For example: usage.pct > 90 && !('critical' in pod.labels) && history.overCount >= 3
+//
+// Indexing a missing label, annotation or metric is an error, so keys pods may
+// not have are tested first, like has(pod.labels.tier) && pod.labels.tier !=
+// 'critical' or 'tier' in pod.labels. Pods the condition fails on are not
+// killed, and the error is reported.
+type Condition struct {
+	expression string
+	program    cel.Program
+}
+
+var conditionEnv *cel.Env
+
+func init() {
+	var err error
+	conditionEnv, err = cel.NewEnv(
+		cel.CrossTypeNumericComparisons(true),
+		cel.Declarations(
+			decls.NewVar("usage", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("pod", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("history", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("custom", decls.NewMapType(decls.String, decls.Double)),
+			decls.NewVar("external", decls.NewMapType(decls.String, decls.Double)),
+		),
+	)
+	if err != nil {
+		panic(err)
+	}
+}
+
+func CompileCondition(expression string) (*Condition, error) {
+	ast, issues := conditionEnv.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expression, issues.Err())
+	}
+
+	program, err := conditionEnv.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("invalid condition %q: %w", expression, err)
+	}
+
+	return &Condition{expression: expression, program: program}, nil
+}
+
+func (c *Condition) String() string {
+	return c.expression
+}
+
+// matches evaluates the condition for a sample of pod that has been over the
+// limit for overCount checks.
+func (c *Condition) matches(pod *v1.Pod, s sample, overCount int) (bool, error) {
+	out, _, err := c.program.Eval(map[string]interface{}{
+		"usage": map[string]interface{}{
+			"pct":       s.percentage,
+			"bytes":     s.using.Value(),
+			"limit":     s.limit.Value(),
+			"gpuPct":    s.gpuPercentage,
+			"pids":      s.pids,
+			"pidsLimit": s.pidsLimit,
+			"swap":      s.swap,
+		},
+		"pod": map[string]interface{}{
+			"name":        pod.Name,
+			"namespace":   pod.Namespace,
+			"workload":    workloadName(pod),
+			"labels":      stringMap(pod.Labels),
+			"annotations": stringMap(pod.Annotations),
+		},
+		"history": map[string]interface{}{
+			"overCount": overCount,
+		},
+		"custom":   floatMap(s.custom),
+		"external": floatMap(s.external),
+	})
+	if err != nil {
+		return false, fmt.Errorf("could not evaluate condition %q: %w", c.expression, err)
+	}
+
+	matches, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q returned %v instead of a boolean", c.expression, out.Value())
+	}
+	return matches, nil
+}
+
+// floatMap never returns nil, like stringMap.
+func floatMap(m map[string]float64) map[string]float64 {
+	if m == nil {
+		return map[string]float64{}
+	}
+	return m
+}
+
+// stringMap never returns nil, so expressions can index labels and annotations
+// of pods without any.
+func stringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return map[string]string{}
+	}
+	return m
+}
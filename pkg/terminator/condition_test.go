@@ -0,0 +1,103 @@
+package terminator
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testReporter keeps the errors reported.
+type testReporter struct {
+	mu      sync.Mutex
+	reports []ErrorReport
+}
+
+func (r *testReporter) Report(ctx context.Context, report ErrorReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestConditionMatches(t *testing.T) {
+	using, limit := resource.MustParse("1000Mi"), resource.MustParse("1Gi")
+	s := sample{using: &using, limit: &limit, percentage: 97}
+	critical := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Labels: map[string]string{"tier": "critical"}}}
+	unlabeled := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api"}}
+	tests := []struct {
+		name       string
+		expression string
+		pod        *v1.Pod
+		expected   bool
+		fails      bool
+	}{
+		{"label", "pod.labels['tier'] != 'critical'", critical, false, false},
+		{"missing label", "pod.labels['tier'] != 'critical'", unlabeled, false, true},
+		{"has missing label", "!has(pod.labels.tier) || pod.labels.tier != 'critical'", unlabeled, true, false},
+		{"has label", "!has(pod.labels.tier) || pod.labels.tier != 'critical'", critical, false, false},
+		{"in missing label", "!('tier' in pod.labels) && usage.pct > 90", unlabeled, true, false},
+		{"missing custom metric", "custom['heap'] > 10", unlabeled, false, true},
+	}
+	for _, test := range tests {
+		condition, err := CompileCondition(test.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		matches, err := condition.matches(test.pod, s, 1)
+		if (err != nil) != test.fails {
+			t.Errorf("%s: expected failing %v, got %v", test.name, test.fails, err)
+		}
+		if matches != test.expected {
+			t.Errorf("%s: expected matching %v, got %v", test.name, test.expected, matches)
+		}
+	}
+}
+
+// TestConditionMissingKey checks a condition failing on a pod without one of
+// its labels reports the error and goes on with the check, not killing it.
+func TestConditionMissingKey(t *testing.T) {
+	condition, err := CompileCondition("pod.labels['tier'] != 'critical'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	action := &testAction{}
+	notifier := &testNotifier{}
+	reporter := &testReporter{}
+	debug := logrus.New()
+	debug.SetOutput(io.Discard)
+	clientset := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}}, optionTestPod("api"))
+	created, err := NewForClients(clientset, metricsfake.NewSimpleClientset(), Options{Workers: 1, Quiet: true, IncludeBarePods: true, Condition: condition},
+		WithMetricsProvider(testProvider{"api": "1000Mi"}), WithAction(action), WithNotifier(notifier), WithErrorReporter(reporter), WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminator := created.(terminator)
+	watcher, err := terminator.watchTargets(context.Background(), Targets{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := terminator.runCheck(context.Background(), watcher, Targets{}, newState(), terminator.defaultPolicy(95, 0), 0); err != nil {
+		t.Fatalf("expected the check to go on, got %s", err)
+	}
+	if len(action.killed) != 0 {
+		t.Errorf("expected no kills, got %v", action.killed)
+	}
+	if len(reporter.reports) != 1 || !strings.Contains(reporter.reports[0].Message, "no such key: tier") {
+		t.Errorf("expected the missing label to be reported, got %+v", reporter.reports)
+	}
+	if expected := []string{eventOverLimit}; !reflect.DeepEqual(notifier.events, expected) {
+		t.Errorf("expected the events %v, got %v", expected, notifier.events)
+	}
+}
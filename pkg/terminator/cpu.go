@@ -0,0 +1,81 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// CPUProvider is implemented by the metrics providers that also return the
+// CPU usage of pods, for the rules of policies.
+type CPUProvider interface {
+	// PodCPU returns the CPU usage of pod, or nil when it has no metrics
+	// yet.
+	PodCPU(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error)
+}
+
+// cpuUsage sums the CPU usage of the regular containers of pod.
+func cpuUsage(pod *v1.Pod, podMetrics *v1beta1.PodMetrics) *resource.Quantity {
+	containers := make(map[string]bool, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containers[container.Name] = true
+	}
+
+	usage := resource.NewMilliQuantity(0, resource.DecimalSI)
+	for _, container := range podMetrics.Containers {
+		if containers[container.Name] {
+			usage.Add(*container.Usage.Cpu())
+		}
+	}
+
+	return usage
+}
+
+// cpuLimit sums the CPU limits of the regular containers of pod, nil when one
+// of them has none.
+func cpuLimit(pod *v1.Pod) *resource.Quantity {
+	limit := resource.NewMilliQuantity(0, resource.DecimalSI)
+	for _, container := range pod.Spec.Containers {
+		containerLimit := container.Resources.Limits.Cpu()
+		if containerLimit.IsZero() {
+			return nil
+		}
+		limit.Add(*containerLimit)
+	}
+	return limit
+}
+
+// cpuPercentage returns the CPU usage of pod as a percentage of its limit,
+// zero when it has no limit or the metrics provider has no CPU.
+func (t terminator) cpuPercentage(ctx context.Context, pod *v1.Pod) (float64, error) {
+	limit := cpuLimit(pod)
+	if limit == nil {
+		t.log.Infof("pod < %s > has no CPU limit", pod.Name)
+		return 0, nil
+	}
+
+	provider, ok := t.provider.(CPUProvider)
+	if !ok {
+		t.log.Infof("the metrics provider has no CPU usage for pod < %s >", pod.Name)
+		return 0, nil
+	}
+
+	usage, err := provider.PodCPU(ctx, pod)
+	if err != nil || usage == nil {
+		return 0, err
+	}
+	return float64(usage.MilliValue()) / float64(limit.MilliValue()) * 100, nil
+}
+
+// restarts returns the highest restart count of the containers of pod.
+func restarts(pod *v1.Pod) int32 {
+	var highest int32
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount > highest {
+			highest = status.RestartCount
+		}
+	}
+	return highest
+}
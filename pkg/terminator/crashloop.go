@@ -0,0 +1,48 @@
+package terminator
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const reasonCrashLoopBackOff = "CrashLoopBackOff"
+
+// crashLoopWindow is how recent the last restart of a container must be for
+// its restart count to make it crash looping, so restarts of days ago don't
+// pause its workload forever.
+const crashLoopWindow = 10 * time.Minute
+
+// crashLoopingWorkloads returns the workloads of pods that have a container in
+// CrashLoopBackOff or that restarted at least maxRestarts times, the last one
+// recently. Killing more pods of those would only pile onto a workload that is
+// already failing.
+func crashLoopingWorkloads(pods []v1.Pod, maxRestarts int32, now time.Time) map[string]bool {
+	workloads := make(map[string]bool)
+	for i := range pods {
+		if isCrashLooping(&pods[i], maxRestarts, now) {
+			workloads[workloadKey(&pods[i])] = true
+		}
+	}
+	return workloads
+}
+
+// isCrashLooping tells whether a container of pod is in CrashLoopBackOff, or
+// restarted at least maxRestarts times with its last restart in the
+// crashLoopWindow. Restarts of containers last OOMKilled don't count, those are
+// the ones the terminator is there to prevent.
+func isCrashLooping(pod *v1.Pod, maxRestarts int32, now time.Time) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason == reasonCrashLoopBackOff {
+			return true
+		}
+		if maxRestarts <= 0 || status.RestartCount < maxRestarts {
+			continue
+		}
+		last := status.LastTerminationState.Terminated
+		if last != nil && last.Reason != reasonOOMKilled && now.Sub(last.FinishedAt.Time) < crashLoopWindow {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,103 @@
+package terminator
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// crashLoopTestPod is a replica of the db statefulset, restarted restarts
+// times, the last one a minute ago.
+func crashLoopTestPod(name string, restarts int32) *v1.Pod {
+	controller := true
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       "web",
+			UID:             types.UID(name),
+			OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: "db", Controller: &controller}},
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:      "app",
+			Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning, ContainerStatuses: []v1.ContainerStatus{{Name: "app", RestartCount: restarts}}},
+	}
+	if restarts > 0 {
+		pod.Status.ContainerStatuses[0].LastTerminationState.Terminated = &v1.ContainerStateTerminated{
+			Reason:     "Error",
+			FinishedAt: metav1.NewTime(time.Now().Add(-time.Minute)),
+		}
+	}
+	return pod
+}
+
+// TestCrashLoopingSiblings checks the replicas of a workload over the limit
+// aren't killed while another one is crash looping, even when the crash
+// looping one is dropped by the shortlist of the two phases.
+func TestCrashLoopingSiblings(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		restarts int32
+		kills    int
+	}{
+		{"crash looping", Options{MaxRestarts: 3}, 5, 0},
+		{"crash looping in two phases", Options{MaxRestarts: 3, TwoPhase: true, CandidatePercentage: 80}, 5, 0},
+		{"restarted in two phases", Options{MaxRestarts: 3, TwoPhase: true, CandidatePercentage: 80}, 1, 1},
+	}
+	for _, test := range tests {
+		action := &testAction{}
+		debug := logrus.New()
+		debug.SetOutput(io.Discard)
+		objects := []runtime.Object{&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}}, crashLoopTestPod("db-0", 0), crashLoopTestPod("db-1", test.restarts)}
+		options := test.options
+		options.Workers, options.Quiet = 1, true
+		created, err := NewForClients(fake.NewSimpleClientset(objects...), metricsfake.NewSimpleClientset(), options,
+			WithMetricsProvider(testProvider{"db-0": "1000Mi", "db-1": "10Mi"}), WithAction(action), WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		terminator := created.(terminator)
+		watcher, err := terminator.watchTargets(context.Background(), Targets{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := terminator.runCheck(context.Background(), watcher, Targets{}, newState(), terminator.defaultPolicy(95, 0), 0); err != nil {
+			t.Fatal(err)
+		}
+		if len(action.killed) != test.kills {
+			t.Errorf("%s: expected %d kills, got %v", test.name, test.kills, action.killed)
+		}
+	}
+}
+
+func TestValidateMaxRestarts(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		valid   bool
+	}{
+		{"max restarts", Options{MaxRestarts: 3}, true},
+		{"metadata only", Options{MetadataOnly: true}, true},
+		{"max restarts with metadata only", Options{MaxRestarts: 3, MetadataOnly: true}, false},
+	}
+	for _, test := range tests {
+		test.options.Workers = 1
+		if err := test.options.validate(); (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %v, got %v", test.name, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/metrics/pkg/client/custom_metrics"
+)
+
+// CustomMetric is a metric of pods from the custom metrics API, like the JVM
+// heap used, from which pods are over the limit once it reaches Threshold.
+// A zero Threshold makes it only available to conditions.
+type CustomMetric struct {
+	Name      string
+	Threshold resource.Quantity
+}
+
+// ParseCustomMetric parses a custom metric like jvm_memory_used_bytes=1500Mi,
+// or just its name.
+func ParseCustomMetric(metric string) (CustomMetric, error) {
+	name, threshold, hasThreshold := strings.Cut(metric, "=")
+	if name == "" {
+		return CustomMetric{}, fmt.Errorf("invalid custom metric %q, must be name=threshold", metric)
+	}
+
+	custom := CustomMetric{Name: name}
+	if hasThreshold {
+		quantity, err := resource.ParseQuantity(threshold)
+		if err != nil {
+			return CustomMetric{}, fmt.Errorf("invalid threshold of custom metric %s: %w", name, err)
+		}
+		custom.Threshold = quantity
+	}
+	return custom, nil
+}
+
+var podGroupKind = schema.GroupKind{Kind: "Pod"}
+
+// customMetrics returns the values of the custom metrics of pod. Each metric is
+// fetched for every pod of a namespace once on each check, a metric that
+// can't be fetched is logged and left out.
+func (t terminator) customMetrics(ctx context.Context, c *check, pod *v1.Pod) map[string]float64 {
+	if t.customMetricsClient == nil || len(t.options.CustomMetrics) == 0 {
+		return nil
+	}
+
+	values := make(map[string]float64, len(t.options.CustomMetrics))
+	for _, metric := range t.options.CustomMetrics {
+		namespaceValues, err := t.namespaceCustomMetric(c, pod.Namespace, metric.Name)
+		if err != nil {
+			t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not get custom metric %s of namespace %s: %s", metric.Name, pod.Namespace, err)
+			continue
+		}
+		if value, ok := namespaceValues[pod.Name]; ok {
+			values[metric.Name] = value
+		}
+	}
+	return values
+}
+
+func (t terminator) namespaceCustomMetric(c *check, namespace, name string) (map[string]float64, error) {
+	values, err := c.customMetrics.get(namespace+"/"+name, func() (interface{}, error) {
+		list, err := t.customMetricsClient.NamespacedMetrics(namespace).GetForObjects(podGroupKind, labels.Everything(), name, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+
+		values := make(map[string]float64, len(list.Items))
+		for _, item := range list.Items {
+			values[item.DescribedObject.Name] = item.Value.AsApproximateFloat64()
+		}
+		return values, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values.(map[string]float64), nil
+}
+
+// overCustom returns the first custom metric of s over its threshold, if any.
+func (t terminator) overCustom(s sample) (string, bool) {
+	for _, metric := range t.options.CustomMetrics {
+		value, ok := s.custom[metric.Name]
+		if ok && !metric.Threshold.IsZero() && value >= metric.Threshold.AsApproximateFloat64() {
+			return metric.Name, true
+		}
+	}
+	return "", false
+}
+
+// newCustomMetricsClient returns a client of the custom metrics API, picking
+// its version by discovery.
+func newCustomMetricsClient(config *rest.Config, discovery discovery.DiscoveryInterface) custom_metrics.CustomMetricsClient {
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discovery))
+	return custom_metrics.NewForConfig(config, mapper, custom_metrics.NewAvailableAPIsGetter(discovery))
+}
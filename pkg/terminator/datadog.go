@@ -0,0 +1,126 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"text/template"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const MetricsSourceDatadog = "datadog"
+
+// DefaultDatadogQuery is the working set of the containers of a pod reported
+// by the Datadog agent.
+const DefaultDatadogQuery = "sum:kubernetes.memory.working_set{kube_namespace:{{.Namespace}},pod_name:{{.Name}}}"
+
+// datadogWindow is how far back the latest point of a pod is looked for.
+const datadogWindow = 5 * time.Minute
+
+// DatadogOptions configures the Datadog metrics source.
+type DatadogOptions struct {
+	// Site is the Datadog site, like datadoghq.com or datadoghq.eu
+	Site   string
+	APIKey string
+	AppKey string
+	// Query is the template of the metrics query of a pod, executed with the
+	// pod, returning its memory usage in bytes
+	Query string
+}
+
+type datadogProvider struct {
+	url    string
+	apiKey string
+	appKey string
+	query  *template.Template
+	client *http.Client
+}
+
+// NewDatadogProvider returns a MetricsProvider querying the Datadog metrics
+// API, for clusters with the Datadog agent and no metrics-server.
+func NewDatadogProvider(options DatadogOptions) (MetricsProvider, error) {
+	site := options.Site
+	if site == "" {
+		site = "datadoghq.com"
+	}
+
+	query := options.Query
+	if query == "" {
+		query = DefaultDatadogQuery
+	}
+	parsed, err := template.New("query").Parse(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid datadog query: %w", err)
+	}
+
+	return datadogProvider{
+		url:    "https://api." + site + "/api/v1/query",
+		apiKey: options.APIKey,
+		appKey: options.AppKey,
+		query:  parsed,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p datadogProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	var query bytes.Buffer
+	if err := p.query.Execute(&query, pod); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	params := url.Values{
+		"query": {query.String()},
+		"from":  {strconv.FormatInt(now.Add(-datadogWindow).Unix(), 10)},
+		"to":    {strconv.FormatInt(now.Unix(), 10)},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("DD-API-KEY", p.apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", p.appKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("datadog returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Series []struct {
+			Pointlist [][2]*float64 `json:"pointlist"`
+		} `json:"series"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid datadog response: %w", err)
+	}
+
+	// the latest point of each series, as a query may return several
+	found := false
+	var usage float64
+	for _, series := range result.Series {
+		for i := len(series.Pointlist) - 1; i >= 0; i-- {
+			if value := series.Pointlist[i][1]; value != nil {
+				found = true
+				usage += *value
+				break
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	return resource.NewQuantity(int64(usage), resource.BinarySI), nil
+}
@@ -0,0 +1,57 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const eventDegraded = "degraded"
+
+// metricsFailed records that the metrics of a pod couldn't be fetched on the
+// check, which skips the pod.
+func (c *check) metricsFailed(err error) {
+	c.defaultsMu.Lock()
+	defer c.defaultsMu.Unlock()
+	c.metricsFailures++
+	c.metricsErr = err
+}
+
+// metricsFetched records that the metrics of a pod were fetched on the check.
+func (c *check) metricsFetched() {
+	c.defaultsMu.Lock()
+	defer c.defaultsMu.Unlock()
+	c.metricsOK++
+}
+
+// updateDegraded tells whether the terminator is degraded after the check c,
+// when the metrics of none of the pods could be fetched. Becoming degraded and
+// recovering are notified, and the time spent degraded doesn't count towards
+// the expiration of the pods over the limit, so they are not forgotten during
+// the outage.
+func (t terminator) updateDegraded(ctx context.Context, c *check, now time.Time) bool {
+	state := c.state
+	if c.metricsFailures > 0 && c.metricsOK == 0 {
+		degraded.WithLabelValues(t.options.Cluster).Set(1)
+		if state.degradedSince.IsZero() {
+			state.degradedSince = now
+			message := fmt.Sprintf("metrics are unavailable, not killing pods until they are back: %s", c.metricsErr)
+			t.out.Errorf("%s", message)
+			t.notify(ctx, Event{Type: eventDegraded, Message: message})
+		}
+		return true
+	}
+
+	degraded.WithLabelValues(t.options.Cluster).Set(0)
+	if !state.degradedSince.IsZero() {
+		outage := now.Sub(state.degradedSince)
+		for _, over := range state.podsToKill {
+			over.at = over.at.Add(outage)
+		}
+		state.degradedSince = time.Time{}
+		message := fmt.Sprintf("metrics are available again after %s", outage.Round(time.Second))
+		t.out.Print(message)
+		t.notify(ctx, Event{Type: eventRecovered, Message: message})
+	}
+	return false
+}
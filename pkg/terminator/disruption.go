@@ -0,0 +1,78 @@
+package terminator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DisruptionBudget is the maximum amount of kills of pods of a workload within
+// a Window, regardless of its PodDisruptionBudgets.
+type DisruptionBudget struct {
+	Kills  int
+	Window time.Duration
+}
+
+// ParseDisruptionBudget parses a budget like 1/30m, one kill every 30 minutes.
+func ParseDisruptionBudget(budget string) (*DisruptionBudget, error) {
+	kills, window, ok := strings.Cut(budget, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid disruption budget %q, must be kills/window like 1/30m", budget)
+	}
+
+	parsed := &DisruptionBudget{}
+	var err error
+	parsed.Kills, err = strconv.Atoi(kills)
+	if err != nil || parsed.Kills < 1 {
+		return nil, fmt.Errorf("invalid disruption budget %q, must be kills/window like 1/30m", budget)
+	}
+	parsed.Window, err = time.ParseDuration(window)
+	if err != nil || parsed.Window <= 0 {
+		return nil, fmt.Errorf("invalid disruption budget %q, must be kills/window like 1/30m", budget)
+	}
+	return parsed, nil
+}
+
+func (b DisruptionBudget) String() string {
+	return fmt.Sprintf("%d/%s", b.Kills, b.Window)
+}
+
+// disrupted tells whether the workload key already had its MaxDisruptions
+// kills within the window before now.
+func (t terminator) disrupted(c *check, key string, now time.Time) bool {
+	budget := t.options.MaxDisruptions
+	return budget != nil && len(recentKills(c.state.disruptions[key], budget.Window, now)) >= budget.Kills
+}
+
+// recordDisruption counts a kill of a pod of the workload key.
+func (t terminator) recordDisruption(c *check, key string, now time.Time) {
+	if t.options.MaxDisruptions == nil {
+		return
+	}
+	c.state.disruptions[key] = append(recentKills(c.state.disruptions[key], t.options.MaxDisruptions.Window, now), now)
+}
+
+// expireDisruptions forgets the kills older than the window of MaxDisruptions.
+func (t terminator) expireDisruptions(state *state, now time.Time) {
+	if t.options.MaxDisruptions == nil {
+		return
+	}
+
+	for key, kills := range state.disruptions {
+		kills = recentKills(kills, t.options.MaxDisruptions.Window, now)
+		if len(kills) == 0 {
+			delete(state.disruptions, key)
+		} else {
+			state.disruptions[key] = kills
+		}
+	}
+}
+
+// recentKills returns the kills within window before now, oldest first.
+func recentKills(kills []time.Time, window time.Duration, now time.Time) []time.Time {
+	for len(kills) > 0 && now.Sub(kills[0]) >= window {
+		kills = kills[1:]
+	}
+	return kills
+}
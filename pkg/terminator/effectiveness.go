@@ -0,0 +1,66 @@
+package terminator
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var killLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "terminator_kill_latency_seconds",
+	Help:    "Time from the first check a pod was over the limit to its kill",
+	Buckets: prometheus.ExponentialBuckets(15, 2, 10),
+}, []string{"cluster", "namespace"})
+
+var oomsPrevented = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_ooms_prevented_total",
+	Help: "Amount of pods killed over the limit before they were OOMKilled",
+}, []string{"cluster", "namespace", "workload"})
+
+var oomsMissed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_ooms_missed_total",
+	Help: "Amount of targeted containers OOMKilled before being killed, by whether they were over the limit",
+}, []string{"cluster", "namespace", "workload", "over_limit"})
+
+var effectivenessRatio = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "terminator_effectiveness_ratio",
+	Help: "OOMs prevented out of the OOMs prevented and missed since the terminator started, by workload",
+}, []string{"cluster", "namespace", "workload"})
+
+// outcomes are the OOMs prevented and missed of a workload.
+type outcomes struct {
+	prevented int
+	missed    int
+}
+
+// recordPrevented records the kill of a pod of workload that was first over
+// the limit at firstSeen, counting it as an OOM prevented.
+func (t terminator) recordPrevented(state *state, namespace, workload string, firstSeen, now time.Time) {
+	killLatency.WithLabelValues(t.options.Cluster, namespace).Observe(now.Sub(firstSeen).Seconds())
+	oomsPrevented.WithLabelValues(t.options.Cluster, namespace, workload).Inc()
+	t.outcomes(state, namespace, workload, func(o *outcomes) { o.prevented++ })
+}
+
+// recordMissed records an OOMKilled container of workload, telling whether its
+// pod was over the limit, so the terminator was too slow, or not.
+func (t terminator) recordMissed(state *state, namespace, workload string, over bool) {
+	overLimit := "false"
+	if over {
+		overLimit = "true"
+	}
+	oomsMissed.WithLabelValues(t.options.Cluster, namespace, workload, overLimit).Inc()
+	t.outcomes(state, namespace, workload, func(o *outcomes) { o.missed++ })
+}
+
+// outcomes updates the outcomes of workload with update, and its effectiveness.
+func (t terminator) outcomes(state *state, namespace, workload string, update func(*outcomes)) {
+	key := namespace + "/" + workload
+	o, ok := state.outcomes[key]
+	if !ok {
+		o = new(outcomes)
+		state.outcomes[key] = o
+	}
+	update(o)
+	effectivenessRatio.WithLabelValues(t.options.Cluster, namespace, workload).Set(float64(o.prevented) / float64(o.prevented+o.missed))
+}
@@ -0,0 +1,207 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// Kill actions, what is done to the pods that are killed.
+const (
+	KillActionDelete = "delete"
+	KillActionExec   = "exec"
+)
+
+// execTimeout bounds an exec.
+const execTimeout = 30 * time.Second
+
+// restartPollInterval is how often a container signaled is checked for its
+// restart.
+const restartPollInterval = time.Second
+
+// ContainerAction is an Action that kills a single container of a pod instead
+// of the whole pod.
+type ContainerAction interface {
+	Action
+	KillContainer(ctx context.Context, pod *v1.Pod, container string, gracePeriod *int64) error
+}
+
+type execAction struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+	metrics   metrics.Interface
+	signal    string
+}
+
+// NewExecAction returns an Action that execs kill into the container of pods
+// using the most of its memory limit, by the metrics of metrics-server, sending
+// signal to its PID 1 so only that container is restarted by its
+// restartPolicy, instead of deleting the whole pod. The container needs a kill
+// binary, and PID 1 only gets the signals it handles, so a kill only succeeds
+// once the restart count of the container goes up.
+func NewExecAction(config *rest.Config, clientset kubernetes.Interface, metrics metrics.Interface, signal string) ContainerAction {
+	return execAction{config: config, clientset: clientset, metrics: metrics, signal: signal}
+}
+
+func (a execAction) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	container, err := a.offending(ctx, pod)
+	if err != nil {
+		return err
+	}
+	return a.KillContainer(ctx, pod, container, gracePeriod)
+}
+
+func (a execAction) KillContainer(ctx context.Context, pod *v1.Pod, container string, _ *int64) error {
+	current, err := a.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	restarts, ok := restartCount(current, container)
+	if !ok {
+		return fmt.Errorf("container %s of pod %s is not running", container, pod.Name)
+	}
+
+	if err := a.exec(ctx, pod, container, []string{"kill", "-s", a.signal, "1"}); err != nil {
+		return err
+	}
+	return a.restarted(ctx, current, container, restarts)
+}
+
+// restarted waits for container of pod to restart past restarts, for the
+// termination grace period of pod and the execTimeout, counting pod being gone
+// as restarted.
+func (a execAction) restarted(ctx context.Context, pod *v1.Pod, container string, restarts int32) error {
+	timeout := execTimeout + 30*time.Second
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		timeout = execTimeout + time.Duration(*pod.Spec.TerminationGracePeriodSeconds)*time.Second
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(restartPollInterval)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("container %s of pod %s did not restart after SIG%s, its PID 1 may not handle it", container, pod.Name, a.signal)
+		case <-poll.C:
+		}
+
+		current, err := a.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if errors.IsNotFound(err) || err == nil && current.UID != pod.UID {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if count, ok := restartCount(current, container); ok && count > restarts {
+			return nil
+		}
+	}
+}
+
+// restartCount returns the restarts of container of pod, looking into the init
+// containers too for native sidecars.
+func restartCount(pod *v1.Pod, container string) (int32, bool) {
+	for _, statuses := range [][]v1.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses} {
+		for _, status := range statuses {
+			if status.Name == container {
+				return status.RestartCount, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// offending returns the container of pod using the most of its memory limit,
+// or the most memory when none has a limit. Without metrics it is the first
+// one.
+func (a execAction) offending(ctx context.Context, pod *v1.Pod) (string, error) {
+	if len(pod.Spec.Containers) == 0 {
+		return "", fmt.Errorf("pod %s has no containers", pod.Name)
+	}
+	if a.metrics == nil {
+		return pod.Spec.Containers[0].Name, nil
+	}
+
+	podMetrics, err := a.metrics.MetricsV1beta1().PodMetricses(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	usage := make(map[string]int64, len(podMetrics.Containers))
+	for _, container := range podMetrics.Containers {
+		usage[container.Name] = container.Usage.Memory().Value()
+	}
+
+	offending := pod.Spec.Containers[0].Name
+	var highest float64
+	limited := false
+	for _, container := range pod.Spec.Containers {
+		used := float64(usage[container.Name])
+		if limit := container.Resources.Limits.Memory(); !limit.IsZero() {
+			if ratio := used / float64(limit.Value()); !limited || ratio > highest {
+				offending, highest, limited = container.Name, ratio, true
+			}
+		} else if !limited && used > highest {
+			offending, highest = container.Name, used
+		}
+	}
+	return offending, nil
+}
+
+// exec runs command in container of pod, returning its error with its stderr
+// when it fails. The exec ending without a status counts as done, since the
+// container can exit before it is sent. The stream can't be canceled, so an
+// exec outliving ctx or the execTimeout is left to end on its own.
+func (a execAction) exec(ctx context.Context, pod *v1.Pod, container string, command []string) error {
+	url := a.clientset.CoreV1().RESTClient().Post().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{Container: container, Command: command, Stderr: true}, scheme.ParameterCodec).
+		URL()
+
+	executor, err := remotecommand.NewSPDYExecutor(a.config, http.MethodPost, url)
+	if err != nil {
+		return fmt.Errorf("could not exec into container %s of pod %s: %w", container, pod.Name, err)
+	}
+
+	var stderr bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- executor.Stream(remotecommand.StreamOptions{Stderr: &stderr})
+	}()
+
+	timeout := time.NewTimer(execTimeout)
+	defer timeout.Stop()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout.C:
+		return fmt.Errorf("%s in container %s of pod %s timed out", strings.Join(command, " "), container, pod.Name)
+	}
+	if err == nil {
+		return nil
+	}
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return fmt.Errorf("%s in container %s of pod %s failed: %s: %s", strings.Join(command, " "), container, pod.Name, err, message)
+	}
+	return fmt.Errorf("%s in container %s of pod %s failed: %s", strings.Join(command, " "), container, pod.Name, err)
+}
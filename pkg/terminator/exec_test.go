@@ -0,0 +1,68 @@
+package terminator
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func execTestPod(restarts int32) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web", UID: "api"},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app"}}},
+		Status:     v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{Name: "app", RestartCount: restarts}}},
+	}
+}
+
+// TestExecRestarted checks a container signaled only counts as killed once it
+// restarted or its pod is gone, and not while it keeps running.
+func TestExecRestarted(t *testing.T) {
+	recreated := execTestPod(0)
+	recreated.UID = "api-2"
+	tests := []struct {
+		name      string
+		after     runtime.Object
+		restarted bool
+	}{
+		{"restarted", execTestPod(3), true},
+		{"deleted", nil, true},
+		{"recreated", recreated, true},
+		{"still running", execTestPod(2), false},
+	}
+	for _, test := range tests {
+		clientset := fake.NewSimpleClientset(execTestPod(2))
+		after := test.after
+		clientset.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+			if after == nil {
+				return false, nil, nil
+			}
+			return true, after, nil
+		})
+		if after == nil {
+			if err := clientset.CoreV1().Pods("web").Delete(context.Background(), "api", metav1.DeleteOptions{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*restartPollInterval)
+		err := execAction{clientset: clientset, signal: "TERM"}.restarted(ctx, execTestPod(2), "app", 2)
+		cancel()
+		if restarted := err == nil; restarted != test.restarted {
+			t.Errorf("%s: expected restarted %v, got %v", test.name, test.restarted, err)
+		}
+	}
+}
+
+func TestValidateKillSignal(t *testing.T) {
+	for signal, valid := range map[string]bool{"TERM": true, "QUIT": true, "KILL": false, "HUP": false} {
+		options := Options{Workers: 1, KillAction: KillActionExec, KillSignal: signal}
+		if err := options.validate(); (err == nil) != valid {
+			t.Errorf("%s: expected valid %v, got %v", signal, valid, err)
+		}
+	}
+}
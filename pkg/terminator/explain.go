@@ -0,0 +1,311 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// Explanation is how a pod is evaluated, written by Explain.
+type Explanation struct {
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+	Phase     string `json:"phase"`
+	Node      string `json:"node"`
+	Workload  string `json:"workload"`
+	// Targets are the targets selecting the pod, none when it isn't checked
+	Targets []string `json:"targets"`
+	Policy  string   `json:"policy"`
+	// Containers are the containers that count with their memory limits
+	Containers []ExplainedContainer `json:"containers"`
+	// Usage and Limit are in bytes, Usage nil without metrics yet and Limit
+	// nil when the pod has no limit
+	Usage      *int64  `json:"usage,omitempty"`
+	Limit      *int64  `json:"limit,omitempty"`
+	Percentage float64 `json:"percentage"`
+	// OverLimit tells whether the usage is over the limit of the policy
+	OverLimit bool `json:"overLimit"`
+	// Counter is the amount of checks the pod has been over the limit for
+	// since OverSince, zero when unknown or not over it
+	Counter   int        `json:"counter"`
+	OverSince *time.Time `json:"overSince,omitempty"`
+	// Notes explain what the counters and the usage mean for the pod
+	Notes []string `json:"notes,omitempty"`
+	// Protections are what would keep the pod from being killed right now
+	Protections []string `json:"protections"`
+}
+
+// ExplainedContainer is a container counted against its memory limit, in
+// bytes, nil when it has none.
+type ExplainedContainer struct {
+	Name  string `json:"name"`
+	Limit *int64 `json:"limit,omitempty"`
+}
+
+// Explain returns how the pod namespace/name is evaluated: the targets
+// selecting it, its policy, the containers that count, its usage and over
+// limit counter, and what would keep it from being killed. Nothing is killed.
+func (t terminator) Explain(ctx context.Context, targets Targets, namespace, name string, memoryLimit, killAfter int) (*Explanation, error) {
+	pod, err := t.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &Explanation{
+		Cluster:   t.options.Cluster,
+		Namespace: pod.Namespace,
+		Pod:       pod.Name,
+		Phase:     string(pod.Status.Phase),
+		Node:      pod.Spec.NodeName,
+		Workload:  workloadName(pod),
+	}
+
+	explanation.Targets, err = t.matchingTargets(ctx, targets, pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(explanation.Targets) == 0 {
+		explanation.Notes = append(explanation.Notes, fmt.Sprintf("not selected by%s, so it is not checked", targets))
+	}
+
+	state := newState()
+	if err := t.restoreState(ctx, state); err != nil {
+		return nil, err
+	}
+	pods, err := t.clientset.CoreV1().Pods(pod.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	c, err := t.newCheck(ctx, state, crashLoopingWorkloads(pods.Items, t.options.MaxRestarts, t.clock.Now()), t.defaultPolicy(memoryLimit, killAfter), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := t.policyFor(ctx, c, pod)
+	if err != nil {
+		return nil, err
+	}
+	explanation.Policy = policy.String()
+
+	counted := t.onlyContainers(pod)
+	for _, container := range counted.Spec.Containers {
+		explained := ExplainedContainer{Name: container.Name}
+		if memory := container.Resources.Limits.Memory(); !memory.IsZero() {
+			limit := memory.Value()
+			explained.Limit = &limit
+		}
+		explanation.Containers = append(explanation.Containers, explained)
+	}
+
+	if err := t.explainUsage(ctx, c, counted, policy, explanation); err != nil {
+		return nil, err
+	}
+
+	if over, ok := state.podsToKill[pod.UID]; ok {
+		explanation.Counter = over.count + 1
+		explanation.OverSince = &over.at
+	} else if t.store == nil {
+		explanation.Notes = append(explanation.Notes, "the over limit counter is unknown without a state-configmap")
+	}
+
+	explanation.Protections, err = t.protections(ctx, c, pod, policy)
+	if err != nil {
+		return nil, err
+	}
+	return explanation, nil
+}
+
+func (p policy) String() string {
+	s := fmt.Sprintf("limit %d%%, kill after %d checks, cooldown %s", p.limit, p.killAfter, p.cooldown)
+	if p.condition != nil {
+		s += fmt.Sprintf(", condition %s", p.condition)
+	}
+	if p.rule != nil {
+		s += ", with a rule"
+	}
+	if p.query != "" {
+		s += fmt.Sprintf(", query %s", p.query)
+	}
+	return s
+}
+
+// matchingTargets returns the targets selecting pod.
+func (t terminator) matchingTargets(ctx context.Context, targets Targets, pod *v1.Pod) ([]string, error) {
+	watcher, err := t.watchTargets(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := map[string]Targets{"every pod of the namespace": targets}
+	if targets.explicit() {
+		candidates = make(map[string]Targets)
+		for _, name := range targets.Services {
+			candidates["service/"+name] = Targets{Namespace: targets.Namespace, Services: []string{name}, Nodes: targets.Nodes, NodeSelector: targets.NodeSelector}
+		}
+		for _, name := range targets.Deployments {
+			candidates["deployment/"+name] = Targets{Namespace: targets.Namespace, Deployments: []string{name}, Nodes: targets.Nodes, NodeSelector: targets.NodeSelector}
+		}
+		for _, name := range targets.StatefulSets {
+			candidates["statefulset/"+name] = Targets{Namespace: targets.Namespace, StatefulSets: []string{name}, Nodes: targets.Nodes, NodeSelector: targets.NodeSelector}
+		}
+		for _, name := range targets.Pods {
+			candidates["pod/"+name] = Targets{Namespace: targets.Namespace, Pods: []string{name}, Nodes: targets.Nodes, NodeSelector: targets.NodeSelector}
+		}
+	}
+
+	matched := []string{}
+	for description, candidate := range candidates {
+		pods, err := t.getPods(ctx, watcher, candidate)
+		if err != nil {
+			return nil, err
+		}
+		for _, selected := range pods.Items {
+			if selected.UID == pod.UID {
+				matched = append(matched, description)
+				break
+			}
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+// explainUsage adds the current memory usage of pod against its limit to
+// explanation.
+func (t terminator) explainUsage(ctx context.Context, c *check, pod *v1.Pod, policy policy, explanation *Explanation) error {
+	limit, err := t.memoryLimit(ctx, c, pod)
+	if err != nil {
+		return err
+	}
+	if limit == nil {
+		explanation.Notes = append(explanation.Notes, "the pod has no memory limit, so it is skipped")
+		return nil
+	}
+	limitBytes := limit.Value()
+	explanation.Limit = &limitBytes
+
+	using, err := t.podMemory(ctx, c, pod)
+	if err != nil {
+		return err
+	}
+	if using == nil {
+		explanation.Notes = append(explanation.Notes, "the pod has no metrics yet")
+		return nil
+	}
+	usingBytes := using.Value()
+	explanation.Usage = &usingBytes
+
+	explanation.Percentage = float64(usingBytes) / float64(limitBytes) * 100
+	explanation.OverLimit = explanation.Percentage >= float64(policy.limit)
+	if t.options.Window > 0 {
+		explanation.Notes = append(explanation.Notes, fmt.Sprintf("checks compare the %s of the last %s instead, which isn't kept between runs", t.options.Aggregation, t.options.Window))
+	}
+	return nil
+}
+
+// protections describes what would keep pod from being killed right now.
+func (t terminator) protections(ctx context.Context, c *check, pod *v1.Pod, policy policy) ([]string, error) {
+	yesNo := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+
+	key := workloadKey(pod)
+	now := t.clock.Now()
+	protections := []string{
+		"protected by its policy: " + yesNo(policy.protected),
+		"already marked as killed: " + yesNo(pod.Labels[killedLabel] == "true"),
+		"without a controller, so it would not be recreated: " + yesNo(len(pod.OwnerReferences) == 0 && !t.options.IncludeBarePods),
+		"workload crash looping: " + yesNo(c.crashLooping[key]),
+	}
+	if t.options.MaxPriority != nil {
+		protections = append(protections, fmt.Sprintf("priority %d over the max priority: %s", priority(pod), yesNo(priority(pod) > *t.options.MaxPriority)))
+	}
+	if len(t.options.QOSClasses) > 0 {
+		protections = append(protections, fmt.Sprintf("QoS class %s not allowed: %s", pod.Status.QOSClass, yesNo(!t.options.eligibleQOS(pod))))
+	}
+	if t.options.MaxKillsPerNamespacePerHour > 0 {
+		protections = append(protections, fmt.Sprintf("namespace %s at its kills of the last hour: %s", pod.Namespace, yesNo(t.namespaceRateLimited(pod.Namespace, t.clock.Now()))))
+	}
+
+	rolling, err := t.rollingOut(ctx, c, pod)
+	if err != nil {
+		return nil, err
+	}
+	protections = append(protections, "workload rolling out: "+yesNo(rolling))
+
+	cooldown := fmt.Sprintf("cooldown of %s", policy.cooldown)
+	if lastKill, ok := t.lastWorkloadKill(ctx, pod); ok {
+		cooldown += fmt.Sprintf(", a pod of the workload was last killed %s ago", now.Sub(lastKill).Round(time.Second))
+		if now.Sub(lastKill) < policy.cooldown {
+			cooldown += ", in cooldown"
+		}
+	}
+	protections = append(protections, cooldown)
+
+	if t.options.ActiveHours != nil {
+		protections = append(protections, "outside of active hours: "+yesNo(!t.options.ActiveHours.Contains(now)))
+	}
+	blackout, err := t.inBlackout(ctx, c, pod.Namespace, now)
+	if err != nil {
+		return nil, err
+	}
+	protections = append(protections, "in blackout: "+yesNo(blackout))
+
+	if t.options.NodePressure == NodePressurePause {
+		protections = append(protections, "paused by nodes under memory pressure: "+yesNo(len(c.pressuredNodes) > 0))
+	}
+	if _, ok := t.action.(execAction); ok {
+		protections = append(protections, "Windows containers the exec kill-action can't signal: "+yesNo(t.unsignalable(ctx, c, pod)))
+	}
+
+	budgets, err := t.clientset.PolicyV1().PodDisruptionBudgets(pod.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil && !errors.IsForbidden(err) {
+		return nil, err
+	}
+	if budgets != nil {
+		for _, budget := range budgets.Items {
+			selector, err := metav1.LabelSelectorAsSelector(budget.Spec.Selector)
+			if err != nil || !selector.Matches(labels.Set(pod.Labels)) {
+				continue
+			}
+			protections = append(protections, fmt.Sprintf("PodDisruptionBudget %s allows %d disruptions, it isn't honored since pods are deleted", budget.Name, budget.Status.DisruptionsAllowed))
+		}
+	}
+	return protections, nil
+}
+
+// lastWorkloadKill returns the last kill recorded on the workload of pod by
+// AnnotateWorkloads.
+func (t terminator) lastWorkloadKill(ctx context.Context, pod *v1.Pod) (time.Time, bool) {
+	kind, name, _ := strings.Cut(workloadName(pod), "/")
+
+	var annotations map[string]string
+	switch kind {
+	case "deployment":
+		deployment, err := t.clientset.AppsV1().Deployments(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return time.Time{}, false
+		}
+		annotations = deployment.Annotations
+	case "statefulset":
+		statefulSet, err := t.clientset.AppsV1().StatefulSets(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return time.Time{}, false
+		}
+		annotations = statefulSet.Annotations
+	}
+
+	lastKill, err := time.Parse(time.RFC3339, annotations[lastKillAnnotation])
+	return lastKill, err == nil
+}
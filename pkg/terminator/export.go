@@ -0,0 +1,244 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"golang.org/x/oauth2/google"
+	v1 "k8s.io/api/core/v1"
+)
+
+// Decisions of the records of pods.
+const (
+	DecisionUnder     = "under"
+	DecisionOver      = "over"
+	DecisionKilled    = "killed"
+	DecisionWouldKill = "would-kill"
+)
+
+// Record is the sample of a pod on a check and what was decided about it.
+type Record struct {
+	Time       time.Time
+	Cluster    string
+	Namespace  string
+	Pod        string
+	Workload   string
+	Using      int64
+	Limit      int64
+	Percentage float64
+	Decision   string
+}
+
+// Exporter receives the records of the evaluated pods after each check, for
+// offline analysis.
+type Exporter interface {
+	Export(ctx context.Context, records []Record) error
+}
+
+// NewExporter returns an Exporter writing the records of each check to a CSV
+// file under destination, a local directory, an s3://bucket/prefix or a
+// gs://bucket/prefix. Buckets use the default credentials of their cloud.
+func NewExporter(ctx context.Context, destination string) (Exporter, error) {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid export destination %q: %w", destination, err)
+	}
+	prefix := strings.Trim(parsed.Path, "/")
+
+	switch parsed.Scheme {
+	case "", "file":
+		return fileExporter{dir: parsed.Path}, nil
+	case "s3":
+		cfg, err := config.LoadDefaultConfig(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return s3Exporter{client: s3.NewFromConfig(cfg), bucket: parsed.Host, prefix: prefix}, nil
+	case "gs":
+		client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/devstorage.read_write")
+		if err != nil {
+			return nil, err
+		}
+		return gcsExporter{client: client, bucket: parsed.Host, prefix: prefix}, nil
+	default:
+		return nil, fmt.Errorf("invalid export destination %q, must be a directory, s3:// or gs://", destination)
+	}
+}
+
+// exportName is the name of the file of the records of a check, with their
+// namespace when they are all of the same one, like the checks of each
+// namespace loop, and a random suffix, so checks starting on the same second
+// don't overwrite each other.
+func exportName(records []Record) string {
+	name := records[0].Cluster
+	if name == "" {
+		name = "default"
+	}
+	namespace := records[0].Namespace
+	for _, record := range records[1:] {
+		if record.Namespace != namespace {
+			namespace = ""
+			break
+		}
+	}
+	if namespace != "" {
+		name += "-" + namespace
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		// the time to the nanosecond is unique enough within a process
+		binary.BigEndian.PutUint32(suffix, uint32(time.Now().UnixNano()))
+	}
+	return name + "-" + records[0].Time.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix) + ".csv"
+}
+
+func encodeRecords(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write([]string{"time", "cluster", "namespace", "pod", "workload", "using_bytes", "limit_bytes", "percentage", "decision"}); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		err := w.Write([]string{
+			record.Time.UTC().Format(time.RFC3339),
+			record.Cluster,
+			record.Namespace,
+			record.Pod,
+			record.Workload,
+			strconv.FormatInt(record.Using, 10),
+			strconv.FormatInt(record.Limit, 10),
+			strconv.FormatFloat(record.Percentage, 'f', 2, 64),
+			record.Decision,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+type fileExporter struct {
+	dir string
+}
+
+func (e fileExporter) Export(ctx context.Context, records []Record) error {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.dir, exportName(records)), data, 0o644)
+}
+
+type s3Exporter struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func (e s3Exporter) Export(ctx context.Context, records []Record) error {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+	_, err = e.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(e.bucket),
+		Key:         aws.String(path.Join(e.prefix, exportName(records))),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("text/csv"),
+	})
+	return err
+}
+
+type gcsExporter struct {
+	client *http.Client
+	bucket string
+	prefix string
+}
+
+func (e gcsExporter) Export(ctx context.Context, records []Record) error {
+	data, err := encodeRecords(records)
+	if err != nil {
+		return err
+	}
+
+	params := url.Values{"uploadType": {"media"}, "name": {path.Join(e.prefix, exportName(records))}}
+	endpoint := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(e.bucket) + "/o?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/csv")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cloud storage returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// record keeps the sample of pod and what was decided about it for the
+// exporter.
+func (t terminator) record(c *check, pod *v1.Pod, s sample, decision string) {
+	if t.exporter == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.killedUID == pod.UID && pod.UID != "" {
+		decision = DecisionKilled
+		if c.killedDryRun {
+			decision = DecisionWouldKill
+		}
+	}
+	c.records = append(c.records, Record{
+		Time:       t.clock.Now(),
+		Cluster:    t.options.Cluster,
+		Namespace:  pod.Namespace,
+		Pod:        pod.Name,
+		Workload:   workloadName(pod),
+		Using:      s.using.Value(),
+		Limit:      s.limit.Value(),
+		Percentage: s.percentage,
+		Decision:   decision,
+	})
+}
+
+// export hands the records of a check to the exporter, logging failures so
+// they don't stop the checks.
+func (t terminator) export(ctx context.Context, records []Record) {
+	if t.exporter == nil || len(records) == 0 {
+		return
+	}
+
+	if err := t.exporter.Export(ctx, records); err != nil {
+		t.log.Errorf("could not export the records of the check: %s", err)
+	}
+}
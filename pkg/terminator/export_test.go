@@ -0,0 +1,30 @@
+package terminator
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportName(t *testing.T) {
+	at := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		records  []Record
+		expected string
+	}{
+		{"namespace", []Record{{Time: at, Cluster: "prod", Namespace: "web"}, {Time: at, Cluster: "prod", Namespace: "web"}}, "prod-web-20261014T060000Z-"},
+		{"namespaces", []Record{{Time: at, Cluster: "prod", Namespace: "web"}, {Time: at, Cluster: "prod", Namespace: "jobs"}}, "prod-20261014T060000Z-"},
+		{"default cluster", []Record{{Time: at, Namespace: "web"}}, "default-web-20261014T060000Z-"},
+	}
+	for _, test := range tests {
+		name := exportName(test.records)
+		if !strings.HasPrefix(name, test.expected) || !strings.HasSuffix(name, ".csv") {
+			t.Errorf("%s: expected %s<suffix>.csv, got %s", test.name, test.expected, name)
+		}
+		// checks of the same second get files of their own
+		if again := exportName(test.records); again == name {
+			t.Errorf("%s: expected another name for another check, got %s twice", test.name, name)
+		}
+	}
+}
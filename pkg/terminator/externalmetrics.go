@@ -0,0 +1,61 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ExternalMetric is a metric from the external metrics API, like the depth of
+// a queue, available to conditions so they can combine it with the memory
+// usage of pods. Its value is the sum of the series matching Selector in the
+// namespace of the pod.
+type ExternalMetric struct {
+	Name     string
+	Selector labels.Selector
+}
+
+// ParseExternalMetric parses an external metric like queue_depth, or
+// queue_depth:queue=orders with a selector of its series.
+func ParseExternalMetric(metric string) (ExternalMetric, error) {
+	name, selector, _ := strings.Cut(metric, ":")
+	if name == "" {
+		return ExternalMetric{}, fmt.Errorf("invalid external metric %q, must be name or name:selector", metric)
+	}
+
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return ExternalMetric{}, fmt.Errorf("invalid selector of external metric %s: %w", name, err)
+	}
+	return ExternalMetric{Name: name, Selector: parsed}, nil
+}
+
+// externalMetrics returns the values of the external metrics for namespace.
+// They are fetched once per namespace on each check, a metric that can't be
+// fetched is logged and left out.
+func (t terminator) externalMetrics(ctx context.Context, c *check, namespace string) map[string]float64 {
+	if t.externalMetricsClient == nil || len(t.options.ExternalMetrics) == 0 {
+		return nil
+	}
+
+	values, _ := c.externalMetrics.get(namespace, func() (interface{}, error) {
+		values := make(map[string]float64, len(t.options.ExternalMetrics))
+		for _, metric := range t.options.ExternalMetrics {
+			list, err := t.externalMetricsClient.NamespacedMetrics(namespace).List(metric.Name, metric.Selector)
+			if err != nil {
+				t.operationalError(ctx, c, namespace, "", "could not get external metric %s of namespace %s: %s", metric.Name, namespace, err)
+				continue
+			}
+
+			var value float64
+			for _, item := range list.Items {
+				value += item.Value.AsApproximateFloat64()
+			}
+			values[metric.Name] = value
+		}
+		return values, nil
+	})
+	return values.(map[string]float64)
+}
@@ -0,0 +1,335 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+// Kinds of the workloads of a scenario.
+const (
+	scenarioDeployment  = "deployment"
+	scenarioStatefulSet = "statefulset"
+	scenarioPod         = "pod"
+)
+
+// Scenario is a synthetic cluster whose pods follow scripted memory
+// timelines, to try the terminator and its policies without a cluster.
+type Scenario struct {
+	Namespaces []ScenarioNamespace `json:"namespaces,omitempty"`
+	Workloads  []ScenarioWorkload  `json:"workloads"`
+}
+
+// ScenarioNamespace is a namespace of a scenario, with the labels and
+// annotations matched by policies and blackouts.
+type ScenarioNamespace struct {
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ScenarioWorkload is a deployment, statefulset or bare pod of a scenario.
+// Each of its pods uses the Memory of the timeline on each check, starting
+// over when it is killed or OOMKilled, and staying at the last one once it is
+// done.
+type ScenarioWorkload struct {
+	Namespace string `json:"namespace"`
+	// Kind is deployment, the default, statefulset or pod
+	Kind     string            `json:"kind,omitempty"`
+	Name     string            `json:"name"`
+	Replicas int               `json:"replicas,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Limit    resource.Quantity `json:"limit"`
+	// Memory is the usage of each check, like 512Mi, or a percentage of the
+	// Limit, like 95%. Reaching the limit OOMKills the container
+	Memory []string `json:"memory"`
+
+	timeline []resource.Quantity
+	// hash is the pod template hash of the pods of a deployment
+	hash string
+}
+
+// LoadScenario reads the scenario of the YAML file at path.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	scenario := new(Scenario)
+	if err := yaml.UnmarshalStrict(data, scenario); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+
+	if len(scenario.Workloads) == 0 {
+		return nil, fmt.Errorf("invalid scenario %s: no workloads", path)
+	}
+	for i := range scenario.Workloads {
+		workload := &scenario.Workloads[i]
+		if err := workload.compile(); err != nil {
+			return nil, fmt.Errorf("invalid scenario %s: workload %s/%s: %w", path, workload.Namespace, workload.Name, err)
+		}
+	}
+	return scenario, nil
+}
+
+func (w *ScenarioWorkload) compile() error {
+	if w.Namespace == "" || w.Name == "" {
+		return fmt.Errorf("needs a namespace and a name")
+	}
+	if w.Kind == "" {
+		w.Kind = scenarioDeployment
+	}
+	if w.Kind != scenarioDeployment && w.Kind != scenarioStatefulSet && w.Kind != scenarioPod {
+		return fmt.Errorf("kind must be %s, %s or %s, got %s", scenarioDeployment, scenarioStatefulSet, scenarioPod, w.Kind)
+	}
+	if w.Replicas == 0 || w.Kind == scenarioPod {
+		w.Replicas = 1
+	}
+	if w.Replicas < 0 {
+		return fmt.Errorf("replicas can't be negative, got %d", w.Replicas)
+	}
+	if w.Limit.IsZero() {
+		return fmt.Errorf("needs a limit")
+	}
+	if len(w.Memory) == 0 {
+		return fmt.Errorf("needs a memory timeline")
+	}
+
+	w.hash = rand.String(10)
+	w.timeline = make([]resource.Quantity, len(w.Memory))
+	for i, memory := range w.Memory {
+		if strings.HasSuffix(memory, "%") {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(memory, "%"), 64)
+			if err != nil || value < 0 {
+				return fmt.Errorf("invalid memory %q", memory)
+			}
+			w.timeline[i] = *resource.NewQuantity(int64(float64(w.Limit.Value())*value/100), resource.BinarySI)
+			continue
+		}
+
+		quantity, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return fmt.Errorf("invalid memory %q: %w", memory, err)
+		}
+		w.timeline[i] = quantity
+	}
+	return nil
+}
+
+// NewFakeCluster returns a terminator with options checking the pods of
+// scenario in an in-memory cluster instead of a real one. Killed pods are
+// replaced like their controllers would, and pods reaching their limit are
+// OOMKilled.
+func NewFakeCluster(scenario *Scenario, options Options, opts ...Option) (Terminator, error) {
+	ctx := context.Background()
+	var objects []runtime.Object
+	for _, namespace := range scenario.Namespaces {
+		objects = append(objects, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace.Name, Labels: namespace.Labels, Annotations: namespace.Annotations}})
+	}
+	for _, workload := range scenario.Workloads {
+		objects = append(objects, workload.objects()...)
+	}
+
+	clientset := fake.NewSimpleClientset(objects...)
+	cluster := &fakeCluster{clientset: clientset, workloads: make(map[string]*ScenarioWorkload), checks: make(map[string]int)}
+	for i := range scenario.Workloads {
+		workload := &scenario.Workloads[i]
+		cluster.workloads[workload.Namespace+"/"+workload.Name] = workload
+		if err := cluster.ensureNamespace(ctx, workload.Namespace); err != nil {
+			return nil, err
+		}
+		for replica := 0; replica < workload.Replicas; replica++ {
+			if err := cluster.createPod(ctx, workload, replica); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	opts = append([]Option{WithMetricsProvider(cluster), WithAction(cluster)}, opts...)
+	return NewForClients(clientset, nil, options, opts...)
+}
+
+// objects returns the controller of the pods of w.
+func (w ScenarioWorkload) objects() []runtime.Object {
+	replicas := int32(w.Replicas)
+	meta := metav1.ObjectMeta{Name: w.Name, Namespace: w.Namespace, Labels: w.labels()}
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": w.Name}}
+	template := v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: w.labels()}}
+	switch w.Kind {
+	case scenarioDeployment:
+		return []runtime.Object{&appsv1.Deployment{
+			ObjectMeta: meta,
+			Spec:       appsv1.DeploymentSpec{Replicas: &replicas, Selector: selector, Template: template},
+			Status:     appsv1.DeploymentStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas, AvailableReplicas: replicas},
+		}}
+	case scenarioStatefulSet:
+		return []runtime.Object{&appsv1.StatefulSet{
+			ObjectMeta: meta,
+			Spec:       appsv1.StatefulSetSpec{Replicas: &replicas, Selector: selector, Template: template},
+			Status:     appsv1.StatefulSetStatus{Replicas: replicas, UpdatedReplicas: replicas, ReadyReplicas: replicas},
+		}}
+	}
+	return nil
+}
+
+func (w ScenarioWorkload) labels() map[string]string {
+	labels := map[string]string{"app": w.Name}
+	for key, value := range w.Labels {
+		labels[key] = value
+	}
+	return labels
+}
+
+// fakeCluster is the metrics provider and the action of a scenario, playing
+// the memory timelines of its pods and replacing the killed ones.
+type fakeCluster struct {
+	clientset kubernetes.Interface
+	workloads map[string]*ScenarioWorkload
+
+	mu sync.Mutex
+	// checks are the checks each pod was in, by uid, its step of the timeline
+	checks map[string]int
+}
+
+func (f *fakeCluster) ensureNamespace(ctx context.Context, name string) error {
+	if _, err := f.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{}); err == nil {
+		return nil
+	}
+	_, err := f.clientset.CoreV1().Namespaces().Create(ctx, &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, metav1.CreateOptions{})
+	return err
+}
+
+// createPod creates the replica of workload, with a new uid so its timeline
+// starts over.
+func (f *fakeCluster) createPod(ctx context.Context, workload *ScenarioWorkload, replica int) error {
+	controller := true
+	suffix := rand.String(5)
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         workload.Namespace,
+			UID:               uuid.NewUUID(),
+			Labels:            workload.labels(),
+			Annotations:       map[string]string{scenarioReplicaAnnotation: strconv.Itoa(replica)},
+			CreationTimestamp: metav1.Now(),
+		},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:      workload.Name,
+			Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: workload.Limit}},
+		}}},
+		Status: v1.PodStatus{
+			Phase:             v1.PodRunning,
+			QOSClass:          v1.PodQOSBurstable,
+			Conditions:        []v1.PodCondition{{Type: v1.PodReady, Status: v1.ConditionTrue}},
+			ContainerStatuses: []v1.ContainerStatus{{Name: workload.Name, Ready: true, State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: metav1.Now()}}}},
+		},
+	}
+
+	switch workload.Kind {
+	case scenarioDeployment:
+		replicaSet := workload.Name + "-" + workload.hash
+		pod.Name = replicaSet + "-" + suffix
+		pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey] = workload.hash
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: replicaSet, Controller: &controller}}
+	case scenarioStatefulSet:
+		pod.Name = workload.Name + "-" + strconv.Itoa(replica)
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "StatefulSet", Name: workload.Name, Controller: &controller}}
+	default:
+		pod.Name = workload.Name
+	}
+
+	_, err := f.clientset.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	return err
+}
+
+// scenarioReplicaAnnotation is the replica of its workload a pod of a
+// scenario is, to replace it.
+const scenarioReplicaAnnotation = "oomterminator/scenario-replica"
+
+// workload returns the workload of the scenario pod belongs to.
+func (f *fakeCluster) workload(pod *v1.Pod) (*ScenarioWorkload, bool) {
+	_, name, _ := strings.Cut(workloadName(pod), "/")
+	workload, ok := f.workloads[pod.Namespace+"/"+name]
+	return workload, ok
+}
+
+// PodUsage returns the step of the timeline of pod for this check, OOMKilling
+// its container when it reaches the limit.
+func (f *fakeCluster) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	workload, ok := f.workload(pod)
+	if !ok {
+		return nil, nil
+	}
+
+	f.mu.Lock()
+	step := f.checks[string(pod.UID)]
+	f.checks[string(pod.UID)]++
+	f.mu.Unlock()
+
+	if step >= len(workload.timeline) {
+		step = len(workload.timeline) - 1
+	}
+	usage := workload.timeline[step].DeepCopy()
+	if usage.Cmp(workload.Limit) < 0 {
+		return &usage, nil
+	}
+
+	if err := f.oomKill(ctx, pod); err != nil {
+		return nil, err
+	}
+	usage = workload.timeline[0].DeepCopy()
+	return &usage, nil
+}
+
+// oomKill restarts the container of pod as OOMKilled, starting its timeline
+// over.
+func (f *fakeCluster) oomKill(ctx context.Context, pod *v1.Pod) error {
+	f.mu.Lock()
+	f.checks[string(pod.UID)] = 1
+	f.mu.Unlock()
+
+	current, err := f.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	for i := range current.Status.ContainerStatuses {
+		status := &current.Status.ContainerStatuses[i]
+		status.RestartCount++
+		status.LastTerminationState = v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137, FinishedAt: metav1.Now()}}
+		status.State = v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: metav1.Now()}}
+	}
+	_, err = f.clientset.CoreV1().Pods(pod.Namespace).UpdateStatus(ctx, current, metav1.UpdateOptions{})
+	return err
+}
+
+// Kill deletes pod and creates its replacement, like its controller would.
+func (f *fakeCluster) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	if err := f.clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{GracePeriodSeconds: gracePeriod}); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	delete(f.checks, string(pod.UID))
+	f.mu.Unlock()
+
+	workload, ok := f.workload(pod)
+	if !ok || workload.Kind == scenarioPod {
+		return nil
+	}
+	replica, _ := strconv.Atoi(pod.Annotations[scenarioReplicaAnnotation])
+	return f.createPod(ctx, workload, replica)
+}
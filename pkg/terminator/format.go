@@ -0,0 +1,89 @@
+package terminator
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Units of the memory in the output.
+const (
+	// UnitsBinary are powers of 1024, like 1.5Gi, the default
+	UnitsBinary = "binary"
+	// UnitsDecimal are powers of 1000, like 1.6G
+	UnitsDecimal = "decimal"
+	UnitsBytes   = "bytes"
+)
+
+func validUnits(units string) error {
+	switch units {
+	case "", UnitsBinary, UnitsDecimal, UnitsBytes:
+		return nil
+	default:
+		return fmt.Errorf("invalid output-units %q, must be binary, decimal or bytes", units)
+	}
+}
+
+// formatter formats the memory and percentages of the output, so logs, reports
+// and notifications read the same.
+type formatter struct {
+	units string
+	// precision are the decimals of percentages
+	precision int
+}
+
+var (
+	binarySuffixes  = []string{"", "Ki", "Mi", "Gi", "Ti", "Pi"}
+	decimalSuffixes = []string{"", "k", "M", "G", "T", "P"}
+)
+
+// bytes formats q, like 1.5Gi.
+func (f formatter) bytes(q *resource.Quantity) string {
+	if q == nil {
+		return "unknown"
+	}
+	return f.bytesOf(q.Value())
+}
+
+func (f formatter) bytesOf(value int64) string {
+	base, suffixes := 1024.0, binarySuffixes
+	switch f.units {
+	case UnitsBytes:
+		return strconv.FormatInt(value, 10)
+	case UnitsDecimal:
+		base, suffixes = 1000, decimalSuffixes
+	}
+
+	v := float64(value)
+	i := 0
+	for math.Abs(v) >= base && i < len(suffixes)-1 {
+		v /= base
+		i++
+	}
+	s := strconv.FormatFloat(v, 'f', 1, 64)
+	return strings.TrimSuffix(s, ".0") + suffixes[i]
+}
+
+// percent formats the percentage p, like 95%.
+func (f formatter) percent(p float64) string {
+	return strconv.FormatFloat(p, 'f', f.precision, 64) + "%"
+}
+
+// usage formats using out of limit with its percentage, like 1.5Gi/2Gi = 75%.
+func (f formatter) usage(using, limit *resource.Quantity, percentage float64) string {
+	return fmt.Sprintf("%s/%s = %s", f.bytes(using), f.bytes(limit), f.percent(percentage))
+}
+
+// FormatBytes formats value in units, like 1.5Gi with UnitsBinary, as in the
+// output of the terminator.
+func FormatBytes(units string, value int64) string {
+	return formatter{units: units}.bytesOf(value)
+}
+
+// FormatPercent formats the percentage p with precision decimals, like 95%.
+func FormatPercent(precision int, p float64) string {
+	return formatter{precision: precision}.percent(p)
+}
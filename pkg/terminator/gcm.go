@@ -0,0 +1,122 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/oauth2/google"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const MetricsSourceGCM = "gcm"
+
+// gcmWindow is how far back the latest point of a pod is looked for.
+const gcmWindow = 5 * time.Minute
+
+// GCMOptions configures the Google Cloud Monitoring metrics source.
+// Credentials come from the Application Default Credentials.
+type GCMOptions struct {
+	Project string
+	// Location and ClusterName filter the k8s_container series, when set
+	Location    string
+	ClusterName string
+}
+
+type gcmProvider struct {
+	options GCMOptions
+	client  *http.Client
+}
+
+// NewGCMProvider returns a MetricsProvider reading the non-evictable
+// kubernetes.io/container/memory/used_bytes series of Cloud Monitoring, the
+// working set of the containers of a pod on GKE.
+func NewGCMProvider(ctx context.Context, options GCMOptions) (MetricsProvider, error) {
+	client, err := google.DefaultClient(ctx, "https://www.googleapis.com/auth/monitoring.read")
+	if err != nil {
+		return nil, err
+	}
+	client.Timeout = 10 * time.Second
+
+	return gcmProvider{options: options, client: client}, nil
+}
+
+func (p gcmProvider) filter(pod *v1.Pod) string {
+	filter := fmt.Sprintf(`metric.type="kubernetes.io/container/memory/used_bytes" AND metric.labels.memory_type="non-evictable" AND resource.type="k8s_container" AND resource.labels.namespace_name=%q AND resource.labels.pod_name=%q`, pod.Namespace, pod.Name)
+	if p.options.Location != "" {
+		filter += fmt.Sprintf(` AND resource.labels.location=%q`, p.options.Location)
+	}
+	if p.options.ClusterName != "" {
+		filter += fmt.Sprintf(` AND resource.labels.cluster_name=%q`, p.options.ClusterName)
+	}
+	return filter
+}
+
+func (p gcmProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	now := time.Now()
+	params := url.Values{
+		"filter":             {p.filter(pod)},
+		"interval.startTime": {now.Add(-gcmWindow).UTC().Format(time.RFC3339)},
+		"interval.endTime":   {now.UTC().Format(time.RFC3339)},
+	}
+	endpoint := "https://monitoring.googleapis.com/v3/projects/" + url.PathEscape(p.options.Project) + "/timeSeries?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("cloud monitoring returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		TimeSeries []struct {
+			Resource struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"resource"`
+			Points []struct {
+				Value struct {
+					Int64Value string `json:"int64Value"`
+				} `json:"value"`
+			} `json:"points"`
+		} `json:"timeSeries"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid cloud monitoring response: %w", err)
+	}
+
+	containers := make(map[string]bool, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containers[container.Name] = true
+	}
+
+	// points are newest first, one series per container
+	found := false
+	usage := resource.NewQuantity(0, resource.BinarySI)
+	for _, series := range result.TimeSeries {
+		if !containers[series.Resource.Labels["container_name"]] || len(series.Points) == 0 {
+			continue
+		}
+		value, err := strconv.ParseInt(series.Points[0].Value.Int64Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloud monitoring point: %w", err)
+		}
+		found = true
+		usage.Add(*resource.NewQuantity(value, resource.BinarySI))
+	}
+	if !found {
+		return nil, nil
+	}
+	return usage, nil
+}
@@ -0,0 +1,278 @@
+package terminator
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Git hosts pull requests are opened on.
+const (
+	GitProviderGitHub = "github"
+	GitProviderGitLab = "gitlab"
+)
+
+// WorkloadKinds are the api version and kind of the workloads whose memory can
+// be patched, by their kind in workload names.
+var WorkloadKinds = map[string][2]string{
+	"deployment":  {"apps/v1", "Deployment"},
+	"statefulset": {"apps/v1", "StatefulSet"},
+	"daemonset":   {"apps/v1", "DaemonSet"},
+	"replicaset":  {"apps/v1", "ReplicaSet"},
+}
+
+// PullRequestOptions configure the pull requests patching the memory limits of
+// repeat offenders.
+type PullRequestOptions struct {
+	// Provider is github or gitlab
+	Provider string
+	// Repository is owner/name on GitHub, or the path of the project on
+	// GitLab
+	Repository string
+	// BaseURL is the address of the API, default is the one of github.com or
+	// gitlab.com
+	BaseURL string
+	Token   string
+	// Branch is the branch pull requests are opened against
+	Branch string
+	// Path is where the patch of a workload is written in the repository,
+	// with {cluster}, {namespace}, {kind} and {name} replaced by the ones of
+	// the workload. The patch is a standalone strategic merge patch, which
+	// only changes the workload once a kustomization lists it in its patches
+	Path string
+}
+
+type pullRequestNotifier struct {
+	options PullRequestOptions
+	client  *http.Client
+}
+
+// NewPullRequestNotifier returns a Notifier opening a pull request for the
+// repeat_offender events with a limit suggestion, writing a patch of the
+// workload setting the suggested limits at the Path of the repository, so the
+// fix is reviewed and kept instead of the terminator recycling its pods.
+func NewPullRequestNotifier(options PullRequestOptions) (Notifier, error) {
+	switch options.Provider {
+	case GitProviderGitHub:
+		if options.BaseURL == "" {
+			options.BaseURL = "https://api.github.com"
+		}
+	case GitProviderGitLab:
+		if options.BaseURL == "" {
+			options.BaseURL = "https://gitlab.com/api/v4"
+		}
+	default:
+		return nil, fmt.Errorf("invalid git provider %q, must be %s or %s", options.Provider, GitProviderGitHub, GitProviderGitLab)
+	}
+	if options.Repository == "" || options.Token == "" {
+		return nil, fmt.Errorf("pull requests need a repository and a token")
+	}
+	if options.Branch == "" {
+		options.Branch = "main"
+	}
+	if options.Path == "" {
+		options.Path = "{namespace}/{kind}-{name}-memory.yaml"
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+	return pullRequestNotifier{options: options, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+// remediation is the change proposed for a repeat offender.
+type remediation struct {
+	branch  string
+	path    string
+	content []byte
+	title   string
+	body    string
+}
+
+func (n pullRequestNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != eventRepeatOffender || event.Suggestion == nil {
+		return nil
+	}
+
+	kind, name, _ := strings.Cut(event.Workload, "/")
+	apiVersionKind, ok := WorkloadKinds[kind]
+	if !ok {
+		return nil
+	}
+
+	content, err := limitsPatch(event, apiVersionKind, name)
+	if err != nil {
+		return err
+	}
+	fix := remediation{
+		branch:  fmt.Sprintf("oomterminator/%s-%s-%s-%s", event.Namespace, kind, name, strings.ToLower(event.Suggestion.Limit.String())),
+		path:    strings.NewReplacer("{cluster}", event.Cluster, "{namespace}", event.Namespace, "{kind}", kind, "{name}", name).Replace(n.options.Path),
+		content: content,
+		title:   fmt.Sprintf("Raise the memory limit of %s/%s to %s", event.Namespace, event.Workload, event.Suggestion.Limit.String()),
+		body:    event.Message,
+	}
+	if event.Cluster != "" {
+		fix.title += " in " + event.Cluster
+	}
+
+	if n.options.Provider == GitProviderGitLab {
+		return n.gitLab(ctx, fix)
+	}
+	return n.gitHub(ctx, fix)
+}
+
+// limitsPatch is a patch of the workload of event setting the suggested memory
+// limits of its containers.
+func limitsPatch(event Event, apiVersionKind [2]string, name string) ([]byte, error) {
+	names := make([]string, 0, len(event.Suggestion.Containers))
+	for container := range event.Suggestion.Containers {
+		names = append(names, container)
+	}
+	sort.Strings(names)
+
+	containers := make([]interface{}, 0, len(names))
+	for _, container := range names {
+		limit := event.Suggestion.Containers[container]
+		containers = append(containers, map[string]interface{}{
+			"name":      container,
+			"resources": map[string]interface{}{"limits": map[string]interface{}{"memory": limit.String()}},
+		})
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": apiVersionKind[0],
+		"kind":       apiVersionKind[1],
+		"metadata":   map[string]interface{}{"name": name, "namespace": event.Namespace},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": containers},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# suggested by oomterminator from %d samples: %s\n", event.Suggestion.Samples, event.Message)
+	return append([]byte(header), data...), nil
+}
+
+// gitHub opens the pull request of fix on GitHub, unless one is already open
+// from its branch. A branch left without one, like when its pull request was
+// closed or opening it failed, gets the patch written again and a new one.
+func (n pullRequestNotifier) gitHub(ctx context.Context, fix remediation) error {
+	repository := n.options.BaseURL + "/repos/" + n.options.Repository
+	owner, _, _ := strings.Cut(n.options.Repository, "/")
+	var open []struct {
+		Number int `json:"number"`
+	}
+	if _, err := n.call(ctx, http.MethodGet, repository+"/pulls?state=open&head="+url.QueryEscape(owner+":"+fix.branch), nil, &open); err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return nil
+	}
+
+	var base struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if _, err := n.call(ctx, http.MethodGet, repository+"/git/ref/heads/"+n.options.Branch, nil, &base); err != nil {
+		return err
+	}
+
+	// the branch already exists when its pull request isn't open anymore
+	status, err := n.call(ctx, http.MethodPost, repository+"/git/refs", map[string]string{"ref": "refs/heads/" + fix.branch, "sha": base.Object.SHA}, nil)
+	if err != nil && status != http.StatusUnprocessableEntity {
+		return err
+	}
+
+	contents := repository + "/contents/" + escapePath(fix.path)
+	var existing struct {
+		SHA string `json:"sha"`
+	}
+	status, err = n.call(ctx, http.MethodGet, contents+"?ref="+url.QueryEscape(fix.branch), nil, &existing)
+	if err != nil && status != http.StatusNotFound {
+		return err
+	}
+	file := map[string]string{"message": fix.title, "content": base64.StdEncoding.EncodeToString(fix.content), "branch": fix.branch}
+	if existing.SHA != "" {
+		file["sha"] = existing.SHA
+	}
+	if _, err := n.call(ctx, http.MethodPut, contents, file, nil); err != nil {
+		return err
+	}
+
+	_, err = n.call(ctx, http.MethodPost, repository+"/pulls", map[string]string{"title": fix.title, "head": fix.branch, "base": n.options.Branch, "body": fix.body}, nil)
+	return err
+}
+
+// gitLab opens the merge request of fix on GitLab, unless one is already open
+// from its branch. A branch left without one gets the patch committed again
+// and a new one.
+func (n pullRequestNotifier) gitLab(ctx context.Context, fix remediation) error {
+	project := n.options.BaseURL + "/projects/" + url.PathEscape(n.options.Repository)
+	var open []struct {
+		IID int `json:"iid"`
+	}
+	if _, err := n.call(ctx, http.MethodGet, project+"/merge_requests?state=opened&source_branch="+url.QueryEscape(fix.branch), nil, &open); err != nil {
+		return err
+	}
+	if len(open) > 0 {
+		return nil
+	}
+
+	// the branch already exists when its merge request isn't open anymore
+	branches := fmt.Sprintf("%s/repository/branches?branch=%s&ref=%s", project, url.QueryEscape(fix.branch), url.QueryEscape(n.options.Branch))
+	status, err := n.call(ctx, http.MethodPost, branches, nil, nil)
+	if err != nil && status != http.StatusBadRequest {
+		return err
+	}
+
+	action := "update"
+	status, err = n.call(ctx, http.MethodGet, project+"/repository/files/"+url.PathEscape(fix.path)+"?ref="+url.QueryEscape(fix.branch), nil, nil)
+	if status == http.StatusNotFound {
+		action = "create"
+	} else if err != nil {
+		return err
+	}
+
+	commit := map[string]interface{}{
+		"branch":         fix.branch,
+		"commit_message": fix.title,
+		"actions":        []map[string]string{{"action": action, "file_path": fix.path, "content": string(fix.content)}},
+	}
+	if _, err := n.call(ctx, http.MethodPost, project+"/repository/commits", commit, nil); err != nil {
+		return err
+	}
+
+	_, err = n.call(ctx, http.MethodPost, project+"/merge_requests", map[string]string{"title": fix.title, "source_branch": fix.branch, "target_branch": n.options.Branch, "description": fix.body}, nil)
+	return err
+}
+
+// call sends body as JSON to the API of the git host and decodes its response
+// into out, returning its status code.
+func (n pullRequestNotifier) call(ctx context.Context, method, address string, body, out interface{}) (int, error) {
+	header := make(http.Header)
+	if n.options.Provider == GitProviderGitLab {
+		header.Set("PRIVATE-TOKEN", n.options.Token)
+	} else {
+		header.Set("Authorization", "Bearer "+n.options.Token)
+		header.Set("Accept", "application/vnd.github+json")
+	}
+	return callJSON(ctx, n.client, header, method, address, body, out)
+}
+
+// escapePath escapes each segment of path.
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,151 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	dcgmFramebufferUsed = "DCGM_FI_DEV_FB_USED"
+	dcgmFramebufferFree = "DCGM_FI_DEV_FB_FREE"
+)
+
+// GPUUsage is the GPU memory used by a pod and the total memory of its
+// devices, in bytes.
+type GPUUsage struct {
+	Used  int64
+	Total int64
+}
+
+func (u GPUUsage) percentage() float64 {
+	if u.Total == 0 {
+		return 0
+	}
+	return float64(u.Used) / float64(u.Total) * 100
+}
+
+// GPUProvider returns the GPU memory usage of pods, by namespace/name.
+type GPUProvider interface {
+	GPUUsage(ctx context.Context) (map[string]GPUUsage, error)
+}
+
+type dcgmProvider struct {
+	clientset kubernetes.Interface
+	namespace string
+	service   string
+	client    *http.Client
+}
+
+// NewDCGMProvider returns a GPUProvider scraping every endpoint of the
+// dcgm-exporter service namespace/service, which attributes the memory of
+// each device to the pod it is allocated to.
+func NewDCGMProvider(clientset kubernetes.Interface, namespace, service string) GPUProvider {
+	return dcgmProvider{clientset: clientset, namespace: namespace, service: service, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p dcgmProvider) GPUUsage(ctx context.Context) (map[string]GPUUsage, error) {
+	endpoints, err := p.clientset.CoreV1().Endpoints(p.namespace).Get(ctx, p.service, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string]GPUUsage)
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Ports) == 0 {
+			continue
+		}
+
+		port := subset.Ports[0].Port
+		for _, subsetPort := range subset.Ports {
+			if subsetPort.Name == "metrics" {
+				port = subsetPort.Port
+			}
+		}
+
+		for _, address := range subset.Addresses {
+			url := "http://" + net.JoinHostPort(address.IP, strconv.Itoa(int(port))) + "/metrics"
+			if err := p.scrape(ctx, url, usage); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return usage, nil
+}
+
+// scrape adds the GPU memory of the pods exposed by the exporter at url to
+// usage.
+func (p dcgmProvider) scrape(ctx context.Context, url string, usage map[string]GPUUsage) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("dcgm-exporter %s returned status %d", url, resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return fmt.Errorf("invalid metrics from dcgm-exporter %s: %w", url, err)
+	}
+
+	// the framebuffer metrics are in MiB
+	add := func(family *dto.MetricFamily, used bool) {
+		if family == nil {
+			return
+		}
+		for _, metric := range family.Metric {
+			labels := make(map[string]string, len(metric.Label))
+			for _, label := range metric.Label {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["pod"] == "" || metric.Gauge == nil {
+				continue
+			}
+
+			key := labels["namespace"] + "/" + labels["pod"]
+			bytes := int64(metric.Gauge.GetValue() * 1024 * 1024)
+			pod := usage[key]
+			if used {
+				pod.Used += bytes
+			}
+			pod.Total += bytes
+			usage[key] = pod
+		}
+	}
+	add(families[dcgmFramebufferUsed], true)
+	add(families[dcgmFramebufferFree], false)
+	return nil
+}
+
+// gpuUsage fetches the GPU memory of the pods once for a check. Failing to is
+// logged and only the memory of pods is evaluated.
+func (t terminator) gpuUsage(ctx context.Context) map[string]GPUUsage {
+	if t.gpu == nil {
+		return nil
+	}
+
+	usage, err := t.gpu.GPUUsage(ctx)
+	if err != nil {
+		t.log.Errorf("could not get the GPU usage of pods: %s", err)
+		return nil
+	}
+	return usage
+}
@@ -0,0 +1,80 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	eventErrorBudget = "error_budget_exhausted"
+	eventRecovered   = "recovered"
+)
+
+// health are the consecutive checks that failed, shared by every copy of the
+// terminator.
+type health struct {
+	mu       sync.Mutex
+	failures int
+}
+
+// Ready tells whether the checks are not failing for more than the
+// ErrorBudget.
+func (t terminator) Ready() bool {
+	t.health.mu.Lock()
+	defer t.health.mu.Unlock()
+	return t.options.ErrorBudget == 0 || t.health.failures < t.options.ErrorBudget
+}
+
+// checkFailed counts a failed check, telling whether it is within the
+// ErrorBudget and should be retried. Running out of it is notified once.
+// Without a budget, a check rejected as unauthorized is still retried once, as
+// credentials that expired during it, like the token of an exec plugin, are
+// refreshed by the transport on the rejection.
+func (t terminator) checkFailed(ctx context.Context, err error) bool {
+	if t.options.ErrorBudget == 0 {
+		if !errors.IsUnauthorized(err) {
+			return false
+		}
+
+		t.health.mu.Lock()
+		t.health.failures++
+		failures := t.health.failures
+		t.health.mu.Unlock()
+		if failures > 1 {
+			return false
+		}
+		t.out.Errorf("check failed, retrying with refreshed credentials: %s", err)
+		return true
+	}
+
+	t.health.mu.Lock()
+	t.health.failures++
+	failures := t.health.failures
+	t.health.mu.Unlock()
+
+	t.out.Errorf("check failed (%d in a row): %s", failures, err)
+	if failures == t.options.ErrorBudget {
+		message := fmt.Sprintf("%d checks failed in a row, not ready until one succeeds: %s", failures, err)
+		t.out.Errorf("%s", message)
+		t.notify(ctx, Event{Type: eventErrorBudget, Message: message})
+	}
+	return true
+}
+
+// checkSucceeded resets the failed checks, notifying the recovery when they
+// ran out of the ErrorBudget.
+func (t terminator) checkSucceeded(ctx context.Context) {
+	t.health.mu.Lock()
+	failures := t.health.failures
+	t.health.failures = 0
+	t.health.mu.Unlock()
+
+	if t.options.ErrorBudget > 0 && failures >= t.options.ErrorBudget {
+		message := fmt.Sprintf("checks are succeeding again after %d failed in a row", failures)
+		t.out.Print(message)
+		t.notify(ctx, Event{Type: eventRecovered, Message: message})
+	}
+}
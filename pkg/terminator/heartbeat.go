@@ -0,0 +1,53 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// heartbeat pings a dead man's switch, like healthchecks.io, after each
+// successful check, so a terminator that silently stops checking pages instead
+// of looking like a quiet cluster.
+type heartbeat struct {
+	url    string
+	client *http.Client
+}
+
+// newHeartbeat returns the heartbeat of url, with {cluster} replaced by the
+// cluster, so each cluster of a fleet has its own switch.
+func newHeartbeat(url, cluster string) *heartbeat {
+	return &heartbeat{url: strings.ReplaceAll(url, "{cluster}", cluster), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (h *heartbeat) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("heartbeat %s returned %s", h.url, resp.Status)
+	}
+	return nil
+}
+
+// beat pings the heartbeat, when there is one. Failing to ping it is logged,
+// the next check pings it again.
+func (t terminator) beat(ctx context.Context) {
+	if t.heartbeat == nil {
+		return
+	}
+
+	if err := t.heartbeat.ping(ctx); err != nil {
+		t.log.Errorf("could not ping the heartbeat: %s", err)
+	}
+}
@@ -0,0 +1,155 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const eventScaledUp = "scaled_up"
+
+// Annotations of the HPAs scaled up, with their minimum replicas before the
+// first scale up and when they were last scaled up, so they are restored after
+// the ScaleCooldown, even by another run of the terminator.
+const (
+	minReplicasAnnotation = "terminator.rubbioli.io/original-min-replicas"
+	scaledUpAnnotation    = "terminator.rubbioli.io/scaled-up"
+)
+
+// hpaScaleUp is a scale up of the HPA of a workload, at to replicas.
+type hpaScaleUp struct {
+	at       time.Time
+	replicas int32
+}
+
+// scaleUp raises the minimum replicas of the HPA of the workload of pod to one
+// over the higher of its minimum and current replicas, when it is below its
+// maximum replicas and wasn't scaled up within the ScaleCooldown, telling
+// whether pod shouldn't be killed. Memory pressure across many replicas
+// usually means the workload is under-provisioned rather than leaking. Pods of
+// a workload scaled up within the ScaleCooldown aren't killed either while its
+// current replicas are below the ones it was scaled up to.
+func (t terminator) scaleUp(ctx context.Context, c *check, pod *v1.Pod, dryRun bool, reason *Reason) (bool, error) {
+	hpa, err := t.hpaOf(ctx, c, pod)
+	if err != nil || hpa == nil {
+		return false, err
+	}
+
+	key := workloadKey(pod)
+	now := t.clock.Now()
+	current := hpa.Status.CurrentReplicas
+	c.mu.Lock()
+	last, ok := c.state.scaledUp[key]
+	if at, err := time.Parse(time.RFC3339, hpa.Annotations[scaledUpAnnotation]); err == nil && at.After(last.at) {
+		last, ok = hpaScaleUp{at: at, replicas: minReplicasOf(hpa)}, true
+	}
+	recent := ok && now.Sub(last.at) < t.options.ScaleCooldown
+	pending := recent && current < last.replicas
+	atMax := current >= hpa.Spec.MaxReplicas || minReplicasOf(hpa) >= hpa.Spec.MaxReplicas
+	replicas := minReplicasOf(hpa)
+	if current > replicas {
+		replicas = current
+	}
+	replicas++
+	if !recent && !atMax {
+		c.state.scaledUp[key] = hpaScaleUp{at: now, replicas: replicas}
+	}
+	c.mu.Unlock()
+	switch {
+	case pending:
+		t.log.Infof("hpa %s of pod < %s > is still scaling up to %d replicas, from %d", hpa.Name, pod.Name, last.replicas, current)
+		return true, nil
+	case recent:
+		t.log.Infof("hpa %s of pod < %s > was scaled up %s ago", hpa.Name, pod.Name, now.Sub(last.at).Round(time.Second))
+		return false, nil
+	case atMax:
+		t.log.Infof("hpa %s of pod < %s > is at its max replicas", hpa.Name, pod.Name)
+		return false, nil
+	}
+
+	workload := workloadName(pod)
+	message := fmt.Sprintf("scaling %s up to at least %d replicas instead of deleting pod %s", workload, replicas, pod.Name)
+	t.out.Print(message)
+	if !dryRun {
+		original := hpa.Annotations[minReplicasAnnotation]
+		if original == "" {
+			original = strconv.Itoa(int(minReplicasOf(hpa)))
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:%q,%q:%q}},"spec":{"minReplicas":%d}}`, minReplicasAnnotation, original, scaledUpAnnotation, now.UTC().Format(time.RFC3339), replicas)
+		if _, err := t.clientset.AutoscalingV1().HorizontalPodAutoscalers(pod.Namespace).Patch(ctx, hpa.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			return false, err
+		}
+		t.notify(ctx, Event{Type: eventScaledUp, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Reason: reason.snapshot()})
+	}
+
+	return true, nil
+}
+
+// restoreHPAs puts back the minimum replicas of the HPAs of namespace, all of
+// them when it is empty, scaled up more than the ScaleCooldown ago.
+func (t terminator) restoreHPAs(ctx context.Context, c *check, namespace string) {
+	hpas, err := t.clientset.AutoscalingV1().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.operationalError(ctx, c, namespace, "", "could not list the hpas to restore: %s", err)
+		return
+	}
+
+	now := t.clock.Now()
+	for _, hpa := range hpas.Items {
+		at, err := time.Parse(time.RFC3339, hpa.Annotations[scaledUpAnnotation])
+		if err != nil || now.Sub(at) < t.options.ScaleCooldown {
+			continue
+		}
+
+		spec := ""
+		if original, err := strconv.Atoi(hpa.Annotations[minReplicasAnnotation]); err == nil {
+			spec = fmt.Sprintf(`,"spec":{"minReplicas":%d}`, original)
+		}
+		patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null}}%s}`, minReplicasAnnotation, scaledUpAnnotation, spec)
+		if _, err := t.clientset.AutoscalingV1().HorizontalPodAutoscalers(hpa.Namespace).Patch(ctx, hpa.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{}); err != nil {
+			t.operationalError(ctx, c, hpa.Namespace, "", "could not restore the min replicas of hpa %s: %s", hpa.Name, err)
+			continue
+		}
+		t.out.Printf("Restored the min replicas of hpa %s in %s to %s", hpa.Name, hpa.Namespace, hpa.Annotations[minReplicasAnnotation])
+	}
+}
+
+// minReplicasOf returns the minimum replicas of hpa, 1 when unset.
+func minReplicasOf(hpa *autoscalingv1.HorizontalPodAutoscaler) int32 {
+	if hpa.Spec.MinReplicas == nil {
+		return 1
+	}
+	return *hpa.Spec.MinReplicas
+}
+
+// hpaOf returns the HPA scaling the workload of pod, or nil when it has none.
+// The HPAs of a namespace are fetched once on each check.
+func (t terminator) hpaOf(ctx context.Context, c *check, pod *v1.Pod) (*autoscalingv1.HorizontalPodAutoscaler, error) {
+	listed, err := c.hpas.get(pod.Namespace, func() (interface{}, error) {
+		list, err := t.clientset.AutoscalingV1().HorizontalPodAutoscalers(pod.Namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return list.Items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	hpas := listed.([]autoscalingv1.HorizontalPodAutoscaler)
+
+	workload := workloadName(pod)
+	for i := range hpas {
+		target := hpas[i].Spec.ScaleTargetRef
+		if strings.ToLower(target.Kind)+"/"+target.Name == workload {
+			return &hpas[i], nil
+		}
+	}
+	return nil, nil
+}
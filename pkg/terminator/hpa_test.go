@@ -0,0 +1,122 @@
+package terminator
+
+import (
+	"context"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+func hpaTestHPA(minReplicas, current, maxReplicas int32) *autoscalingv1.HorizontalPodAutoscaler {
+	return &autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web"},
+		Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{Kind: "Pod", Name: "api"},
+			MinReplicas:    &minReplicas,
+			MaxReplicas:    maxReplicas,
+		},
+		Status: autoscalingv1.HorizontalPodAutoscalerStatus{CurrentReplicas: current},
+	}
+}
+
+// TestScaleUp checks HPAs are scaled up to one over their current replicas
+// unless they run their max replicas, and that the pods of a workload scaled
+// up aren't killed until its replicas are up.
+func TestScaleUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		hpa      *autoscalingv1.HorizontalPodAutoscaler
+		calls    int
+		scaled   bool
+		replicas string
+	}{
+		{"over its min", hpaTestHPA(2, 2, 5), 1, true, `"minReplicas":3`},
+		{"over its current replicas", hpaTestHPA(2, 4, 10), 1, true, `"minReplicas":5`},
+		{"running its max replicas", hpaTestHPA(2, 5, 5), 1, false, ""},
+		{"at its max min replicas", hpaTestHPA(5, 3, 5), 1, false, ""},
+		{"pending on the same check", hpaTestHPA(2, 2, 5), 2, true, `"minReplicas":3`},
+	}
+	for _, test := range tests {
+		clientset := fake.NewSimpleClientset(test.hpa)
+		debug := logrus.New()
+		debug.SetOutput(io.Discard)
+		created, err := NewForClients(clientset, metricsfake.NewSimpleClientset(), Options{Workers: 1, Quiet: true, ScaleHPAs: true, ScaleCooldown: time.Hour},
+			WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		terminator := created.(terminator)
+		c, err := terminator.newCheck(context.Background(), newState(), nil, terminator.defaultPolicy(95, 0), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < test.calls; i++ {
+			scaled, err := terminator.scaleUp(context.Background(), c, windowsTestPod("api", ""), false, &Reason{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scaled != test.scaled {
+				t.Errorf("%s: expected scaled %v on call %d, got %v", test.name, test.scaled, i+1, scaled)
+			}
+		}
+
+		var patches []string
+		for _, action := range clientset.Actions() {
+			if action.Matches("patch", "horizontalpodautoscalers") {
+				patches = append(patches, string(action.(k8stesting.PatchAction).GetPatch()))
+			}
+		}
+		switch {
+		case test.replicas == "" && len(patches) != 0:
+			t.Errorf("%s: expected no scale up, got %v", test.name, patches)
+		case test.replicas != "" && (len(patches) != 1 || !strings.Contains(patches[0], test.replicas)):
+			t.Errorf("%s: expected a single scale up to %s, got %v", test.name, test.replicas, patches)
+		}
+	}
+}
+
+// TestScaleUpCooldown checks pods of a workload scaled up within the cooldown
+// are killed once its replicas are up, but not before.
+func TestScaleUpCooldown(t *testing.T) {
+	for _, test := range []struct {
+		name    string
+		current int32
+		scaled  bool
+	}{
+		{"replicas starting", 2, true},
+		{"replicas up", 3, false},
+	} {
+		hpa := hpaTestHPA(3, test.current, 5)
+		hpa.Annotations = map[string]string{minReplicasAnnotation: "2", scaledUpAnnotation: time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)}
+		debug := logrus.New()
+		debug.SetOutput(io.Discard)
+		created, err := NewForClients(fake.NewSimpleClientset(hpa), metricsfake.NewSimpleClientset(), Options{Workers: 1, Quiet: true, ScaleHPAs: true, ScaleCooldown: time.Hour},
+			WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		terminator := created.(terminator)
+		c, err := terminator.newCheck(context.Background(), newState(), nil, terminator.defaultPolicy(95, 0), 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scaled, err := terminator.scaleUp(context.Background(), c, windowsTestPod("api", ""), false, &Reason{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if scaled != test.scaled {
+			t.Errorf("%s: expected the kill skipped %v, got %v", test.name, test.scaled, scaled)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Trackers issues are opened on.
+const (
+	IssueTrackerGitHub = "github"
+	IssueTrackerJira   = "jira"
+)
+
+// issueLabel labels the issues opened by the terminator, so the open ones are
+// found again instead of duplicated.
+const issueLabel = "oomterminator"
+
+const (
+	defaultIssueTitle = `{{.Workload}} in {{.Namespace}}{{with .Cluster}} of {{.}}{{end}} keeps going over its memory limit`
+	defaultIssueBody  = `{{.Message}}.
+{{with .Team}}
+Team: {{.}}
+{{end}}{{with .Suggestion}}
+Suggested memory limit: {{.Limit}} instead of {{.Current}}, from {{.Samples}} samples of its usage.
+{{range $container, $limit := .Containers}}- {{$container}}: {{bytes $limit}}
+{{end}}{{end}}{{with .History}}
+Usage when its pods were killed: {{sparkline .}}
+
+| Time | Pod | Usage | Limit | Percentage | Checks over the limit |
+| --- | --- | --- | --- | --- | --- |
+{{range .}}| {{.Time.Format "2006-01-02 15:04:05"}} | {{.Pod}} | {{bytes .Usage}} | {{bytes .Limit}} | {{printf "%.0f" .Percentage}}% | {{.OverCount}} |
+{{end}}{{end}}`
+)
+
+// IssueOptions configure the issues opened for the escalations of repeat
+// offenders.
+type IssueOptions struct {
+	// Tracker is github or jira
+	Tracker string
+	// BaseURL is the address of the API, default is the one of github.com
+	// for GitHub, and the address of the Jira site otherwise
+	BaseURL string
+	// Repository is owner/name of the GitHub repository, and Project the key
+	// of the Jira project, issues are opened on
+	Repository string
+	Project    string
+	// IssueType is the type of the Jira issues, default is Task
+	IssueType string
+	// User is the email authenticating with Token to Jira Cloud, without it
+	// Token is a Jira personal access token
+	User  string
+	Token string
+	// Title and Body are text/template templates of the issues, executed
+	// with the Event, default is a summary with the usage of the killed pods
+	Title string
+	Body  string
+}
+
+type issueNotifier struct {
+	options IssueOptions
+	title   *template.Template
+	body    *template.Template
+	client  *http.Client
+}
+
+// NewIssueNotifier returns a Notifier opening an issue for each repeat_offender
+// event, assigned to the Assignees of the team owning its namespace, unless an
+// open issue of the terminator already has the same title.
+func NewIssueNotifier(options IssueOptions) (Notifier, error) {
+	switch options.Tracker {
+	case IssueTrackerGitHub:
+		if options.Repository == "" {
+			return nil, fmt.Errorf("github issues need a repository")
+		}
+		if options.BaseURL == "" {
+			options.BaseURL = "https://api.github.com"
+		}
+	case IssueTrackerJira:
+		if options.Project == "" || options.BaseURL == "" {
+			return nil, fmt.Errorf("jira issues need a project and the address of the jira site")
+		}
+		if options.IssueType == "" {
+			options.IssueType = "Task"
+		}
+	default:
+		return nil, fmt.Errorf("invalid issue tracker %q, must be %s or %s", options.Tracker, IssueTrackerGitHub, IssueTrackerJira)
+	}
+	if options.Token == "" {
+		return nil, fmt.Errorf("issues need a token")
+	}
+	if options.Title == "" {
+		options.Title = defaultIssueTitle
+	}
+	if options.Body == "" {
+		options.Body = defaultIssueBody
+	}
+	options.BaseURL = strings.TrimSuffix(options.BaseURL, "/")
+
+	funcs := template.FuncMap{"bytes": formatBytes, "sparkline": sparkline}
+	title, err := template.New("title").Funcs(funcs).Parse(options.Title)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue title: %w", err)
+	}
+	body, err := template.New("body").Funcs(funcs).Parse(options.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid issue body: %w", err)
+	}
+
+	return issueNotifier{options: options, title: title, body: body, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (n issueNotifier) Notify(ctx context.Context, event Event) error {
+	if event.Type != eventRepeatOffender {
+		return nil
+	}
+
+	var title, body bytes.Buffer
+	if err := n.title.Execute(&title, event); err != nil {
+		return err
+	}
+	if err := n.body.Execute(&body, event); err != nil {
+		return err
+	}
+	summary := strings.TrimSpace(title.String())
+
+	if n.options.Tracker == IssueTrackerJira {
+		return n.jira(ctx, summary, body.String(), event.Assignees)
+	}
+	return n.gitHub(ctx, summary, body.String(), event.Assignees)
+}
+
+// gitHub opens the issue on GitHub, unless an open one of the terminator has
+// the same title.
+func (n issueNotifier) gitHub(ctx context.Context, title, body string, assignees []string) error {
+	repository := n.options.BaseURL + "/repos/" + n.options.Repository
+	var open []struct {
+		Title string `json:"title"`
+	}
+	if err := n.call(ctx, http.MethodGet, repository+"/issues?state=open&per_page=100&labels="+issueLabel, nil, &open); err != nil {
+		return err
+	}
+	for _, issue := range open {
+		if issue.Title == title {
+			return nil
+		}
+	}
+
+	issue := map[string]interface{}{"title": title, "body": body, "labels": []string{issueLabel}}
+	if len(assignees) > 0 {
+		issue["assignees"] = assignees
+	}
+	return n.call(ctx, http.MethodPost, repository+"/issues", issue, nil)
+}
+
+// jira opens the issue on Jira, unless an unresolved one of the terminator has
+// the same summary. Jira issues have a single assignee, the first one.
+func (n issueNotifier) jira(ctx context.Context, summary, description string, assignees []string) error {
+	jql := fmt.Sprintf(`project = %q AND labels = %s AND statusCategory != Done AND summary ~ %q`, n.options.Project, issueLabel, summary)
+	var found struct {
+		Issues []struct {
+			Fields struct {
+				Summary string `json:"summary"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	if err := n.call(ctx, http.MethodGet, n.options.BaseURL+"/rest/api/2/search?fields=summary&jql="+url.QueryEscape(jql), nil, &found); err != nil {
+		return err
+	}
+	for _, issue := range found.Issues {
+		if issue.Fields.Summary == summary {
+			return nil
+		}
+	}
+
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": n.options.Project},
+		"issuetype":   map[string]string{"name": n.options.IssueType},
+		"summary":     summary,
+		"description": description,
+		"labels":      []string{issueLabel},
+	}
+	if len(assignees) > 0 {
+		fields["assignee"] = map[string]string{"accountId": assignees[0]}
+	}
+	return n.call(ctx, http.MethodPost, n.options.BaseURL+"/rest/api/2/issue", map[string]interface{}{"fields": fields}, nil)
+}
+
+// call sends body as JSON to the API of the tracker and decodes its response
+// into out.
+func (n issueNotifier) call(ctx context.Context, method, address string, body, out interface{}) error {
+	header := make(http.Header)
+	switch {
+	case n.options.Tracker == IssueTrackerGitHub:
+		header.Set("Authorization", "Bearer "+n.options.Token)
+		header.Set("Accept", "application/vnd.github+json")
+	case n.options.User != "":
+		header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(n.options.User+":"+n.options.Token)))
+	default:
+		header.Set("Authorization", "Bearer "+n.options.Token)
+	}
+	_, err := callJSON(ctx, n.client, header, method, address, body, out)
+	return err
+}
+
+// formatBytes formats bytes like the output of the terminator, like 1.5Gi, and
+// quantities as they would be set, like 1192Mi.
+func formatBytes(value interface{}) string {
+	switch value := value.(type) {
+	case int64:
+		return FormatBytes(UnitsBinary, value)
+	case resource.Quantity:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// sparkline draws the usage percentage of the kills of history, from the
+// lowest to the highest one.
+func sparkline(history []KillRecord) string {
+	bars := []rune("▁▂▃▄▅▆▇█")
+	lowest, highest := 0.0, 0.0
+	for i, kill := range history {
+		if i == 0 || kill.Percentage < lowest {
+			lowest = kill.Percentage
+		}
+		if kill.Percentage > highest {
+			highest = kill.Percentage
+		}
+	}
+
+	var line strings.Builder
+	for _, kill := range history {
+		bar := len(bars) - 1
+		if highest > lowest {
+			bar = int((kill.Percentage - lowest) / (highest - lowest) * float64(len(bars)-1))
+		}
+		line.WriteRune(bars[bar])
+	}
+	return line.String()
+}
@@ -0,0 +1,48 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// callJSON sends body as JSON to address with header, which authenticates to
+// the API, and decodes its response into out, returning its status code. A
+// status of 300 or more is an error.
+func callJSON(ctx context.Context, client *http.Client, header http.Header, method, address string, body, out interface{}) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, address, reader)
+	if err != nil {
+		return 0, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s %s returned %s", method, strings.SplitN(address, "?", 2)[0], resp.Status)
+	}
+	if out == nil {
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,69 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCallJSON(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]string{"echo": body["title"]})
+	}))
+	defer server.Close()
+
+	header := http.Header{"Private-Token": {"secret"}}
+	var out map[string]string
+	status, err := callJSON(context.Background(), server.Client(), header, http.MethodPost, server.URL+"/created", map[string]string{"title": "fix"}, &out)
+	if err != nil || status != http.StatusCreated || out["echo"] != "fix" {
+		t.Errorf("expected 201 with the title echoed, got %d %v %v", status, out, err)
+	}
+
+	status, err = callJSON(context.Background(), server.Client(), header, http.MethodGet, server.URL+"/missing?ref=main", nil, nil)
+	if err == nil || status != http.StatusNotFound {
+		t.Errorf("expected a 404 error, got %d %v", status, err)
+	}
+	if expected := "GET " + server.URL + "/missing returned 404 Not Found"; err != nil && err.Error() != expected {
+		t.Errorf("expected %q without the query, got %q", expected, err)
+	}
+}
+
+// TestIssueJiraAuth checks Jira Cloud is called with the basic auth of the
+// user and its token.
+func TestIssueJiraAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, token, ok := r.BasicAuth(); !ok || user != "sre@example.com" || token != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"issues":[]}`))
+	}))
+	defer server.Close()
+
+	notifier, err := NewIssueNotifier(IssueOptions{Tracker: IssueTrackerJira, BaseURL: server.URL, Project: "SRE", User: "sre@example.com", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := notifier.(issueNotifier)
+	issues.client = server.Client()
+	var found struct{}
+	if err := issues.call(context.Background(), http.MethodGet, server.URL+"/rest/api/2/search", nil, &found); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,92 @@
+package terminator
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// KafkaOptions configures the Kafka notifier.
+type KafkaOptions struct {
+	Brokers []string
+	Topic   string
+	// SASLMechanism is plain, scram-sha-256 or scram-sha-512, empty disables
+	// SASL
+	SASLMechanism string
+	Username      string
+	Password      string
+	// TLS connects to the brokers with TLS, verified against the system roots
+	TLS bool
+	// CloudEvents publishes events as structured CloudEvents instead of the
+	// plain notification
+	CloudEvents bool
+}
+
+type kafkaNotifier struct {
+	writer      *kafka.Writer
+	cloudEvents bool
+}
+
+// NewKafkaNotifier returns a Notifier publishing events to a Kafka topic,
+// keyed by namespace/pod so the events of a pod stay in order.
+func NewKafkaNotifier(options KafkaOptions) (Notifier, error) {
+	transport := &kafka.Transport{}
+	if options.TLS {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	var mechanism sasl.Mechanism
+	var err error
+	switch options.SASLMechanism {
+	case "":
+	case "plain":
+		mechanism = plain.Mechanism{Username: options.Username, Password: options.Password}
+	case "scram-sha-256":
+		mechanism, err = scram.Mechanism(scram.SHA256, options.Username, options.Password)
+	case "scram-sha-512":
+		mechanism, err = scram.Mechanism(scram.SHA512, options.Username, options.Password)
+	default:
+		return nil, fmt.Errorf("invalid kafka-sasl-mechanism %q, must be plain, scram-sha-256 or scram-sha-512", options.SASLMechanism)
+	}
+	if err != nil {
+		return nil, err
+	}
+	transport.SASL = mechanism
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(options.Brokers...),
+		Topic:        options.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		// events are written one at a time, without waiting for a batch
+		BatchTimeout: 10 * time.Millisecond,
+		WriteTimeout: 10 * time.Second,
+		Transport:    transport,
+	}
+	return kafkaNotifier{writer: writer, cloudEvents: options.CloudEvents}, nil
+}
+
+func (n kafkaNotifier) Notify(ctx context.Context, event Event) error {
+	message := kafka.Message{Key: []byte(event.Namespace + "/" + event.Pod)}
+
+	var err error
+	if n.cloudEvents {
+		// the structured content mode of the Kafka binding
+		message.Value, err = json.Marshal(newCloudEvent(event))
+		message.Headers = []kafka.Header{{Key: "content-type", Value: []byte(cloudEventsContentType)}}
+	} else {
+		message.Value, err = json.Marshal(event)
+	}
+	if err != nil {
+		return err
+	}
+
+	return n.writer.WriteMessages(ctx, message)
+}
@@ -0,0 +1,94 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// kubeletSummary is the part of the stats summary of a kubelet the terminator
+// uses, from /stats/summary.
+type kubeletSummary struct {
+	Pods []kubeletPodStats `json:"pods"`
+}
+
+type kubeletPodStats struct {
+	PodRef struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"podRef"`
+	Containers []struct {
+		Name   string `json:"name"`
+		Memory *struct {
+			UsageBytes *uint64 `json:"usageBytes"`
+			RSSBytes   *uint64 `json:"rssBytes"`
+		} `json:"memory"`
+	} `json:"containers"`
+	ProcessStats *struct {
+		ProcessCount *uint64 `json:"process_count"`
+	} `json:"process_stats"`
+	// Swap is only reported by kubelets of nodes with swap, since 1.28
+	Swap *struct {
+		SwapUsageBytes *uint64 `json:"swapUsageBytes"`
+	} `json:"swap"`
+}
+
+// kubeletConfig is the part of the configuration of a kubelet the terminator
+// uses, from /configz.
+type kubeletConfig struct {
+	KubeletConfig struct {
+		PodPidsLimit *int64 `json:"podPidsLimit"`
+	} `json:"kubeletconfig"`
+}
+
+// nodeStats returns the stats of the pods of node by namespace/name, through
+// the API server proxy. They are fetched once on each check.
+func (t terminator) nodeStats(ctx context.Context, c *check, node string) (map[string]kubeletPodStats, error) {
+	stats, err := c.nodeStats.get(node, func() (interface{}, error) {
+		var summary kubeletSummary
+		if err := t.kubeletGet(ctx, node, &summary, "stats", "summary"); err != nil {
+			return nil, err
+		}
+
+		stats := make(map[string]kubeletPodStats, len(summary.Pods))
+		for _, pod := range summary.Pods {
+			stats[pod.PodRef.Namespace+"/"+pod.PodRef.Name] = pod
+		}
+		return stats, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats.(map[string]kubeletPodStats), nil
+}
+
+// nodePidsLimit returns the pids limit of each pod of node, zero when there is
+// none. It is fetched once on each check.
+func (t terminator) nodePidsLimit(ctx context.Context, c *check, node string) (int64, error) {
+	limit, err := c.nodePidsLimits.get(node, func() (interface{}, error) {
+		var config kubeletConfig
+		if err := t.kubeletGet(ctx, node, &config, "configz"); err != nil {
+			return nil, err
+		}
+
+		var limit int64
+		if config.KubeletConfig.PodPidsLimit != nil && *config.KubeletConfig.PodPidsLimit > 0 {
+			limit = *config.KubeletConfig.PodPidsLimit
+		}
+		return limit, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return limit.(int64), nil
+}
+
+// kubeletGet decodes the JSON at path of the kubelet of node into v.
+func (t terminator) kubeletGet(ctx context.Context, node string, v interface{}, path ...string) error {
+	data, err := t.clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix(path...).
+		DoRaw(ctx)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
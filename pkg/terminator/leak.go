@@ -0,0 +1,264 @@
+package terminator
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// minLeakFit is the minimum coefficient of determination of the fitted usage of
+// a pod for its growth to be considered steady rather than noise.
+const minLeakFit = 0.5
+
+// Analysis configures the leak analysis.
+type Analysis struct {
+	Sleep          time.Duration
+	ReportInterval time.Duration
+	// Window is how long samples are kept
+	Window time.Duration
+	// LeakThreshold is the memory growth per hour from which a pod is leaking
+	LeakThreshold resource.Quantity
+	// MinSamples is the amount of samples a pod needs to be analyzed
+	MinSamples int
+	// Report receives the reports of every workload instead of them being
+	// printed, when set
+	Report func([]LeakReport)
+}
+
+// LeakReport is the analysis of the memory growth of the pods of a workload.
+type LeakReport struct {
+	Cluster  string `json:"cluster,omitempty"`
+	Workload string `json:"workload"`
+	Analyzed int    `json:"analyzed"`
+	Growing  int    `json:"growing"`
+	// GrowthPerHour is the average growth of the growing pods in bytes
+	GrowthPerHour int64 `json:"growthPerHour"`
+	Leaking       bool  `json:"leaking"`
+	// Cost is the cost of leaking workloads, when there is an OpenCostURL
+	Cost *WorkloadCost `json:"cost,omitempty"`
+}
+
+type usageSample struct {
+	at    time.Time
+	bytes float64
+}
+
+type podSamples struct {
+	name     string
+	samples  []usageSample
+	lastSeen time.Time
+}
+
+// leakDetector keeps the memory usage samples of pods by workload, including
+// pods that are already gone, so growth can be compared across generations.
+type leakDetector struct {
+	mu        sync.Mutex
+	workloads map[string]map[types.UID]*podSamples
+}
+
+// leakReport is the analysis of the pods of a workload.
+type leakReport struct {
+	workload string
+	analyzed int
+	growing  int
+	// slope is the average growth of the growing pods in bytes per hour
+	slope float64
+}
+
+func (r leakReport) leaking() bool {
+	return r.analyzed > 0 && r.growing*2 >= r.analyzed
+}
+
+func newLeakDetector() *leakDetector {
+	return &leakDetector{workloads: make(map[string]map[types.UID]*podSamples)}
+}
+
+func (d *leakDetector) add(pod *v1.Pod, usage *resource.Quantity, at time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := workloadKey(pod)
+	if d.workloads[key] == nil {
+		d.workloads[key] = make(map[types.UID]*podSamples)
+	}
+
+	samples, ok := d.workloads[key][pod.UID]
+	if !ok {
+		samples = &podSamples{name: pod.Name}
+		d.workloads[key][pod.UID] = samples
+	}
+	samples.samples = append(samples.samples, usageSample{at: at, bytes: float64(usage.Value())})
+	samples.lastSeen = at
+}
+
+// prune drops samples older than window, and pods left without any.
+func (d *leakDetector) prune(window time.Duration, now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for key, pods := range d.workloads {
+		for uid, pod := range pods {
+			first := sort.Search(len(pod.samples), func(i int) bool {
+				return now.Sub(pod.samples[i].at) <= window
+			})
+			pod.samples = pod.samples[first:]
+			if len(pod.samples) == 0 {
+				delete(pods, uid)
+			}
+		}
+		if len(pods) == 0 {
+			delete(d.workloads, key)
+		}
+	}
+}
+
+// report fits the usage of every pod with at least minSamples samples over time.
+// A pod is growing when the slope is over threshold bytes per hour and the fit
+// is steady, and a workload is probably leaking when at least half of its
+// analyzed pods are growing.
+func (d *leakDetector) report(threshold float64, minSamples int) []leakReport {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	reports := make([]leakReport, 0, len(d.workloads))
+	for key, pods := range d.workloads {
+		report := leakReport{workload: key}
+		var slopes float64
+		for _, pod := range pods {
+			if len(pod.samples) < minSamples {
+				continue
+			}
+
+			report.analyzed++
+			slope, fit := linearFit(pod.samples)
+			if slope >= threshold && fit >= minLeakFit {
+				report.growing++
+				slopes += slope
+			}
+		}
+
+		if report.growing > 0 {
+			report.slope = slopes / float64(report.growing)
+		}
+		reports = append(reports, report)
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		return reports[i].workload < reports[j].workload
+	})
+	return reports
+}
+
+// linearFit fits samples with least squares, returning the slope in bytes per
+// hour and the coefficient of determination of the fit.
+func linearFit(samples []usageSample) (float64, float64) {
+	n := float64(len(samples))
+	var sumX, sumY, sumXY, sumXX, sumYY float64
+	for _, sample := range samples {
+		x := sample.at.Sub(samples[0].at).Hours()
+		sumX += x
+		sumY += sample.bytes
+		sumXY += x * sample.bytes
+		sumXX += x * x
+		sumYY += sample.bytes * sample.bytes
+	}
+
+	varX := n*sumXX - sumX*sumX
+	varY := n*sumYY - sumY*sumY
+	if varX == 0 {
+		return 0, 0
+	}
+
+	cov := n*sumXY - sumX*sumY
+	slope := cov / varX
+	if varY == 0 {
+		return slope, 1
+	}
+	return slope, cov * cov / (varX * varY)
+}
+
+// Analyze samples the memory usage of the targeted pods and periodically logs
+// the workloads that are probably leaking memory. No pod is killed.
+func (t terminator) Analyze(ctx context.Context, targets Targets, analysis Analysis) error {
+	watcher, err := t.watchTargets(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	detector := newLeakDetector()
+	lastReport := t.clock.Now()
+	for {
+		pods, err := t.getPods(ctx, watcher, targets)
+		if err != nil {
+			return err
+		}
+
+		now := t.clock.Now()
+		err = t.evaluate(ctx, pods.Items, func(ctx context.Context, pod *v1.Pod) error {
+			if pod.Status.Phase != v1.PodRunning {
+				return nil
+			}
+
+			usage, err := t.podUsage(ctx, pod)
+			if err != nil || usage == nil {
+				return err
+			}
+
+			detector.add(pod, usage, now)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		detector.prune(analysis.Window, now)
+		if now.Sub(lastReport) >= analysis.ReportInterval {
+			t.printLeakReport(ctx, analysis, detector.report(float64(analysis.LeakThreshold.Value()), analysis.MinSamples))
+			lastReport = now
+		}
+
+		if err := t.sleep(ctx, analysis.Sleep); err != nil {
+			return err
+		}
+	}
+}
+
+// printLeakReport prints the workloads probably leaking, or passes the
+// reports to the Report of analysis.
+func (t terminator) printLeakReport(ctx context.Context, analysis Analysis, reports []leakReport) {
+	leaks := 0
+	exported := make([]LeakReport, 0, len(reports))
+	for _, report := range reports {
+		leakReport := LeakReport{
+			Cluster:       t.options.Cluster,
+			Workload:      report.workload,
+			Analyzed:      report.analyzed,
+			Growing:       report.growing,
+			GrowthPerHour: int64(report.slope),
+			Leaking:       report.leaking(),
+		}
+		if report.leaking() {
+			leaks++
+			namespace, workload := splitNamespacedName(report.workload)
+			leakReport.Cost = t.workloadCost(ctx, namespace, workload)
+			if analysis.Report == nil {
+				t.out.Printf("%s is probably leaking memory: %d of %d pods growing %s per hour on average", report.workload, report.growing, report.analyzed, t.format.bytesOf(leakReport.GrowthPerHour))
+				if leakReport.Cost != nil {
+					t.out.Printf("%s costs %s", report.workload, leakReport.Cost)
+				}
+			}
+		} else {
+			t.log.Infof("%s is not leaking: %d of %d pods growing", report.workload, report.growing, report.analyzed)
+		}
+		exported = append(exported, leakReport)
+	}
+	if analysis.Report != nil {
+		analysis.Report(exported)
+	}
+	t.out.Printf("leak report: %d of %d workloads probably leaking", leaks, len(reports))
+}
@@ -0,0 +1,128 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// memoryLimit returns the memory limit pod is compared against, the sum of the
+// limits of its regular containers. Init containers, native sidecars included,
+// and ephemeral containers are left out.
+// Containers without a limit fall back to the LimitRange default of their
+// namespace and, when NoLimitBasis is set, to the allocatable memory of their
+// node. Otherwise NoLimitAction decides, and a nil limit means the pod should
+// be skipped.
+func (t terminator) memoryLimit(ctx context.Context, c *check, pod *v1.Pod) (*resource.Quantity, error) {
+	limit := resource.NewQuantity(0, resource.BinarySI)
+	unlimited := false
+	for _, container := range pod.Spec.Containers {
+		containerLimit := container.Resources.Limits.Memory()
+		if containerLimit.IsZero() {
+			defaultLimit, err := t.defaultMemoryLimit(ctx, c, pod.Namespace)
+			if err != nil {
+				return nil, err
+			}
+			if defaultLimit == nil {
+				unlimited = true
+				break
+			}
+
+			t.log.Infof("container %s of pod < %s > has no memory limit, using the LimitRange default of %s", container.Name, pod.Name, t.format.bytes(defaultLimit))
+			containerLimit = defaultLimit
+		}
+		limit.Add(*containerLimit)
+	}
+
+	if !unlimited {
+		return limit, nil
+	}
+
+	if t.options.NoLimitBasis == NoLimitBasisNodeAllocatable && pod.Spec.NodeName != "" {
+		allocatable, err := t.allocatableMemory(ctx, c, pod.Spec.NodeName)
+		if err != nil {
+			return nil, err
+		}
+		t.log.Infof("pod < %s > has no memory limit, using the allocatable memory of node %s (%s)", pod.Name, pod.Spec.NodeName, t.format.bytes(allocatable))
+		return allocatable, nil
+	}
+
+	switch t.options.NoLimitAction {
+	case NoLimitActionUseAbsolute:
+		t.log.Infof("pod < %s > has no memory limit, using the absolute limit of %s", pod.Name, t.format.bytes(t.options.AbsoluteLimit))
+		return t.options.AbsoluteLimit, nil
+	case NoLimitActionWarn:
+		t.out.Printf("pod < %s > has no memory limit, skipping it", pod.Name)
+	default:
+		t.log.Infof("pod < %s > has no memory limit, skipping it", pod.Name)
+	}
+
+	skippedPods.WithLabelValues(t.options.Cluster, skipReasonNoLimit).Inc()
+	return nil, nil
+}
+
+// memoryUsage sums the memory usage of the regular containers of pod, so a
+// kubectl debug session or a heavy init step doesn't count towards the limit.
+func memoryUsage(pod *v1.Pod, podMetrics *v1beta1.PodMetrics) *resource.Quantity {
+	containers := make(map[string]bool, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containers[container.Name] = true
+	}
+
+	usage := resource.NewQuantity(0, resource.BinarySI)
+	for _, container := range podMetrics.Containers {
+		if containers[container.Name] {
+			usage.Add(*container.Usage.Memory())
+		}
+	}
+
+	return usage
+}
+
+// defaultMemoryLimit returns the default memory limit for containers set by the
+// LimitRanges of namespace, or nil when there is none. LimitRanges are listed
+// once per namespace on each check.
+func (t terminator) defaultMemoryLimit(ctx context.Context, c *check, namespace string) (*resource.Quantity, error) {
+	limit, err := c.defaultLimits.get(namespace, func() (interface{}, error) {
+		limitRanges, err := t.clientset.CoreV1().LimitRanges(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		var limit *resource.Quantity
+		for _, limitRange := range limitRanges.Items {
+			for _, item := range limitRange.Spec.Limits {
+				if item.Type != v1.LimitTypeContainer {
+					continue
+				}
+				if memory, ok := item.Default[v1.ResourceMemory]; ok {
+					limit = &memory
+				}
+			}
+		}
+		return limit, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return limit.(*resource.Quantity), nil
+}
+
+// allocatableMemory returns the allocatable memory of node. Nodes are fetched
+// once on each check.
+func (t terminator) allocatableMemory(ctx context.Context, c *check, name string) (*resource.Quantity, error) {
+	allocatable, err := c.nodeAllocatable.get(name, func() (interface{}, error) {
+		node, err := t.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return node.Status.Allocatable.Memory(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return allocatable.(*resource.Quantity), nil
+}
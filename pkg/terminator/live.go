@@ -0,0 +1,89 @@
+package terminator
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// live is what is changed while the terminator runs, shared by its copies.
+type live struct {
+	mu       sync.Mutex
+	policies *PolicyFile
+	// dumps are the dumps requested and not done yet
+	dumps chan struct{}
+}
+
+func (l *live) currentPolicies() *PolicyFile {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.policies
+}
+
+func (t terminator) SetPolicies(policies *PolicyFile) {
+	t.live.mu.Lock()
+	defer t.live.mu.Unlock()
+	t.live.policies = policies
+}
+
+func (t terminator) Dump() {
+	select {
+	case t.live.dumps <- struct{}{}:
+	default:
+		// a dump is already pending
+	}
+}
+
+// wait is like sleep, calling dump for the dumps requested meanwhile.
+func (t terminator) wait(ctx context.Context, d time.Duration, dump func()) error {
+	done := t.clock.After(d)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.live.dumps:
+			dump()
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// dump logs the state of the terminator, between checks.
+func (t terminator) dump(state *state, targets Targets, defaults policy, sleep, killSleep time.Duration) {
+	t.out.Printf("Dump: checking for pods%s every %s", targets, sleep)
+	t.out.Printf("Dump: limit %d%%, kill after %d checks, kill sleep %s, cooldown %s, dry run %t", defaults.limit, defaults.killAfter, killSleep, defaults.cooldown, t.options.DryRun)
+	if defaults.condition != nil {
+		t.out.Printf("Dump: condition %s", defaults.condition)
+	}
+
+	policies := t.live.currentPolicies()
+	if policies != nil {
+		t.out.Printf("Dump: %d namespace policies", len(policies.Namespaces))
+	}
+
+	t.out.Printf("Dump: watching %d pods: %s", len(state.watched), strings.Join(state.watched, ", "))
+
+	over := make([]*overLimit, 0, len(state.podsToKill))
+	for _, pod := range state.podsToKill {
+		over = append(over, pod)
+	}
+	sort.Slice(over, func(i, j int) bool {
+		if over[i].name != over[j].name {
+			return over[i].name < over[j].name
+		}
+		return over[i].at.Before(over[j].at)
+	})
+	for _, pod := range over {
+		t.out.Printf("Dump: pod < %s > over the limit for %d checks since %s", pod.name, pod.count+1, pod.at.Format(time.RFC3339))
+	}
+
+	for workload := range state.pausedWorkloads {
+		t.out.Printf("Dump: kills of %s are paused, it is crash looping", workload)
+	}
+	for pod := range state.deferredKills {
+		t.out.Printf("Dump: kill of pod < %s > deferred by a blackout", pod)
+	}
+}
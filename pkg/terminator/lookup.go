@@ -0,0 +1,53 @@
+package terminator
+
+import "sync"
+
+// lookup is a value fetched once on a check, like the stats of a node. Its mu
+// is held while it is fetched, so the pods needing it wait for a single fetch
+// while the ones needing other keys fetch theirs, and a failed fetch is kept
+// for the rest of the check instead of being retried by each pod.
+type lookup struct {
+	mu    sync.Mutex
+	done  bool
+	value interface{}
+	err   error
+}
+
+// lookups are the lookups of a check of one kind, by key.
+type lookups struct {
+	mu      sync.Mutex
+	entries map[string]*lookup
+}
+
+// put sets the value of key, fetched along with others.
+func (l *lookups) put(key string, value interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.entries == nil {
+		l.entries = make(map[string]*lookup)
+	}
+	l.entries[key] = &lookup{done: true, value: value}
+}
+
+// get returns the value of key, fetching it on the first call of the check.
+func (l *lookups) get(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	l.mu.Lock()
+	if l.entries == nil {
+		l.entries = make(map[string]*lookup)
+	}
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &lookup{}
+		l.entries[key] = entry
+	}
+	l.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if !entry.done {
+		entry.value, entry.err = fetch()
+		entry.done = true
+	}
+	return entry.value, entry.err
+}
@@ -0,0 +1,49 @@
+package terminator
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// TestLookups checks each key is fetched once on a check, failed fetches
+// included, while a slow key doesn't hold the others.
+func TestLookups(t *testing.T) {
+	var l lookups
+	var fetches int32
+	failing := errors.New("proxy error")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := l.get("bad-node", func() (interface{}, error) {
+				atomic.AddInt32(&fetches, 1)
+				return nil, failing
+			})
+			if err != failing {
+				t.Errorf("expected the error of the fetch, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetches != 1 {
+		t.Errorf("expected a single fetch of the failing node, got %d", fetches)
+	}
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go l.get("slow-node", func() (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	<-started
+	value, err := l.get("node", func() (interface{}, error) { return int64(1024), nil })
+	close(release)
+	if err != nil || value.(int64) != 1024 {
+		t.Errorf("expected 1024 while another node is being fetched, got %v, %v", value, err)
+	}
+}
@@ -0,0 +1,58 @@
+package terminator
+
+import (
+	"container/list"
+	"time"
+)
+
+// lru orders keys by when they were last used, so the per pod state is bounded
+// by evicting the least recently used ones over a capacity and expiring the
+// ones unused for too long, without going through all of them on each check.
+type lru struct {
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key string
+	at  time.Time
+}
+
+func newLRU() *lru {
+	return &lru{order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// touch marks key as used at now.
+func (l *lru) touch(key string, now time.Time) {
+	if element, ok := l.entries[key]; ok {
+		element.Value.(*lruEntry).at = now
+		l.order.MoveToFront(element)
+		return
+	}
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, at: now})
+}
+
+// evict removes and returns the least recently used keys over capacity, none
+// with a capacity of zero.
+func (l *lru) evict(capacity int) []string {
+	var evicted []string
+	for capacity > 0 && l.order.Len() > capacity {
+		evicted = append(evicted, l.remove(l.order.Back()))
+	}
+	return evicted
+}
+
+// expire removes and returns the keys last used before before.
+func (l *lru) expire(before time.Time) []string {
+	var expired []string
+	for back := l.order.Back(); back != nil && back.Value.(*lruEntry).at.Before(before); back = l.order.Back() {
+		expired = append(expired, l.remove(back))
+	}
+	return expired
+}
+
+func (l *lru) remove(element *list.Element) string {
+	key := l.order.Remove(element).(*lruEntry).key
+	delete(l.entries, key)
+	return key
+}
@@ -0,0 +1,65 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	MemoryMetricWorkingSet = "working_set"
+	MemoryMetricRSS        = "rss"
+	MemoryMetricUsage      = "usage"
+)
+
+func validMemoryMetric(metric string) error {
+	switch metric {
+	case "", MemoryMetricWorkingSet, MemoryMetricRSS, MemoryMetricUsage:
+		return nil
+	default:
+		return fmt.Errorf("invalid memory-metric %q", metric)
+	}
+}
+
+// kubeletUsage returns the rss or usage of the regular containers of pod read
+// from the kubelet of its node, or nil when it has no stats yet.
+func (t terminator) kubeletUsage(ctx context.Context, c *check, pod *v1.Pod) (*resource.Quantity, error) {
+	if pod.Spec.NodeName == "" {
+		return nil, nil
+	}
+
+	stats, err := t.nodeStats(ctx, c, pod.Spec.NodeName)
+	if err != nil {
+		return nil, err
+	}
+
+	podStats, ok := stats[pod.Namespace+"/"+pod.Name]
+	if !ok || len(podStats.Containers) == 0 {
+		t.log.Infof("Pod %s has no stats", pod.Name)
+		return nil, nil
+	}
+
+	containers := make(map[string]bool, len(pod.Spec.Containers))
+	for _, container := range pod.Spec.Containers {
+		containers[container.Name] = true
+	}
+
+	var usage int64
+	for _, container := range podStats.Containers {
+		if !containers[container.Name] || container.Memory == nil {
+			continue
+		}
+
+		bytes := container.Memory.UsageBytes
+		if t.options.MemoryMetric == MemoryMetricRSS {
+			bytes = container.Memory.RSSBytes
+		}
+		if bytes != nil {
+			usage += int64(*bytes)
+		}
+	}
+
+	return resource.NewQuantity(usage, resource.BinarySI), nil
+}
@@ -0,0 +1,161 @@
+package terminator
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podShape is what is kept of the spec of a pod listed by its metadata, to
+// compute its usage out of its limit without fetching it again: its node and
+// its containers with only their names and resources.
+func podShape(pod *v1.Pod) *v1.Pod {
+	shape := &v1.Pod{Spec: v1.PodSpec{NodeName: pod.Spec.NodeName}}
+	for _, container := range pod.Spec.Containers {
+		shape.Spec.Containers = append(shape.Spec.Containers, v1.Container{Name: container.Name, Resources: container.Resources})
+	}
+	return shape
+}
+
+// metadataPods lists the metadata of the pods of namespace matching selector
+// from the cache of watcher, as pods without spec nor status.
+func metadataPods(watcher *targetWatcher, namespace string, selector labels.Selector) (*v1.PodList, error) {
+	cached, err := watcher.metadata.Namespace(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := &v1.PodList{Items: make([]v1.Pod, len(cached))}
+	for i, pod := range cached {
+		pods.Items[i] = v1.Pod{ObjectMeta: pod.ObjectMeta}
+	}
+	return pods, nil
+}
+
+// shortlist returns the full pods of the ones listed by their metadata using
+// at least CandidatePercentage of their limit, fetched from the API server,
+// since only they can be over it. Pods are fetched once more on their first
+// check, to keep their shape. Pods without a limit or whose usage can't be
+// told are candidates too, and the Nodes of targets are kept.
+func (t terminator) shortlist(ctx context.Context, state *state, targets Targets, pods []v1.Pod) ([]v1.Pod, error) {
+	var mu sync.Mutex
+	shapes := make(map[types.UID]*v1.Pod, len(pods))
+	var candidates []v1.Pod
+	err := t.evaluate(ctx, pods, func(ctx context.Context, pod *v1.Pod) error {
+		mu.Lock()
+		shape, ok := state.shapes[pod.UID]
+		mu.Unlock()
+
+		var full *v1.Pod
+		if !ok {
+			var err error
+			full, err = t.fullPod(ctx, pod)
+			if err != nil || full == nil {
+				return err
+			}
+			shape = podShape(full)
+		}
+
+		mu.Lock()
+		shapes[pod.UID] = shape
+		mu.Unlock()
+		if !t.candidate(ctx, pod, shape) {
+			return nil
+		}
+
+		if full == nil {
+			var err error
+			full, err = t.fullPod(ctx, pod)
+			if err != nil || full == nil {
+				return err
+			}
+		}
+		mu.Lock()
+		candidates = append(candidates, *full)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	state.shapes = shapes
+	t.log.Infof("%d of %d pods are candidates", len(candidates), len(pods))
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Namespace != candidates[j].Namespace {
+			return candidates[i].Namespace < candidates[j].Namespace
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+	return t.onTargetNodes(ctx, targets, candidates)
+}
+
+// shortlistCached returns the pods using at least CandidatePercentage of their
+// limit, the cheap phase of a check with TwoPhase: only the metrics listed
+// ahead of it are read, so the policies, limit ranges, protections and owners
+// are only looked up for the pods that can be over the limit.
+func (t terminator) shortlistCached(ctx context.Context, pods []v1.Pod) ([]v1.Pod, error) {
+	shortlisted := make([]bool, len(pods))
+	index := make(map[*v1.Pod]int, len(pods))
+	for i := range pods {
+		index[&pods[i]] = i
+	}
+
+	var mu sync.Mutex
+	err := t.evaluate(ctx, pods, func(ctx context.Context, pod *v1.Pod) error {
+		if pod.Status.Phase != v1.PodRunning || !t.candidate(ctx, pod, pod) {
+			return nil
+		}
+		mu.Lock()
+		shortlisted[index[pod]] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]v1.Pod, 0, len(pods))
+	for i, pod := range pods {
+		if shortlisted[i] {
+			candidates = append(candidates, pod)
+		}
+	}
+	t.log.Infof("%d of %d pods are candidates", len(candidates), len(pods))
+	return candidates, nil
+}
+
+// candidate returns whether pod with shape uses at least CandidatePercentage
+// of its limit by the metrics provider.
+func (t terminator) candidate(ctx context.Context, pod *v1.Pod, shape *v1.Pod) bool {
+	shaped := &v1.Pod{ObjectMeta: pod.ObjectMeta, Spec: shape.Spec}
+	var limit int64
+	for _, container := range t.onlyContainers(shaped).Spec.Containers {
+		containerLimit := container.Resources.Limits.Memory()
+		if containerLimit.IsZero() {
+			return true
+		}
+		limit += containerLimit.Value()
+	}
+
+	usage, err := t.provider.PodUsage(ctx, t.onlyContainers(shaped))
+	if err != nil {
+		return true
+	}
+	return usage != nil && float64(usage.Value())/float64(limit)*100 >= float64(t.options.CandidatePercentage)
+}
+
+// fullPod fetches pod from the API server, nil when it was deleted.
+func (t terminator) fullPod(ctx context.Context, pod *v1.Pod) (*v1.Pod, error) {
+	full, err := t.clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return full, err
+}
@@ -0,0 +1,38 @@
+package terminator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	skipReasonNoLimit      = "no_limit"
+	skipReasonProtected    = "protected"
+	skipReasonStaleMetrics = "stale_metrics"
+)
+
+var skippedPods = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_skipped_pods_total",
+	Help: "Amount of times a pod was not evaluated, by reason",
+}, []string{"cluster", "reason"})
+
+var oomKills = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_oom_kills_total",
+	Help: "Amount of targeted containers that were OOMKilled",
+}, []string{"cluster", "namespace", "workload"})
+
+var policyConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "terminator_policy_conflicts_total",
+	Help: "Amount of times a pod matched policies with different thresholds",
+}, []string{"cluster", "namespace"})
+
+var degraded = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "terminator_degraded",
+	Help: "Whether the metrics of pods can't be fetched (1) or can (0), with Degrade",
+}, []string{"cluster"})
+
+var limitUtilization = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "terminator_limit_utilization_ratio",
+	Help:    "Memory usage of the watched pods out of their limit on each check",
+	Buckets: []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.85, 0.9, 0.95, 1, 1.1},
+}, []string{"cluster", "namespace"})
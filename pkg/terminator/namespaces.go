@@ -0,0 +1,119 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// budget are the kills of the checks sharing it, counted against MaxKills, and
+// the lock keeping them to one kill at a time.
+type budget struct {
+	mu    sync.Mutex
+	kills int
+}
+
+func (b *budget) spent() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.kills
+}
+
+// TerminateNamespaces checks the pods of each namespace of intervals, like
+// Terminate, in its own loop sleeping its interval between checks, so
+// latency-critical namespaces are checked often and batch ones seldom. They
+// share MaxKills, reserving each kill from it, and kill at most one pod per
+// check each. It stops all of them with the first one that fails.
+func (t terminator) TerminateNamespaces(ctx context.Context, targets Targets, intervals map[string]time.Duration, memoryLimit, killAfter int, killSleep time.Duration) error {
+	if len(intervals) == 0 {
+		return fmt.Errorf("no namespaces to check")
+	}
+	namespaces := make([]string, 0, len(intervals))
+	for namespace, interval := range intervals {
+		if namespace == "" || interval <= 0 {
+			return fmt.Errorf("invalid interval %s of namespace %q", interval, namespace)
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	shared := new(budget)
+	var storeMu sync.Mutex
+	errs := make(chan error, len(namespaces))
+	for _, namespace := range namespaces {
+		nt := t
+		if t.store != nil {
+			nt.store = namespaceStore{store: t.store, namespace: namespace, mu: &storeMu}
+		}
+		namespaceTargets := targets
+		namespaceTargets.Namespace = namespace
+
+		go func(namespace string, interval time.Duration) {
+			err := nt.terminate(ctx, namespaceTargets, memoryLimit, killAfter, interval, killSleep, shared)
+			if err != nil && ctx.Err() == nil {
+				err = fmt.Errorf("namespace %s: %w", namespace, err)
+				cancel()
+			}
+			errs <- err
+		}(namespace, intervals[namespace])
+	}
+
+	var first error
+	for range namespaces {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// namespaceStore keeps the counters of the pods of namespace in a StateStore
+// shared with other namespaces, replacing only its own ones on Save.
+type namespaceStore struct {
+	store     StateStore
+	namespace string
+	mu        *sync.Mutex
+}
+
+func (s namespaceStore) Load(ctx context.Context) (map[string]OverLimit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pods, err := s.store.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for key, over := range pods {
+		if over.Namespace != s.namespace {
+			delete(pods, key)
+		}
+	}
+	return pods, nil
+}
+
+func (s namespaceStore) Save(ctx context.Context, pods map[string]OverLimit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saved, err := s.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+	if saved == nil {
+		saved = make(map[string]OverLimit, len(pods))
+	}
+	for key, over := range saved {
+		if over.Namespace == s.namespace {
+			delete(saved, key)
+		}
+	}
+	for key, over := range pods {
+		saved[key] = over
+	}
+	return s.store.Save(ctx, saved)
+}
@@ -0,0 +1,79 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSOptions configures the NATS notifier.
+type NATSOptions struct {
+	URL string
+	// Subject prefixes the subjects of events, published to
+	// <Subject>.<type> like oomterminator.pod_killed
+	Subject string
+	// CredsFile is the credentials file of the user, empty connects without
+	// one
+	CredsFile string
+	// JetStream publishes to a stream capturing the subjects, waiting for it
+	// to persist each event
+	JetStream bool
+}
+
+// natsFlushTimeout bounds the flush of an event published to core NATS.
+const natsFlushTimeout = 5 * time.Second
+
+type natsNotifier struct {
+	conn    *nats.Conn
+	js      nats.JetStreamContext
+	subject string
+}
+
+// NewNATSNotifier returns a Notifier publishing events to NATS subjects, one
+// per type of event. Events published to core NATS are flushed to the server
+// before Notify returns.
+func NewNATSNotifier(options NATSOptions) (Notifier, error) {
+	opts := []nats.Option{nats.Name("oomterminator"), nats.MaxReconnects(-1)}
+	if options.CredsFile != "" {
+		opts = append(opts, nats.UserCredentials(options.CredsFile))
+	}
+
+	conn, err := nats.Connect(options.URL, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	notifier := natsNotifier{conn: conn, subject: options.Subject}
+	if options.JetStream {
+		notifier.js, err = conn.JetStream()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return notifier, nil
+}
+
+func (n natsNotifier) Notify(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := n.subject + "." + event.Type
+	if n.js != nil {
+		_, err := n.js.Publish(subject, data, nats.Context(ctx))
+		return err
+	}
+	if err := n.conn.Publish(subject, data); err != nil {
+		return err
+	}
+
+	// core NATS only buffers what is published, so events are flushed before
+	// the terminator can stop, like right after a kill on shutdown
+	flushCtx, cancel := context.WithTimeout(ctx, natsFlushTimeout)
+	defer cancel()
+	return n.conn.FlushWithContext(flushCtx)
+}
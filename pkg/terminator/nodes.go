@@ -0,0 +1,56 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// nodeFieldSelector selects the pods of the node of targets server-side, when
+// they are limited to a single node by name, like with one terminator per
+// node.
+func (t Targets) nodeFieldSelector() string {
+	if len(t.Nodes) != 1 || t.NodeSelector != "" {
+		return ""
+	}
+	return fields.OneTermEqualSelector("spec.nodeName", t.Nodes[0]).String()
+}
+
+// onTargetNodes keeps the pods scheduled on the Nodes of targets and on the
+// ones matching its NodeSelector, when they are set.
+func (t terminator) onTargetNodes(ctx context.Context, targets Targets, pods []v1.Pod) ([]v1.Pod, error) {
+	if len(targets.Nodes) == 0 && targets.NodeSelector == "" {
+		return pods, nil
+	}
+
+	nodes := make(map[string]bool, len(targets.Nodes))
+	for _, name := range targets.Nodes {
+		nodes[name] = true
+	}
+
+	if targets.NodeSelector != "" {
+		if _, err := labels.Parse(targets.NodeSelector); err != nil {
+			return nil, fmt.Errorf("invalid node-selector %q: %w", targets.NodeSelector, err)
+		}
+
+		selected, err := t.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{LabelSelector: targets.NodeSelector})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range selected.Items {
+			nodes[node.Name] = true
+		}
+	}
+
+	scoped := pods[:0]
+	for _, pod := range pods {
+		if nodes[pod.Spec.NodeName] {
+			scoped = append(scoped, pod)
+		}
+	}
+	return scoped, nil
+}
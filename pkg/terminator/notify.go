@@ -0,0 +1,121 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	eventOOMKilled    = "oom_killed"
+	eventKillPaused   = "kill_paused"
+	eventKillDeferred = "kill_deferred"
+	eventOverLimit    = "over_limit"
+	eventPodKilled    = "pod_killed"
+)
+
+// Event is something that happened to a pod worth notifying about.
+type Event struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Workload  string    `json:"workload,omitempty"`
+	Container string    `json:"container,omitempty"`
+	Message   string    `json:"message"`
+	// Cost is the cost of the workload, on kills when there is an
+	// OpenCostURL
+	Cost *WorkloadCost `json:"cost,omitempty"`
+	// Reason is the reason of the decision on the pod
+	Reason *Reason `json:"reason,omitempty"`
+	// History are the last kills of the workload and their usage, on
+	// repeat_offender events
+	History []KillRecord `json:"history,omitempty"`
+	// Suggestion is the memory limit suggested for the pods of the workload,
+	// on repeat_offender events
+	Suggestion *LimitSuggestion `json:"suggestion,omitempty"`
+	// Team is the tenant of the policy file owning the namespace, and
+	// Assignees its assignees
+	Team      string   `json:"team,omitempty"`
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a Notifier that POSTs events as JSON to url.
+func NewWebhookNotifier(url string) Notifier {
+	return webhookNotifier{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n webhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type multiNotifier []Notifier
+
+// NewMultiNotifier returns a Notifier sending events to all of notifiers,
+// failing with the first of their errors.
+func NewMultiNotifier(notifiers ...Notifier) Notifier {
+	return multiNotifier(notifiers)
+}
+
+func (n multiNotifier) Notify(ctx context.Context, event Event) error {
+	var first error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, event); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// notify sends event to the configured notifier, if any. Failing to notify is
+// logged but doesn't stop the terminator.
+func (t terminator) notify(ctx context.Context, event Event) {
+	if t.notifier == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = t.clock.Now()
+	}
+	event.Cluster = t.options.Cluster
+	if tenant := t.live.currentPolicies().tenant(event.Namespace); tenant != nil {
+		event.Team, event.Assignees = tenant.Name, tenant.Assignees
+	}
+
+	if err := t.notifier.Notify(ctx, event); err != nil {
+		t.log.Errorf("could not notify %s of pod %s: %s", event.Type, event.Pod, err)
+	}
+}
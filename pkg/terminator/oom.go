@@ -0,0 +1,99 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+const reasonOOMKilled = "OOMKilled"
+
+// oomKillHandler sends an Event to events whenever a container of a pod is
+// OOMKilled. When targeted is set only pods selected by the targets count.
+func (t terminator) oomKillHandler(targeted bool, events chan<- Event) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			oldPod, pod := old.(*v1.Pod), new.(*v1.Pod)
+			if targeted && !t.selectors.matches(pod.Namespace, labels.Set(pod.Labels)) {
+				return
+			}
+
+			for _, container := range oomKilledContainers(oldPod, pod) {
+				event := Event{
+					Type:      eventOOMKilled,
+					Namespace: pod.Namespace,
+					Pod:       pod.Name,
+					Workload:  workloadName(pod),
+					Container: container,
+				}
+
+				select {
+				case events <- event:
+				default:
+					t.log.Errorf("dropping OOMKilled event of pod %s, too many pending", pod.Name)
+				}
+			}
+		},
+	}
+}
+
+// oomKilledContainers returns the containers of pod that were OOMKilled since
+// old, restarted or not.
+func oomKilledContainers(old, pod *v1.Pod) []string {
+	restarts := make(map[string]int32, len(old.Status.ContainerStatuses))
+	terminated := make(map[string]bool, len(old.Status.ContainerStatuses))
+	for _, status := range old.Status.ContainerStatuses {
+		restarts[status.Name] = status.RestartCount
+		terminated[status.Name] = status.State.Terminated != nil
+	}
+
+	var containers []string
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.RestartCount > restarts[status.Name] && isOOMKilled(status.LastTerminationState) {
+			containers = append(containers, status.Name)
+		} else if !terminated[status.Name] && isOOMKilled(status.State) {
+			containers = append(containers, status.Name)
+		}
+	}
+
+	return containers
+}
+
+func isOOMKilled(state v1.ContainerState) bool {
+	return state.Terminated != nil && state.Terminated.Reason == reasonOOMKilled
+}
+
+// recordOOMKills records the pending OOMKilled events without blocking.
+func (t terminator) recordOOMKills(ctx context.Context, events <-chan Event, state *state) {
+	for {
+		select {
+		case event := <-events:
+			t.recordOOMKill(ctx, event, state)
+		default:
+			return
+		}
+	}
+}
+
+// recordOOMKill logs and notifies an OOMKilled container, correlating it with
+// the over limit state of its pod to tell whether the terminator was too slow.
+func (t terminator) recordOOMKill(ctx context.Context, event Event, state *state) {
+	oomKills.WithLabelValues(t.options.Cluster, event.Namespace, event.Workload).Inc()
+
+	event.Message = fmt.Sprintf("container %s of pod %s was OOMKilled without being over the limit", event.Container, event.Pod)
+	wasOver := false
+	for _, over := range state.podsToKill {
+		if over.namespace == event.Namespace && over.name == event.Pod {
+			event.Message = fmt.Sprintf("container %s of pod %s was OOMKilled after being over the limit for %d checks", event.Container, event.Pod, over.count+1)
+			wasOver = true
+			break
+		}
+	}
+	t.recordMissed(state, event.Namespace, event.Workload, wasOver)
+
+	t.out.Killf("%s", event.Message)
+	t.notify(ctx, event)
+}
@@ -0,0 +1,99 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// KillRequest is a kill decision sent to a Guard for review.
+type KillRequest struct {
+	Cluster     string            `json:"cluster,omitempty"`
+	Namespace   string            `json:"namespace"`
+	Pod         string            `json:"pod"`
+	Workload    string            `json:"workload"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Usage       int64             `json:"usage"`
+	Limit       int64             `json:"limit"`
+	Percentage  float64           `json:"percentage"`
+	OverCount   int               `json:"overCount"`
+	DryRun      bool              `json:"dryRun"`
+	GracePeriod *int64            `json:"gracePeriodSeconds,omitempty"`
+}
+
+// Verdict is the answer of a Guard to a KillRequest. Besides allowing or
+// denying the kill, it can turn it into a dry run or change its grace period.
+type Verdict struct {
+	Allow       bool   `json:"allow"`
+	Reason      string `json:"reason,omitempty"`
+	DryRun      bool   `json:"dryRun,omitempty"`
+	GracePeriod *int64 `json:"gracePeriodSeconds,omitempty"`
+}
+
+// Guard reviews every kill before it happens.
+type Guard interface {
+	Review(ctx context.Context, request KillRequest) (Verdict, error)
+}
+
+type opaGuard struct {
+	url      string
+	failOpen bool
+	client   *http.Client
+}
+
+// NewOPAGuard returns a Guard querying the OPA decision at url, like
+// http://localhost:8181/v1/data/terminator/decision, with the KillRequest as
+// input. The decision must be a Verdict, an undefined decision denies the
+// kill. When OPA can't be queried the kill is denied, unless failOpen.
+func NewOPAGuard(url string, failOpen bool) Guard {
+	return opaGuard{url: url, failOpen: failOpen, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (g opaGuard) Review(ctx context.Context, request KillRequest) (Verdict, error) {
+	verdict, err := g.query(ctx, request)
+	if err != nil {
+		if g.failOpen {
+			return Verdict{Allow: true, Reason: fmt.Sprintf("could not query OPA, failing open: %s", err)}, nil
+		}
+		return Verdict{}, err
+	}
+	return verdict, nil
+}
+
+func (g opaGuard) query(ctx context.Context, request KillRequest) (Verdict, error) {
+	body, err := json.Marshal(map[string]KillRequest{"input": request})
+	if err != nil {
+		return Verdict{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.url, bytes.NewReader(body))
+	if err != nil {
+		return Verdict{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return Verdict{}, fmt.Errorf("OPA returned status %d", resp.StatusCode)
+	}
+
+	var decision struct {
+		Result *Verdict `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&decision); err != nil {
+		return Verdict{}, fmt.Errorf("invalid OPA decision: %w", err)
+	}
+
+	if decision.Result == nil {
+		return Verdict{Reason: "undefined decision"}, nil
+	}
+	return *decision.Result, nil
+}
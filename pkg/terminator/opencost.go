@@ -0,0 +1,114 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// openCostWindow is how far back the cost of workloads is computed from.
+const openCostWindow = "7d"
+
+// minutesPerMonth scales the cost of a window to a month of 30 days.
+const minutesPerMonth = 30 * 24 * 60
+
+// WorkloadCost is the cost of a workload by OpenCost, projected to a month.
+type WorkloadCost struct {
+	Monthly float64 `json:"monthly"`
+	// MonthlyHeadroom is the cost of the memory requested by its pods but not
+	// used
+	MonthlyHeadroom float64 `json:"monthlyHeadroom"`
+}
+
+func (c WorkloadCost) String() string {
+	return fmt.Sprintf("$%.2f/month, $%.2f/month of it in memory headroom", c.Monthly, c.MonthlyHeadroom)
+}
+
+type openCost struct {
+	url    string
+	client *http.Client
+}
+
+func newOpenCost(address string) *openCost {
+	return &openCost{url: strings.TrimSuffix(address, "/"), client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// openCostAllocation is the part of an OpenCost allocation used.
+type openCostAllocation struct {
+	Minutes       float64 `json:"minutes"`
+	TotalCost     float64 `json:"totalCost"`
+	RAMCost       float64 `json:"ramCost"`
+	RAMEfficiency float64 `json:"ramEfficiency"`
+}
+
+// workloadCost returns the cost of workload, like deployment/api, in
+// namespace, or nil when OpenCost has no allocations for it.
+func (o *openCost) workloadCost(ctx context.Context, namespace, workload string) (*WorkloadCost, error) {
+	kind, name, _ := strings.Cut(workload, "/")
+	query := url.Values{
+		"window":     {openCostWindow},
+		"aggregate":  {"controller"},
+		"accumulate": {"true"},
+		"filter":     {fmt.Sprintf(`namespace:"%s"+controllerKind:"%s"+controllerName:"%s"`, namespace, kind, name)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.url+"/allocation/compute?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("opencost responded %s", resp.Status)
+	}
+
+	var body struct {
+		Data []map[string]*openCostAllocation `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	var cost *WorkloadCost
+	for _, allocations := range body.Data {
+		for key, allocation := range allocations {
+			if allocation == nil || allocation.Minutes <= 0 || strings.HasPrefix(key, "__") {
+				continue
+			}
+
+			if cost == nil {
+				cost = new(WorkloadCost)
+			}
+			scale := minutesPerMonth / allocation.Minutes
+			cost.Monthly += allocation.TotalCost * scale
+			if allocation.RAMEfficiency < 1 {
+				cost.MonthlyHeadroom += allocation.RAMCost * (1 - allocation.RAMEfficiency) * scale
+			}
+		}
+	}
+	return cost, nil
+}
+
+// workloadCost returns the cost of workload in namespace when there is an
+// OpenCostURL, or nil. Failing to get it is logged.
+func (t terminator) workloadCost(ctx context.Context, namespace, workload string) *WorkloadCost {
+	if t.opencost == nil {
+		return nil
+	}
+
+	cost, err := t.opencost.workloadCost(ctx, namespace, workload)
+	if err != nil {
+		t.log.Errorf("could not get the cost of %s: %s", workload, err)
+		return nil
+	}
+	return cost
+}
@@ -0,0 +1,115 @@
+package terminator
+
+import (
+	"log"
+
+	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/metadata"
+	"k8s.io/metrics/pkg/client/custom_metrics"
+	"k8s.io/metrics/pkg/client/external_metrics"
+)
+
+// Option replaces a dependency of the terminator.
+type Option func(*terminator)
+
+// WithMetricsProvider sets where the memory usage of pods comes from, default
+// is metrics-server.
+func WithMetricsProvider(provider MetricsProvider) Option {
+	return func(t *terminator) {
+		t.provider = provider
+		t.customProvider = true
+	}
+}
+
+// WithAction sets what is done to the pods that are killed, default is
+// deleting them.
+func WithAction(action Action) Option {
+	return func(t *terminator) {
+		t.action = action
+	}
+}
+
+// WithNotifier sets who is notified of relevant events, like OOMKilled pods.
+func WithNotifier(notifier Notifier) Option {
+	return func(t *terminator) {
+		t.notifier = notifier
+	}
+}
+
+// WithErrorReporter makes the terminator report its operational errors and
+// panics to reporter.
+func WithErrorReporter(reporter ErrorReporter) Option {
+	return func(t *terminator) {
+		t.reporter = reporter
+	}
+}
+
+// WithExporter sets who receives the samples and decisions of each check.
+func WithExporter(exporter Exporter) Option {
+	return func(t *terminator) {
+		t.exporter = exporter
+	}
+}
+
+// WithGuard sets who reviews every kill before it happens.
+func WithGuard(guard Guard) Option {
+	return func(t *terminator) {
+		t.guard = guard
+	}
+}
+
+// WithStateStore sets where the over limit counters are kept across restarts,
+// default is nowhere.
+func WithStateStore(store StateStore) Option {
+	return func(t *terminator) {
+		t.store = store
+	}
+}
+
+// WithGPUProvider sets where the GPU memory usage of pods comes from, default
+// is nowhere unless there is a GPU service.
+func WithGPUProvider(provider GPUProvider) Option {
+	return func(t *terminator) {
+		t.gpu = provider
+	}
+}
+
+// WithCustomMetricsClient sets the client of the custom metrics API, which New
+// builds from its config.
+func WithCustomMetricsClient(client custom_metrics.CustomMetricsClient) Option {
+	return func(t *terminator) {
+		t.customMetricsClient = client
+	}
+}
+
+// WithExternalMetricsClient sets the client of the external metrics API, which
+// New builds from its config.
+func WithExternalMetricsClient(client external_metrics.ExternalMetricsClient) Option {
+	return func(t *terminator) {
+		t.externalMetricsClient = client
+	}
+}
+
+// WithMetadataClient sets the client listing the metadata of pods with
+// MetadataOnly, which New builds from its config.
+func WithMetadataClient(client metadata.Interface) Option {
+	return func(t *terminator) {
+		t.metadata = client
+	}
+}
+
+// WithClock replaces the real clock, for tests and simulations.
+func WithClock(clock Clock) Option {
+	return func(t *terminator) {
+		t.clock = clock
+	}
+}
+
+// WithLogger sets where debug logs and what is always printed go, default are
+// the standard loggers of logrus and log.
+func WithLogger(debug *logrus.Entry, out *log.Logger) Option {
+	return func(t *terminator) {
+		t.log = debug
+		t.out.logger = out
+	}
+}
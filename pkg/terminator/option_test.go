@@ -0,0 +1,154 @@
+package terminator
+
+import (
+	"context"
+	"io"
+	"log"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// testClock is a Clock whose time only moves when it is slept on.
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	after := make(chan time.Time, 1)
+	after <- c.now
+	return after
+}
+
+// testProvider is a MetricsProvider with a fixed usage by pod name, pods
+// without one having no metrics yet.
+type testProvider map[string]string
+
+func (p testProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	usage, ok := p[pod.Name]
+	if !ok {
+		return nil, nil
+	}
+	quantity := resource.MustParse(usage)
+	return &quantity, nil
+}
+
+// testAction keeps the pods killed instead of deleting them.
+type testAction struct {
+	mu     sync.Mutex
+	killed []string
+}
+
+func (a *testAction) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.killed = append(a.killed, pod.Name)
+	return nil
+}
+
+// testNotifier keeps the types of the events notified.
+type testNotifier struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (n *testNotifier) Notify(ctx context.Context, event Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event.Type)
+	return nil
+}
+
+func optionTestPod(name string) runtime.Object {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "web", UID: types.UID(name)},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:      "app",
+			Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+// TestOptions checks checks driven by a fake clock and metrics provider kill
+// through the action and notify the notifier given as options, never reaching
+// metrics-server nor deleting pods.
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		usage     testProvider
+		killAfter int
+		checks    int
+		kills     int
+		events    []string
+	}{
+		{"under the limit", testProvider{"api": "512Mi"}, 0, 2, 0, nil},
+		{"without metrics", testProvider{}, 0, 2, 0, nil},
+		{"over the limit", testProvider{"api": "1000Mi"}, 0, 1, 1, []string{eventOverLimit, eventPodKilled}},
+		{"before kill-after", testProvider{"api": "1000Mi"}, 2, 2, 0, []string{eventOverLimit}},
+		{"after kill-after", testProvider{"api": "1000Mi"}, 2, 3, 1, []string{eventOverLimit, eventPodKilled}},
+		// the pods left once a pod is killed are not evaluated on the check
+		{"one kill per check", testProvider{"api": "1000Mi", "worker": "1000Mi"}, 0, 1, 1, []string{eventOverLimit, eventPodKilled}},
+	}
+	for _, test := range tests {
+		clock := &testClock{now: time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)}
+		action := &testAction{}
+		notifier := &testNotifier{}
+		clientset := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}}, optionTestPod("api"), optionTestPod("worker"))
+
+		debug := logrus.New()
+		debug.SetOutput(io.Discard)
+		created, err := NewForClients(clientset, metricsfake.NewSimpleClientset(), Options{Workers: 1, Quiet: true, IncludeBarePods: true},
+			WithClock(clock), WithMetricsProvider(test.usage), WithAction(action), WithNotifier(notifier), WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		terminator := created.(terminator)
+		watcher, err := terminator.watchTargets(context.Background(), Targets{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		state := newState()
+		for i := 0; i < test.checks; i++ {
+			if err := terminator.runCheck(context.Background(), watcher, Targets{}, state, terminator.defaultPolicy(95, test.killAfter), time.Minute); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if len(action.killed) != test.kills {
+			t.Errorf("%s: expected %d kills, got %v", test.name, test.kills, action.killed)
+		}
+		if !reflect.DeepEqual(notifier.events, test.events) {
+			t.Errorf("%s: expected the events %v, got %v", test.name, test.events, notifier.events)
+		}
+		if test.kills > 0 && !clock.Now().After(time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)) {
+			t.Errorf("%s: expected the kill sleep to go through the clock", test.name)
+		}
+		for _, action := range clientset.Actions() {
+			if action.Matches("delete", "pods") {
+				t.Errorf("%s: expected the pods to be killed by the action, got a delete of the clientset", test.name)
+			}
+		}
+	}
+}
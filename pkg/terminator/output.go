@@ -0,0 +1,58 @@
+package terminator
+
+import (
+	"fmt"
+	"log"
+)
+
+const (
+	colorRed    = "\x1b[31m"
+	colorYellow = "\x1b[33m"
+	colorReset  = "\x1b[0m"
+)
+
+// output is what the terminator prints, as opposed to its debug logs. With
+// Quiet only the kills and errors are printed, and with Color they are red and
+// the pods over the limit yellow.
+type output struct {
+	logger *log.Logger
+	quiet  bool
+	color  bool
+}
+
+func (o output) Printf(format string, v ...interface{}) {
+	if !o.quiet {
+		o.logger.Printf(format, v...)
+	}
+}
+
+func (o output) Print(v ...interface{}) {
+	if !o.quiet {
+		o.logger.Print(v...)
+	}
+}
+
+// Warnf prints what needs attention but is not a kill, like a pod over the
+// limit.
+func (o output) Warnf(format string, v ...interface{}) {
+	if !o.quiet {
+		o.logger.Print(o.colored(colorYellow, fmt.Sprintf(format, v...)))
+	}
+}
+
+// Killf prints a kill, even when quiet.
+func (o output) Killf(format string, v ...interface{}) {
+	o.logger.Print(o.colored(colorRed, fmt.Sprintf(format, v...)))
+}
+
+// Errorf prints an error, even when quiet.
+func (o output) Errorf(format string, v ...interface{}) {
+	o.logger.Print(o.colored(colorRed, fmt.Sprintf(format, v...)))
+}
+
+func (o output) colored(color, message string) string {
+	if !o.color {
+		return message
+	}
+	return color + message + colorReset
+}
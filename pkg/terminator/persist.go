@@ -0,0 +1,141 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// OverLimit is a pod over the limit, as persisted by a StateStore.
+type OverLimit struct {
+	UID       types.UID `json:"uid"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+}
+
+// StateStore keeps the over limit counters across restarts, so a restart
+// doesn't delay kills by another kill-after.
+type StateStore interface {
+	// Load returns the saved counters by pod uid, or nothing when none were
+	// saved
+	Load(ctx context.Context) (map[string]OverLimit, error)
+	Save(ctx context.Context, pods map[string]OverLimit) error
+}
+
+type configMapStore struct {
+	clientset kubernetes.Interface
+	namespace string
+	name      string
+	key       string
+}
+
+// NewConfigMapStore returns a StateStore saving the counters as JSON at key of
+// the ConfigMap namespace/name, which is created when missing. Several
+// terminators can share the ConfigMap with different keys.
+func NewConfigMapStore(clientset kubernetes.Interface, namespace, name, key string) StateStore {
+	return configMapStore{clientset: clientset, namespace: namespace, name: name, key: key}
+}
+
+func (s configMapStore) Load(ctx context.Context) (map[string]OverLimit, error) {
+	configMap, err := s.clientset.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data, ok := configMap.Data[s.key]
+	if !ok {
+		return nil, nil
+	}
+
+	var pods map[string]OverLimit
+	if err := json.Unmarshal([]byte(data), &pods); err != nil {
+		return nil, err
+	}
+	return pods, nil
+}
+
+func (s configMapStore) Save(ctx context.Context, pods map[string]OverLimit) error {
+	data, err := json.Marshal(pods)
+	if err != nil {
+		return err
+	}
+
+	configMaps := s.clientset.CoreV1().ConfigMaps(s.namespace)
+	configMap, err := configMaps.Get(ctx, s.name, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+			Data:       map[string]string{s.key: string(data)},
+		}
+		_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	configMap.Data[s.key] = string(data)
+	_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// restoreState loads the counters saved by the state store into state.
+func (t terminator) restoreState(ctx context.Context, state *state) error {
+	if t.store == nil {
+		return nil
+	}
+
+	pods, err := t.store.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	for key, over := range pods {
+		// counters saved by older versions are keyed by the pod name
+		if over.Name == "" {
+			over.Name = key
+		}
+		state.podsToKill[over.UID] = &overLimit{namespace: over.Namespace, name: over.Name, at: over.FirstSeen, count: over.Count}
+	}
+	state.saved = pods
+	t.log.Infof("restored %d pods over the limit", len(pods))
+	return nil
+}
+
+// persistState saves the counters of state to the state store, when they
+// changed since they were last saved. Failing to save is logged, and retried
+// after the next check.
+func (t terminator) persistState(ctx context.Context, state *state) {
+	if t.store == nil {
+		return
+	}
+
+	pods := make(map[string]OverLimit, len(state.podsToKill))
+	for uid, over := range state.podsToKill {
+		pods[string(uid)] = OverLimit{UID: uid, Namespace: over.namespace, Name: over.name, Count: over.count, FirstSeen: over.at}
+	}
+	if reflect.DeepEqual(pods, state.saved) {
+		return
+	}
+
+	if err := t.store.Save(ctx, pods); err != nil {
+		t.log.Errorf("could not save the pods over the limit: %s", err)
+		return
+	}
+	state.saved = pods
+}
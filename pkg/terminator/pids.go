@@ -0,0 +1,38 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// podPids returns the amount of processes of pod and its pids limit, read from
+// the kubelet of its node. The limit is PodPidsLimit when the kubelet has none,
+// and zero when there is no limit.
+func (t terminator) podPids(ctx context.Context, c *check, pod *v1.Pod) (count, limit int64, err error) {
+	if pod.Spec.NodeName == "" {
+		return 0, 0, nil
+	}
+
+	stats, err := t.nodeStats(ctx, c, pod.Spec.NodeName)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	podStats, ok := stats[pod.Namespace+"/"+pod.Name]
+	if !ok || podStats.ProcessStats == nil || podStats.ProcessStats.ProcessCount == nil {
+		return 0, 0, nil
+	}
+
+	limit, err = t.nodePidsLimit(ctx, c, pod.Spec.NodeName)
+	if err != nil {
+		// configz may not be allowed, the fallback limit still applies
+		t.log.Infof("could not get the pids limit of node %s: %s", pod.Spec.NodeName, err)
+		limit = 0
+	}
+	if limit == 0 {
+		limit = t.options.PodPidsLimit
+	}
+
+	return int64(*podStats.ProcessStats.ProcessCount), limit, nil
+}
@@ -0,0 +1,135 @@
+package terminator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Plan are the pods a dry run would have killed, written by the terminators
+// with a PlanFile, to be reviewed before enforcing the kills.
+type Plan struct {
+	Started time.Time     `json:"started"`
+	Updated time.Time     `json:"updated"`
+	Kills   []PlannedKill `json:"kills"`
+}
+
+// PlannedKill is a pod a dry run would have killed, when and why. A pod
+// planned again, since dry runs don't kill it, is counted on its first kill,
+// with the Time, Cause and Reason of its last one.
+type PlannedKill struct {
+	Time      time.Time `json:"time"`
+	FirstTime time.Time `json:"firstTime"`
+	Count     int       `json:"count"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Workload  string    `json:"workload,omitempty"`
+	Cause     string    `json:"cause"`
+	Reason    *Reason   `json:"reason"`
+}
+
+// key identifies the pod of kill across the clusters of a plan.
+func (kill PlannedKill) key() string {
+	return kill.Cluster + "/" + kill.Namespace + "/" + kill.Pod
+}
+
+// planFile is the plan written to a path, shared by the terminators of the
+// clusters writing to it.
+type planFile struct {
+	mu   sync.Mutex
+	path string
+	plan Plan
+	// pods are the indexes of the kills of the plan by their key
+	pods map[string]int
+}
+
+var plans = struct {
+	sync.Mutex
+	files map[string]*planFile
+}{files: make(map[string]*planFile)}
+
+// openPlan returns the plan written to path, started at now by the first
+// terminator writing to it unless path already has one, which goes on so a
+// restart doesn't lose the kills planned before it.
+func openPlan(path string, now time.Time) (*planFile, error) {
+	plans.Lock()
+	defer plans.Unlock()
+
+	if file, ok := plans.files[path]; ok {
+		return file, nil
+	}
+
+	file := &planFile{path: path, plan: Plan{Started: now, Updated: now, Kills: []PlannedKill{}}, pods: make(map[string]int)}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &file.plan); err != nil {
+			return nil, fmt.Errorf("invalid plan file %s: %w", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	for i, kill := range file.plan.Kills {
+		// plans written before kills were merged count each of them once
+		if kill.Count == 0 {
+			file.plan.Kills[i].FirstTime, file.plan.Kills[i].Count = kill.Time, 1
+		}
+		file.pods[kill.key()] = i
+	}
+	plans.files[path] = file
+	return file, nil
+}
+
+// add appends kill to the plan, or counts it on the kill of the same pod, and
+// rewrites its file, replacing it at once so a run stopped at any time leaves
+// a valid plan. Merging the kills of a pod keeps the plan of a long dry run to
+// a kill per pod instead of growing on each of them.
+func (f *planFile) add(kill PlannedKill) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if i, ok := f.pods[kill.key()]; ok {
+		planned := &f.plan.Kills[i]
+		planned.Time, planned.Cause, planned.Reason, planned.Workload = kill.Time, kill.Cause, kill.Reason, kill.Workload
+		planned.Count++
+	} else {
+		kill.FirstTime, kill.Count = kill.Time, 1
+		f.pods[kill.key()] = len(f.plan.Kills)
+		f.plan.Kills = append(f.plan.Kills, kill)
+	}
+	f.plan.Updated = kill.Time
+	data, err := json.MarshalIndent(f.plan, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// planKill adds the kill of pod a dry run skipped to the plan, when there is
+// a PlanFile. Failing to write it is logged.
+func (t terminator) planKill(kill PlannedKill) {
+	if t.plan == nil {
+		return
+	}
+
+	kill.Cluster = t.options.Cluster
+	if err := t.plan.add(kill); err != nil {
+		t.log.Errorf("could not write the plan %s: %s", t.options.PlanFile, err)
+	}
+}
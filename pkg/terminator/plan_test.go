@@ -0,0 +1,57 @@
+package terminator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPlanMerge checks the kills planned again for a pod are counted on its
+// first one instead of listed again, also after the plan is opened again.
+func TestPlanMerge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	started := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	file, err := openPlan(path, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, pod := range []string{"api", "worker", "api", "api"} {
+		if err := file.add(PlannedKill{Time: started.Add(time.Duration(i) * time.Hour), Namespace: "web", Pod: pod, Cause: "over the limit"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var plan Plan
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Kills) != 2 {
+		t.Fatalf("expected a kill per pod, got %+v", plan.Kills)
+	}
+	api := plan.Kills[0]
+	if api.Pod != "api" || api.Count != 3 || !api.FirstTime.Equal(started) || !api.Time.Equal(started.Add(3*time.Hour)) {
+		t.Errorf("expected api planned 3 times from %s to %s, got %+v", started, started.Add(3*time.Hour), api)
+	}
+
+	// a restart goes on counting the kills of the plan
+	plans.Lock()
+	delete(plans.files, path)
+	plans.Unlock()
+	file, err = openPlan(path, started.Add(4*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.add(PlannedKill{Time: started.Add(5 * time.Hour), Namespace: "web", Pod: "worker"}); err != nil {
+		t.Fatal(err)
+	}
+	if worker := file.plan.Kills[1]; len(file.plan.Kills) != 2 || worker.Count != 2 {
+		t.Errorf("expected worker planned twice, got %+v", file.plan.Kills)
+	}
+}
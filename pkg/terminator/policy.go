@@ -0,0 +1,422 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyFile holds the default policies of namespaces, so tiered thresholds
+// can be kept in one place instead of flags.
+type PolicyFile struct {
+	Namespaces []NamespacePolicy `json:"namespaces,omitempty"`
+	// Tenants own some namespaces, and their policies only select pods of
+	// those, see Tenant
+	Tenants []Tenant `json:"tenants,omitempty"`
+
+	// path and revision of the file, and whether it holds the built-in
+	// policies, for the provenance of the decisions
+	path     string
+	revision string
+	system   bool
+}
+
+// NamespacePolicy is the default policy of the namespace called Name, or of the
+// namespaces whose labels match Selector, with overrides for some of its
+// workloads, keyed like deployment/api.
+type NamespacePolicy struct {
+	Name     string `json:"name,omitempty"`
+	Selector string `json:"selector,omitempty"`
+	Policy
+	Workloads map[string]Policy `json:"workloads,omitempty"`
+
+	selector labels.Selector
+	// tenant owning the policy, which only applies to the owned namespaces
+	tenant string
+	owned  map[string]bool
+}
+
+// Policy sets the thresholds of pods, unset fields are inherited from the less
+// specific policy.
+type Policy struct {
+	// Limit is the memory usage percentage limit
+	Limit *int `json:"limit,omitempty"`
+	// KillAfter is the amount of checks a pod needs to be over the limit
+	KillAfter *int `json:"killAfter,omitempty"`
+	// Cooldown is the minimum time between kills of pods of the same workload
+	Cooldown *metav1.Duration `json:"cooldown,omitempty"`
+	// Condition is a CEL expression deciding whether a pod is killed, see
+	// Condition
+	Condition string `json:"condition,omitempty"`
+	// Rule combines thresholds on several resources, replacing Limit, see
+	// Rule
+	Rule *Rule `json:"rule,omitempty"`
+	// Query is a PromQL expression over the pod, deciding whether it is over
+	// the limit too, see Options.Query
+	Query string `json:"query,omitempty"`
+	// Protected pods are never killed
+	Protected *bool `json:"protected,omitempty"`
+
+	condition *Condition
+}
+
+// policy is the effective policy of a pod.
+type policy struct {
+	limit     int
+	killAfter int
+	cooldown  time.Duration
+	condition *Condition
+	rule      *Rule
+	query     string
+	protected bool
+
+	provenance provenance
+}
+
+func (p policy) with(override Policy) policy {
+	if override.Limit != nil {
+		p.limit = *override.Limit
+	}
+	if override.KillAfter != nil {
+		p.killAfter = *override.KillAfter
+	}
+	if override.Cooldown != nil {
+		p.cooldown = override.Cooldown.Duration
+	}
+	if override.condition != nil {
+		p.condition = override.condition
+	}
+	if override.Rule != nil {
+		p.rule = override.Rule
+	}
+	if override.Query != "" {
+		p.query = override.Query
+	}
+	if override.Protected != nil {
+		p.protected = *override.Protected
+	}
+	return p
+}
+
+func (p *Policy) compile() error {
+	if p.Limit != nil && *p.Limit < 1 {
+		return fmt.Errorf("limit must be at least 1, got %d", *p.Limit)
+	}
+	if p.KillAfter != nil && *p.KillAfter < 0 {
+		return fmt.Errorf("killAfter can't be negative, got %d", *p.KillAfter)
+	}
+	if p.Cooldown != nil && p.Cooldown.Duration < 0 {
+		return fmt.Errorf("cooldown can't be negative, got %s", p.Cooldown.Duration)
+	}
+
+	if p.Rule != nil {
+		if err := p.Rule.validate(); err != nil {
+			return err
+		}
+	}
+
+	if p.Condition == "" {
+		return nil
+	}
+
+	var err error
+	p.condition, err = CompileCondition(p.Condition)
+	return err
+}
+
+func LoadPolicyFile(path string) (*PolicyFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file := new(PolicyFile)
+	if err := yaml.UnmarshalStrict(data, file); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	file.path, file.revision = path, revision(data)
+	if err := file.addTenants(path); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(file.Namespaces))
+	for i := range file.Namespaces {
+		namespace := &file.Namespaces[i]
+		if (namespace.Name == "") == (namespace.Selector == "") {
+			return nil, fmt.Errorf("invalid policy file %s: namespace policy %d needs either a name or a selector", path, i)
+		}
+		if names[namespace.Name] {
+			return nil, fmt.Errorf("invalid policy file %s: namespace %s is repeated", path, namespace.Name)
+		}
+		if namespace.Name != "" {
+			names[namespace.Name] = true
+		}
+
+		if namespace.Selector != "" {
+			namespace.selector, err = labels.Parse(namespace.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy file %s: invalid selector %q: %w", path, namespace.Selector, err)
+			}
+		}
+
+		if err := namespace.Policy.compile(); err != nil {
+			return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+		}
+		for workload, override := range namespace.Workloads {
+			if err := override.compile(); err != nil {
+				return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+			}
+			namespace.Workloads[workload] = override
+		}
+	}
+
+	if err := file.validateSelectors(); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// validateSelectors checks no selector of file can select a system or protected
+// namespace, which only their policies by name should change, and that no two
+// equally specific selectors that can select the same namespace set different
+// thresholds, as the one applied would only depend on their order in the file.
+// Namespaces are told apart by their kubernetes.io/metadata.name label, as the
+// rest of their labels are only known on each check.
+func (file *PolicyFile) validateSelectors() error {
+	protected := append([]string(nil), systemNamespaces...)
+	for _, namespacePolicy := range file.Namespaces {
+		if namespacePolicy.Name != "" && namespacePolicy.Protected != nil && *namespacePolicy.Protected {
+			protected = append(protected, namespacePolicy.Name)
+		}
+	}
+
+	for i := range file.Namespaces {
+		a := &file.Namespaces[i]
+		if a.selector == nil {
+			continue
+		}
+		for _, name := range protected {
+			if a.applies(name) && a.selector.Matches(labels.Set{v1.LabelMetadataName: name}) {
+				return fmt.Errorf("selector %q can select the protected namespace %s, set its policy by name instead", a.Selector, name)
+			}
+		}
+
+		for j := i + 1; j < len(file.Namespaces); j++ {
+			b := &file.Namespaces[j]
+			if b.selector == nil || specificity(a) != specificity(b) || !overlapping(a, b) {
+				continue
+			}
+			if workload, ok := conflictingWorkload(a, b); ok {
+				if workload != "" {
+					return fmt.Errorf("selectors %q and %q can select the same namespaces with different thresholds for %s", a.Selector, b.Selector, workload)
+				}
+				return fmt.Errorf("selectors %q and %q can select the same namespaces with different thresholds", a.Selector, b.Selector)
+			}
+		}
+	}
+	return nil
+}
+
+// overlapping tells whether a namespace can be selected by both a and b, unless
+// they are the policies of different tenants or a label they require can't
+// have a value matching both.
+func overlapping(a, b *NamespacePolicy) bool {
+	if a.owned != nil && b.owned != nil && a.tenant != b.tenant {
+		return false
+	}
+
+	requirementsA, _ := a.selector.Requirements()
+	requirementsB, _ := b.selector.Requirements()
+	for _, x := range requirementsA {
+		for _, y := range requirementsB {
+			if x.Key() == y.Key() && (excludes(x, y) || excludes(y, x)) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// excludes tells whether no value of a label can match both x and y.
+func excludes(x, y labels.Requirement) bool {
+	switch x.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		switch y.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			return !x.Values().HasAny(y.Values().List()...)
+		case selection.NotEquals, selection.NotIn:
+			return y.Values().IsSuperset(x.Values())
+		case selection.DoesNotExist:
+			return true
+		}
+	case selection.Exists:
+		return y.Operator() == selection.DoesNotExist
+	}
+	return false
+}
+
+// conflictingWorkload returns the workload a and b set different thresholds
+// for, empty when it is the one of the namespace, and whether there is one.
+func conflictingWorkload(a, b *NamespacePolicy) (string, bool) {
+	if conflicting(a, b, "") {
+		return "", true
+	}
+	workloads := make([]string, 0, len(a.Workloads)+len(b.Workloads))
+	for workload := range a.Workloads {
+		workloads = append(workloads, workload)
+	}
+	for workload := range b.Workloads {
+		workloads = append(workloads, workload)
+	}
+	sort.Strings(workloads)
+	for _, workload := range workloads {
+		if conflicting(a, b, workload) {
+			return workload, true
+		}
+	}
+	return "", false
+}
+
+// systemNamespaces are protected unless system namespaces are allowed.
+var systemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// SystemPolicies returns the policies protecting the system namespaces. They
+// are applied below the policy file, which can still unprotect some of them or
+// their workloads.
+func SystemPolicies() *PolicyFile {
+	protected := true
+	file := &PolicyFile{system: true}
+	for _, name := range systemNamespaces {
+		file.Namespaces = append(file.Namespaces, NamespacePolicy{Name: name, Policy: Policy{Protected: &protected}})
+	}
+	return file
+}
+
+// policyFor returns the effective policy of pod. The defaults of the check are
+// overridden by the system policies and then by the policy file.
+func (t terminator) policyFor(ctx context.Context, c *check, pod *v1.Pod) (policy, error) {
+	effective := c.defaults
+	for _, file := range []*PolicyFile{t.options.SystemPolicies, c.policies} {
+		if file == nil {
+			continue
+		}
+
+		var err error
+		effective, err = t.applyPolicies(ctx, c, file, pod, effective)
+		if err != nil {
+			return effective, err
+		}
+	}
+
+	return effective, nil
+}
+
+// applyPolicies overrides effective by the most specific namespace policy of
+// file matching pod by selector, then by the one matching by name, and then by
+// the override of its workload.
+func (t terminator) applyPolicies(ctx context.Context, c *check, file *PolicyFile, pod *v1.Pod, effective policy) (policy, error) {
+	var byName *NamespacePolicy
+	var matching []*NamespacePolicy
+	for i := range file.Namespaces {
+		namespacePolicy := &file.Namespaces[i]
+		if !namespacePolicy.applies(pod.Namespace) {
+			continue
+		}
+		if namespacePolicy.Name == pod.Namespace {
+			byName = namespacePolicy
+		}
+
+		if namespacePolicy.selector != nil {
+			namespace, err := t.namespace(ctx, c, pod.Namespace)
+			if err != nil {
+				return effective, err
+			}
+			if namespacePolicy.selector.Matches(labels.Set(namespace.Labels)) {
+				matching = append(matching, namespacePolicy)
+			}
+		}
+	}
+
+	workload := workloadName(pod)
+	bySelector := t.mostSpecific(pod.Namespace, workload, matching)
+	for _, namespacePolicy := range []*NamespacePolicy{bySelector, byName} {
+		if namespacePolicy != nil {
+			effective = effective.from(namespacePolicy.Policy, file.source(namespacePolicy, ""))
+		}
+	}
+	for _, namespacePolicy := range []*NamespacePolicy{bySelector, byName} {
+		if namespacePolicy == nil {
+			continue
+		}
+		if override, ok := namespacePolicy.Workloads[workload]; ok {
+			effective = effective.from(override, file.source(namespacePolicy, workload))
+		}
+	}
+
+	return effective, nil
+}
+
+// mostSpecific returns the policy of policies whose selector has the most
+// requirements, the first one of the file on a tie, which only happens with the
+// same thresholds. Policies setting different thresholds for the workload than
+// the chosen one are logged and counted as conflicts.
+func (t terminator) mostSpecific(namespace, workload string, policies []*NamespacePolicy) *NamespacePolicy {
+	if len(policies) == 0 {
+		return nil
+	}
+
+	chosen := policies[0]
+	for _, namespacePolicy := range policies[1:] {
+		if specificity(namespacePolicy) > specificity(chosen) {
+			chosen = namespacePolicy
+		}
+	}
+
+	for _, namespacePolicy := range policies {
+		if namespacePolicy != chosen && conflicting(namespacePolicy, chosen, workload) {
+			t.log.Warnf("namespace %s matches the policies of selectors %q and %q, using %q", namespace, namespacePolicy.Selector, chosen.Selector, chosen.Selector)
+			policyConflicts.WithLabelValues(t.options.Cluster, namespace).Inc()
+		}
+	}
+	return chosen
+}
+
+func specificity(namespacePolicy *NamespacePolicy) int {
+	requirements, _ := namespacePolicy.selector.Requirements()
+	return len(requirements)
+}
+
+// conflicting tells whether a and b set different thresholds for workload.
+func conflicting(a, b *NamespacePolicy, workload string) bool {
+	thresholds := func(p Policy) Policy {
+		p.condition = nil
+		return p
+	}
+	return !reflect.DeepEqual(thresholds(a.Policy), thresholds(b.Policy)) ||
+		!reflect.DeepEqual(thresholds(a.Workloads[workload]), thresholds(b.Workloads[workload]))
+}
+
+// namespace returns the namespace called name. Namespaces are fetched once on
+// each check, except in ScopeNamespace, where they have no labels nor
+// annotations.
+func (t terminator) namespace(ctx context.Context, c *check, name string) (*v1.Namespace, error) {
+	namespace, err := c.namespaces.get(name, func() (interface{}, error) {
+		if t.options.Scope == ScopeNamespace {
+			return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name}}, nil
+		}
+		return t.clientset.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return namespace.(*v1.Namespace), nil
+}
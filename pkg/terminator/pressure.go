@@ -0,0 +1,73 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// NodePressurePrioritize kills the pods over the limit on nodes under
+	// memory pressure first, without waiting for kill-after, before the
+	// kubelet evicts some other pod
+	NodePressurePrioritize = "prioritize"
+	// NodePressurePause doesn't kill pods while nodes are under memory
+	// pressure, leaving it to the kubelet evictions, since their replacements
+	// could be scheduled onto them
+	NodePressurePause = "pause"
+)
+
+func validNodePressure(mode string) error {
+	switch mode {
+	case "", NodePressurePrioritize, NodePressurePause:
+		return nil
+	}
+	return fmt.Errorf("invalid node-pressure %q, must be prioritize or pause", mode)
+}
+
+// pressuredNodes returns the nodes under memory pressure when there is a
+// NodePressure mode, keeping the allocatable memory of every node for the
+// check too.
+func (t terminator) pressuredNodes(ctx context.Context, c *check) (map[string]bool, error) {
+	if t.options.NodePressure == "" {
+		return nil, nil
+	}
+
+	nodes, err := t.clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	pressured := make(map[string]bool)
+	for i := range nodes.Items {
+		node := &nodes.Items[i]
+		c.nodeAllocatable.put(node.Name, node.Status.Allocatable.Memory())
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == v1.NodeMemoryPressure && condition.Status == v1.ConditionTrue {
+				pressured[node.Name] = true
+			}
+		}
+	}
+
+	if len(pressured) > 0 {
+		names := make([]string, 0, len(pressured))
+		for name := range pressured {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.out.Printf("Nodes under memory pressure: %s", strings.Join(names, ", "))
+	}
+	return pressured, nil
+}
+
+// pressuredFirst moves the pods on nodes under memory pressure to the start of
+// pods, so they are evaluated first.
+func pressuredFirst(pods []v1.Pod, pressured map[string]bool) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		return pressured[pods[i].Spec.NodeName] && !pressured[pods[j].Spec.NodeName]
+	})
+}
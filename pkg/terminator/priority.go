@@ -0,0 +1,24 @@
+package terminator
+
+import (
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// priority returns the priority of pod set from its PriorityClass at admission,
+// zero without one like the default of Kubernetes.
+func priority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority == nil {
+		return 0
+	}
+	return *pod.Spec.Priority
+}
+
+// lowerPriorityFirst sorts pods by their priority, so pods of lower priority
+// are evaluated and killed before the ones of higher priority.
+func lowerPriorityFirst(pods []v1.Pod) {
+	sort.SliceStable(pods, func(i, j int) bool {
+		return priority(&pods[i]) < priority(&pods[j])
+	})
+}
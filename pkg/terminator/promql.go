@@ -0,0 +1,52 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	v1 "k8s.io/api/core/v1"
+)
+
+func newPrometheusAPI(address string) (promv1.API, error) {
+	client, err := api.NewClient(api.Config{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("invalid prometheus-url %q: %w", address, err)
+	}
+	return promv1.NewAPI(client), nil
+}
+
+// queryMatches evaluates the PromQL query of a policy for pod, with $pod and
+// $namespace replaced by its name and namespace. It matches when the result
+// has a non zero value, so both filtering comparisons like
+// `container_memory_rss{pod="$pod"} > 1e9` and ones with the bool modifier
+// work.
+func (t terminator) queryMatches(ctx context.Context, query string, pod *v1.Pod) (bool, error) {
+	if t.prometheus == nil {
+		return false, fmt.Errorf("the query of the policy of pod %s needs a prometheus-url", pod.Name)
+	}
+
+	query = strings.NewReplacer("$pod", pod.Name, "$namespace", pod.Namespace).Replace(query)
+	result, warnings, err := t.prometheus.Query(ctx, query, t.clock.Now())
+	if err != nil {
+		return false, fmt.Errorf("could not evaluate the query %q: %w", query, err)
+	}
+	for _, warning := range warnings {
+		t.log.Warnf("query %q: %s", query, warning)
+	}
+
+	switch result := result.(type) {
+	case model.Vector:
+		for _, value := range result {
+			if value.Value != 0 {
+				return true, nil
+			}
+		}
+	case *model.Scalar:
+		return result.Value != 0, nil
+	}
+	return false, nil
+}
@@ -0,0 +1,126 @@
+package terminator
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Where the settings of a policy come from.
+const (
+	sourceFlag       = "flag"
+	sourceSystem     = "system"
+	sourcePolicyFile = "policy-file"
+)
+
+// The settings of a policy, indexing its provenance.
+const (
+	settingLimit = iota
+	settingKillAfter
+	settingCooldown
+	settingCondition
+	settingRule
+	settingQuery
+	settingProtected
+	settings
+)
+
+var settingNames = [settings]string{"limit", "killAfter", "cooldown", "condition", "rule", "query", "protected"}
+
+// ConfigSource is the configuration a setting of the decision on a pod came
+// from, so a disputed kill can be traced to the exact revision responsible.
+type ConfigSource struct {
+	// Setting is limit, killAfter, cooldown, condition, rule, query or
+	// protected
+	Setting string `json:"setting"`
+	// Source is flag, system for the built-in policies or policy-file
+	Source string `json:"source"`
+	// Name is the flag, or the policy setting it, like namespace payments,
+	// selector tier=batch or tenant search namespace search, followed by the
+	// workload of an override
+	Name string `json:"name"`
+	// File and Revision are the path and the sha256 prefix of the content of
+	// the policy file
+	File     string `json:"file,omitempty"`
+	Revision string `json:"revision,omitempty"`
+}
+
+// provenance is the source of each setting of a policy.
+type provenance [settings]ConfigSource
+
+// set records source as the source of setting.
+func (p *provenance) set(setting int, source ConfigSource) {
+	source.Setting = settingNames[setting]
+	p[setting] = source
+}
+
+// sources returns the sources of the settings that have one.
+func (p provenance) sources() []ConfigSource {
+	var sources []ConfigSource
+	for _, source := range p {
+		if source.Source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+// from overrides p by override like with, recording source as the source of
+// the settings it overrides.
+func (p policy) from(override Policy, source ConfigSource) policy {
+	p = p.with(override)
+	for setting, set := range [settings]bool{
+		override.Limit != nil,
+		override.KillAfter != nil,
+		override.Cooldown != nil,
+		override.condition != nil,
+		override.Rule != nil,
+		override.Query != "",
+		override.Protected != nil,
+	} {
+		if set {
+			p.provenance.set(setting, source)
+		}
+	}
+	return p
+}
+
+// flagSources returns the provenance of the default policy, set by the flags.
+func (p policy) flagSources() provenance {
+	var sources provenance
+	sources.set(settingLimit, ConfigSource{Source: sourceFlag, Name: "limit"})
+	sources.set(settingKillAfter, ConfigSource{Source: sourceFlag, Name: "kill-after"})
+	sources.set(settingCooldown, ConfigSource{Source: sourceFlag, Name: "cooldown"})
+	if p.condition != nil {
+		sources.set(settingCondition, ConfigSource{Source: sourceFlag, Name: "condition"})
+	}
+	if p.query != "" {
+		sources.set(settingQuery, ConfigSource{Source: sourceFlag, Name: "query"})
+	}
+	return sources
+}
+
+// revision returns the revision of the content of a policy file.
+func revision(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))[:12]
+}
+
+// source returns the source of the settings of namespacePolicy of file, or of
+// its override for workload when it is not empty.
+func (file *PolicyFile) source(namespacePolicy *NamespacePolicy, workload string) ConfigSource {
+	source := ConfigSource{Source: sourcePolicyFile, File: file.path, Revision: file.revision}
+	if file.system {
+		source = ConfigSource{Source: sourceSystem}
+	}
+
+	source.Name = "namespace " + namespacePolicy.Name
+	if namespacePolicy.Name == "" {
+		source.Name = "selector " + namespacePolicy.Selector
+	}
+	if namespacePolicy.tenant != "" {
+		source.Name = "tenant " + namespacePolicy.tenant + " " + source.Name
+	}
+	if workload != "" {
+		source.Name += " workload " + workload
+	}
+	return source
+}
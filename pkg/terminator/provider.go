@@ -0,0 +1,183 @@
+package terminator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// MetricsProvider returns the memory usage of pods.
+type MetricsProvider interface {
+	// PodUsage returns the memory usage of pod, or nil when it has no
+	// metrics yet.
+	PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error)
+}
+
+// ErrStaleMetrics is returned by metrics providers when the usage of a pod is
+// too old to act on, so the pod is skipped on this check.
+var ErrStaleMetrics = stderrors.New("stale metrics")
+
+const MetricsSourceMetricsServer = "metrics-server"
+
+type metricsServerProvider struct {
+	metrics metrics.Interface
+	// maxAge is the age from which metrics are stale, zero disables it
+	maxAge   time.Duration
+	clock    Clock
+	snapshot *metricsSnapshot
+}
+
+// prefetcher is a MetricsProvider that fetches the usage of the pods of a check
+// at once before they are evaluated, instead of one by one.
+type prefetcher interface {
+	prefetch(ctx context.Context, pods []v1.Pod, workers int) error
+}
+
+// metricsSnapshot are the metrics of the pods of the namespaces listed before
+// a check, by namespace and then by name.
+type metricsSnapshot struct {
+	mu         sync.RWMutex
+	namespaces map[string]map[string]*v1beta1.PodMetrics
+}
+
+// get returns the listed metrics of pod, nil when it had none, and whether its
+// namespace was listed.
+func (s *metricsSnapshot) get(pod *v1.Pod) (*v1beta1.PodMetrics, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pods, ok := s.namespaces[pod.Namespace]
+	return pods[pod.Name], ok
+}
+
+// NewMetricsServerProvider returns a MetricsProvider reading the usage of pods
+// from metrics-server, the default one. Metrics older than maxAge by clock are
+// stale, zero disables it.
+func NewMetricsServerProvider(metrics metrics.Interface, maxAge time.Duration, clock Clock) MetricsProvider {
+	return metricsServerProvider{metrics: metrics, maxAge: maxAge, clock: clock, snapshot: &metricsSnapshot{}}
+}
+
+// prefetch lists the metrics of the namespaces of pods, using up to workers
+// concurrent requests, replacing the ones of the last check. Pods of the
+// namespaces that couldn't be listed are fetched one by one, and so are all
+// of them when there are as many namespaces as pods.
+func (p metricsServerProvider) prefetch(ctx context.Context, pods []v1.Pod, workers int) error {
+	namespaces := make(map[string]map[string]*v1beta1.PodMetrics)
+	for _, pod := range pods {
+		namespaces[pod.Namespace] = nil
+	}
+	if len(namespaces) >= len(pods) {
+		namespaces = nil
+	}
+
+	var mu sync.Mutex
+	var failed error
+	listed := make(map[string]map[string]*v1beta1.PodMetrics, len(namespaces))
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for namespace := range jobs {
+				list, err := p.metrics.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+				mu.Lock()
+				if err != nil {
+					failed = err
+				} else {
+					listed[namespace] = make(map[string]*v1beta1.PodMetrics, len(list.Items))
+					for i := range list.Items {
+						listed[namespace][list.Items[i].Name] = &list.Items[i]
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for namespace := range namespaces {
+		jobs <- namespace
+	}
+	close(jobs)
+	wg.Wait()
+
+	p.snapshot.mu.Lock()
+	p.snapshot.namespaces = listed
+	p.snapshot.mu.Unlock()
+	return failed
+}
+
+// podMetrics returns the metrics of pod, or nil when it has none yet.
+func (p metricsServerProvider) podMetrics(ctx context.Context, pod *v1.Pod) (*v1beta1.PodMetrics, error) {
+	podMetrics, listed := p.snapshot.get(pod)
+	if !listed {
+		var err error
+		podMetrics, err = p.metrics.MetricsV1beta1().PodMetricses(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+	}
+
+	if podMetrics == nil || len(podMetrics.Containers) == 0 {
+		return nil, nil
+	}
+
+	// the timestamp is the end of the window the usage was collected over,
+	// so older ones can be from before the pod freed memory
+	if age := p.clock.Now().Sub(podMetrics.Timestamp.Time); p.maxAge > 0 && age > p.maxAge {
+		return nil, fmt.Errorf("metrics of pod %s are %s old: %w", pod.Name, age.Round(time.Second), ErrStaleMetrics)
+	}
+
+	return podMetrics, nil
+}
+
+func (p metricsServerProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	podMetrics, err := p.podMetrics(ctx, pod)
+	if err != nil || podMetrics == nil {
+		return nil, err
+	}
+
+	return memoryUsage(pod, podMetrics), nil
+}
+
+func (p metricsServerProvider) PodCPU(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	podMetrics, err := p.podMetrics(ctx, pod)
+	if err != nil || podMetrics == nil {
+		return nil, err
+	}
+
+	return cpuUsage(pod, podMetrics), nil
+}
+
+// sourceProvider returns the MetricsProvider of the MetricsSource of options,
+// or fallback for metrics-server.
+func sourceProvider(ctx context.Context, options Options, clientset kubernetes.Interface, fallback MetricsProvider) (MetricsProvider, error) {
+	switch options.MetricsSource {
+	case MetricsSourceCAdvisor:
+		selector, err := labels.Parse(options.CAdvisorNodeSelector)
+		if err != nil {
+			return nil, err
+		}
+		return NewCAdvisorProvider(clientset, options.MemoryMetric, selector, fallback), nil
+	case MetricsSourceDatadog:
+		return NewDatadogProvider(options.Datadog)
+	case MetricsSourceCloudWatch:
+		return NewCloudWatchProvider(ctx, options.CloudWatch)
+	case MetricsSourceGCM:
+		return NewGCMProvider(ctx, options.GCM)
+	default:
+		return fallback, nil
+	}
+}
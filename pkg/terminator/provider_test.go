@@ -0,0 +1,51 @@
+package terminator
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// TestMetricsServerStale checks the age of metrics is told by the clock of the
+// provider rather than by the wall time.
+func TestMetricsServerStale(t *testing.T) {
+	collected := time.Date(2026, 10, 14, 6, 0, 0, 0, time.UTC)
+	podMetrics := v1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web"},
+		Timestamp:  metav1.NewTime(collected),
+		Containers: []v1beta1.ContainerMetrics{{Name: "app", Usage: v1.ResourceList{v1.ResourceMemory: resource.MustParse("512Mi")}}},
+	}
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, podMetrics.DeepCopy(), nil
+	})
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "web"}, Spec: v1.PodSpec{Containers: []v1.Container{{Name: "app"}}}}
+
+	tests := []struct {
+		name  string
+		now   time.Time
+		stale bool
+	}{
+		{"fresh", collected.Add(30 * time.Second), false},
+		{"stale", collected.Add(2 * time.Minute), true},
+	}
+	for _, test := range tests {
+		provider := NewMetricsServerProvider(mc, time.Minute, &testClock{now: test.now})
+		usage, err := provider.PodUsage(context.Background(), pod)
+		if stale := errors.Is(err, ErrStaleMetrics); stale != test.stale {
+			t.Errorf("%s: expected stale %v, got %v", test.name, test.stale, err)
+		}
+		if !test.stale && (usage == nil || usage.Cmp(resource.MustParse("512Mi")) != 0) {
+			t.Errorf("%s: expected 512Mi, got %v", test.name, usage)
+		}
+	}
+}
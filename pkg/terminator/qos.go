@@ -0,0 +1,17 @@
+package terminator
+
+import v1 "k8s.io/api/core/v1"
+
+// eligibleQOS returns whether pod can be killed by the QOSClasses of options,
+// which allow any with none.
+func (o Options) eligibleQOS(pod *v1.Pod) bool {
+	if len(o.QOSClasses) == 0 {
+		return true
+	}
+	for _, class := range o.QOSClasses {
+		if v1.PodQOSClass(class) == pod.Status.QOSClass {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package terminator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// What a pod over the limit matched.
+const (
+	matchedMemory    = "memory"
+	matchedRule      = "rule"
+	matchedQuery     = "query"
+	matchedGPU       = "gpu"
+	matchedPids      = "pids"
+	matchedSwap      = "swap"
+	matchedCondition = "condition"
+	// custom metrics are custom:<metric>
+	matchedCustom = "custom:"
+)
+
+// Actions taken on a pod.
+const (
+	// ActionWait counts the pod without killing it yet
+	ActionWait    = "wait"
+	ActionSkip    = "skip"
+	ActionKill    = "kill"
+	ActionDryRun  = "dry_run"
+	ActionScaleUp = "scale_up"
+)
+
+// Protections checked before killing a pod.
+const (
+	protectionBarePod        = "bare_pod"
+	protectionPriority       = "priority"
+	protectionQOS            = "qos"
+	protectionNamespaceRate  = "namespace_rate"
+	protectionCrashLoop      = "crash_loop"
+	protectionRepeatOffender = "repeat_offender"
+	protectionRollout        = "rollout"
+	protectionCooldown       = "cooldown"
+	protectionMaxDisruptions = "max_disruptions"
+	protectionSpread         = "spread"
+	protectionNodePressure   = "node_pressure"
+	protectionActiveHours    = "active_hours"
+	protectionBlackout       = "blackout"
+	protectionGuard          = "guard"
+	protectionWindows        = "windows"
+)
+
+// Reason is the machine-readable reason of a decision on a pod, in the logs
+// and the notifications, so automation doesn't have to parse messages.
+type Reason struct {
+	// MatchedRule is what the pod is over: memory, rule, query, gpu, pids,
+	// swap, condition or custom:<metric>
+	MatchedRule string       `json:"matchedRule,omitempty"`
+	Sample      ReasonSample `json:"sample"`
+	// Counter is the amount of checks the pod has been over the limit for,
+	// out of KillAfter
+	Counter   int `json:"counter"`
+	KillAfter int `json:"killAfter"`
+	// ProtectionsChecked are the protections the pod went through, in order
+	ProtectionsChecked []string `json:"protectionsChecked,omitempty"`
+	// Action is wait, skip, kill, dry_run or scale_up
+	Action string `json:"action"`
+	// SkippedBy is the protection the pod was skipped by
+	SkippedBy string `json:"skippedBy,omitempty"`
+	// Provenance is where the settings of the policy of the pod came from
+	Provenance []ConfigSource `json:"provenance,omitempty"`
+}
+
+// ReasonSample is the usage of a pod the decision was made on.
+type ReasonSample struct {
+	Usage      int64   `json:"usage"`
+	Limit      int64   `json:"limit"`
+	Percentage float64 `json:"percentage"`
+}
+
+func newReason(s sample, matched string, counter, killAfter int) *Reason {
+	return &Reason{
+		MatchedRule: matched,
+		Sample:      ReasonSample{Usage: s.using.Value(), Limit: s.limit.Value(), Percentage: s.percentage},
+		Counter:     counter,
+		KillAfter:   killAfter,
+		Action:      ActionWait,
+	}
+}
+
+// check records that protection was checked.
+func (r *Reason) check(protection string) {
+	r.ProtectionsChecked = append(r.ProtectionsChecked, protection)
+}
+
+// skip records that the pod was skipped by protection.
+func (r *Reason) skip(protection string) {
+	r.Action = ActionSkip
+	r.SkippedBy = protection
+}
+
+// snapshot returns a copy of r as it is, for the notifications.
+func (r *Reason) snapshot() *Reason {
+	snapshot := *r
+	snapshot.ProtectionsChecked = append([]string(nil), r.ProtectionsChecked...)
+	return &snapshot
+}
+
+// matched returns what a pod over the limit with s matched, empty when it is
+// not over it.
+func (t terminator) matched(s sample, overMemory bool) string {
+	switch {
+	case s.ruled:
+		return matchedRule
+	case s.queried:
+		return matchedQuery
+	case s.overGPU(t.options.GPULimit):
+		return matchedGPU
+	case s.overPids(t.options.PIDLimit):
+		return matchedPids
+	}
+	if metric, ok := t.overCustom(s); ok {
+		return matchedCustom + metric
+	}
+	if s.overSwap(t.options.SwapLimit) {
+		return matchedSwap
+	}
+	if overMemory {
+		return matchedMemory
+	}
+	return ""
+}
+
+// logDecision logs the decision on pod with its reason as JSON.
+func (t terminator) logDecision(pod *v1.Pod, reason *Reason) {
+	t.decided(pod.Namespace, pod.Name, reason)
+	data, err := json.Marshal(reason)
+	if err != nil {
+		t.log.Errorf("could not encode the reason of the decision on pod %s: %s", pod.Name, err)
+		return
+	}
+	t.log.WithField("namespace", pod.Namespace).WithField("pod", pod.Name).WithField("reason", string(data)).Info(fmt.Sprintf("decision on pod < %s >: %s", pod.Name, reason.Action))
+}
@@ -0,0 +1,127 @@
+package terminator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Recommendation is the suggested memory of the containers of a workload,
+// from the usage of its pods in a recording.
+type Recommendation struct {
+	Namespace string
+	// Workload is the kind and name of the workload, like deployment/api
+	Workload   string
+	Samples    int
+	Containers []ContainerRecommendation
+}
+
+// ContainerRecommendation is the suggested memory request and limit of a
+// container.
+type ContainerRecommendation struct {
+	Name    string
+	Request resource.Quantity
+	Limit   resource.Quantity
+}
+
+// recommendedUnit is what recommendations are rounded up to.
+const recommendedUnit = 1 << 20
+
+// Recommend reads the checks recorded in r and suggests the memory of the
+// workloads of the recorded pods: the aggregation of their usage, like p95,
+// as request and the limitAggregation of it, like their peak usage, plus
+// headroom percent as limit. The usage
+// of a pod is split among its containers by their current limits, or evenly
+// when they have none. Pods without a controller are left out.
+func Recommend(r io.Reader, aggregation, limitAggregation string, headroom int) ([]Recommendation, error) {
+	if err := validateAggregation(aggregation); err != nil {
+		return nil, err
+	}
+	if err := validateAggregation(limitAggregation); err != nil {
+		return nil, err
+	}
+
+	usages := make(map[string][]float64)
+	pods := make(map[string]v1.Pod)
+	decoder := json.NewDecoder(r)
+	for {
+		var recorded RecordedCheck
+		if err := decoder.Decode(&recorded); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid recording: %w", err)
+		}
+
+		for _, recordedPod := range recorded.Pods {
+			if recordedPod.Usage == nil || strings.HasPrefix(workloadName(&recordedPod.Pod), "pod/") {
+				continue
+			}
+
+			key := workloadKey(&recordedPod.Pod)
+			usages[key] = append(usages[key], float64(recordedPod.Usage.Value()))
+			pods[key] = recordedPod.Pod
+		}
+	}
+
+	keys := make([]string, 0, len(usages))
+	for key := range usages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	recommendations := make([]Recommendation, 0, len(keys))
+	for _, key := range keys {
+		pod := pods[key]
+		request := aggregate(aggregation, usages[key])
+		limit := suggestedLimit(usages[key], limitAggregation, headroom)
+
+		recommendation := Recommendation{Namespace: pod.Namespace, Workload: workloadName(&pod), Samples: len(usages[key])}
+		for i, share := range containerShares(pod.Spec.Containers) {
+			recommendation.Containers = append(recommendation.Containers, ContainerRecommendation{
+				Name:    pod.Spec.Containers[i].Name,
+				Request: roundedQuantity(request * share),
+				Limit:   roundedQuantity(limit * share),
+			})
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+
+	return recommendations, nil
+}
+
+// suggestedLimit returns the limit suggested for usages: their aggregation,
+// like max or p99, plus headroom percent.
+func suggestedLimit(usages []float64, aggregation string, headroom int) float64 {
+	return aggregate(aggregation, usages) * (1 + float64(headroom)/100)
+}
+
+// containerShares returns the share of the memory of a pod of each of its
+// containers, by their current memory limits.
+func containerShares(containers []v1.Container) []float64 {
+	shares := make([]float64, len(containers))
+	total := 0.0
+	for i, container := range containers {
+		shares[i] = float64(container.Resources.Limits.Memory().Value())
+		total += shares[i]
+	}
+
+	for i := range shares {
+		if total == 0 {
+			shares[i] = 1 / float64(len(shares))
+		} else {
+			shares[i] /= total
+		}
+	}
+	return shares
+}
+
+func roundedQuantity(bytes float64) resource.Quantity {
+	rounded := int64(math.Ceil(bytes/recommendedUnit)) * recommendedUnit
+	return *resource.NewQuantity(rounded, resource.BinarySI)
+}
@@ -0,0 +1,252 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// RecordedCheck are the pods found on a check, with their memory usage, and
+// their namespaces, written by Record as a line of JSON to be replayed.
+type RecordedCheck struct {
+	Time       time.Time      `json:"time"`
+	Namespaces []v1.Namespace `json:"namespaces,omitempty"`
+	Pods       []RecordedPod  `json:"pods"`
+}
+
+// RecordedPod is a pod and its memory usage, nil when it had no metrics.
+type RecordedPod struct {
+	Pod   v1.Pod             `json:"pod"`
+	Usage *resource.Quantity `json:"usage,omitempty"`
+}
+
+// Record writes the targeted pods and their memory usage to w on every check,
+// without killing any of them, until ctx is done.
+func (t terminator) Record(ctx context.Context, targets Targets, sleep time.Duration, w io.Writer) error {
+	watcher, err := t.watchTargets(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	for {
+		pods, err := t.getPods(ctx, watcher, targets)
+		if err != nil {
+			return err
+		}
+
+		recorded := RecordedCheck{Time: t.clock.Now(), Pods: make([]RecordedPod, len(pods.Items))}
+		index := make(map[*v1.Pod]int, len(pods.Items))
+		for i := range pods.Items {
+			index[&pods.Items[i]] = i
+			recorded.Pods[i].Pod = pods.Items[i]
+		}
+
+		var mu sync.Mutex
+		err = t.evaluate(ctx, pods.Items, func(ctx context.Context, pod *v1.Pod) error {
+			if pod.Status.Phase != v1.PodRunning {
+				return nil
+			}
+
+			usage, err := t.podUsage(ctx, pod)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			recorded.Pods[index[pod]].Usage = usage
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]bool)
+		for _, pod := range pods.Items {
+			if seen[pod.Namespace] || t.options.Scope == ScopeNamespace {
+				continue
+			}
+			seen[pod.Namespace] = true
+
+			namespace, err := t.clientset.CoreV1().Namespaces().Get(ctx, pod.Namespace, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			recorded.Namespaces = append(recorded.Namespaces, *namespace)
+		}
+
+		if err := encoder.Encode(recorded); err != nil {
+			return err
+		}
+		t.log.Infof("recorded %d pods", len(recorded.Pods))
+
+		if err := t.sleep(ctx, sleep); err != nil {
+			return err
+		}
+	}
+}
+
+// ReplayReport are the pods that would have been killed by a replay.
+type ReplayReport struct {
+	Checks int          `json:"checks"`
+	Kills  []ReplayKill `json:"kills"`
+}
+
+// ReplayKill is a pod that would have been killed at the time of a replayed
+// check.
+type ReplayKill struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+}
+
+// Replay runs the decisions of a terminator with options against the checks
+// recorded in r, without a cluster, returning which pods would have been
+// killed and when. Only the kills are faked, so pods are still skipped by
+// cooldowns, blackouts and crash loops as they would have been.
+func Replay(ctx context.Context, r io.Reader, options Options, memoryLimit, killAfter int, killSleep time.Duration, opts ...Option) (*ReplayReport, error) {
+	clientset := fake.NewSimpleClientset()
+	clock := &replayClock{}
+	provider := &replayProvider{}
+	opts = append(opts, WithMetricsProvider(provider), WithClock(clock))
+
+	created, err := NewForClients(clientset, nil, options, opts...)
+	if err != nil {
+		return nil, err
+	}
+	t := created.(terminator)
+	action := &replayAction{clock: clock, log: t.log, kills: []ReplayKill{}}
+	t.action = action
+
+	watcher, err := t.watchTargets(ctx, Targets{})
+	if err != nil {
+		return nil, err
+	}
+
+	state := newState()
+	defaults := t.defaultPolicy(memoryLimit, killAfter)
+	decoder := json.NewDecoder(r)
+	checks := 0
+	for {
+		var recorded RecordedCheck
+		if err := decoder.Decode(&recorded); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid recording: %w", err)
+		}
+
+		// counters expire after killSleep per check, which the recorded checks
+		// can be further apart than
+		expiry := killSleep
+		if gap := recorded.Time.Sub(clock.now); checks > 0 && gap > expiry {
+			expiry = gap
+		}
+
+		clock.now = recorded.Time
+		if err := provider.load(ctx, clientset, recorded); err != nil {
+			return nil, err
+		}
+		if err := t.runCheck(ctx, watcher, Targets{}, state, defaults, expiry); err != nil {
+			return nil, err
+		}
+		checks++
+	}
+
+	return &ReplayReport{Checks: checks, Kills: action.kills}, nil
+}
+
+// replayClock is at the time of the replayed check and never waits.
+type replayClock struct {
+	now time.Time
+}
+
+func (c *replayClock) Now() time.Time {
+	return c.now
+}
+
+func (c *replayClock) After(time.Duration) <-chan time.Time {
+	after := make(chan time.Time, 1)
+	after <- c.now
+	return after
+}
+
+// replayProvider returns the recorded usage of the pods of the replayed check.
+type replayProvider struct {
+	usage map[string]*resource.Quantity
+}
+
+// load makes clientset hold the namespaces and pods of recorded, and only
+// those pods.
+func (p *replayProvider) load(ctx context.Context, clientset kubernetes.Interface, recorded RecordedCheck) error {
+	for i := range recorded.Namespaces {
+		namespace := &recorded.Namespaces[i]
+		namespace.ResourceVersion = ""
+		if _, err := clientset.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{}); errors.IsAlreadyExists(err) {
+			_, err = clientset.CoreV1().Namespaces().Update(ctx, namespace, metav1.UpdateOptions{})
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	p.usage = make(map[string]*resource.Quantity, len(recorded.Pods))
+	for i := range recorded.Pods {
+		pod := &recorded.Pods[i].Pod
+		pod.ResourceVersion = ""
+		p.usage[pod.Namespace+"/"+pod.Name] = recorded.Pods[i].Usage
+		if _, err := clientset.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{}); errors.IsAlreadyExists(err) {
+			_, err = clientset.CoreV1().Pods(pod.Namespace).Update(ctx, pod, metav1.UpdateOptions{})
+			if err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	existing, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, pod := range existing.Items {
+		if _, ok := p.usage[pod.Namespace+"/"+pod.Name]; ok {
+			continue
+		}
+		if err := clientset.CoreV1().Pods(pod.Namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *replayProvider) PodUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	return p.usage[pod.Namespace+"/"+pod.Name], nil
+}
+
+// replayAction keeps the pods that would have been killed with the time of
+// the replayed check.
+type replayAction struct {
+	clock *replayClock
+	log   *logrus.Entry
+	kills []ReplayKill
+}
+
+func (a *replayAction) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	a.kills = append(a.kills, ReplayKill{Time: a.clock.Now(), Namespace: pod.Namespace, Pod: pod.Name})
+	a.log.Infof("%s: pod < %s/%s > would have been killed", a.clock.Now().Format(time.RFC3339), pod.Namespace, pod.Name)
+	return nil
+}
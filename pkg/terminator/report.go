@@ -0,0 +1,174 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// errorReportInterval is how often an error that keeps happening is reported
+// again, with the times it happened since.
+const errorReportInterval = 10 * time.Minute
+
+// Levels of the reported errors.
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// ErrorReport is an operational error or a panic of the terminator, with the
+// context to debug it once the logs of its pod are gone.
+type ErrorReport struct {
+	Time time.Time `json:"time"`
+	// Level is error, or fatal for panics
+	Level     string `json:"level"`
+	Message   string `json:"message"`
+	Cluster   string `json:"cluster,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	// Repeats are the times the error happened since it was last reported
+	Repeats int `json:"repeats,omitempty"`
+	// LastDecision is the last decision made on a pod before the error
+	LastDecision *Decision `json:"lastDecision,omitempty"`
+	// Stack is the stack of the goroutine that panicked
+	Stack string `json:"stack,omitempty"`
+}
+
+// Decision is a decision made on a pod.
+type Decision struct {
+	Time      time.Time `json:"time"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod"`
+	Reason    *Reason   `json:"reason"`
+}
+
+// ErrorReporter sends the errors of the terminator to an error tracker.
+type ErrorReporter interface {
+	Report(ctx context.Context, report ErrorReport) error
+}
+
+type webhookErrorReporter struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookErrorReporter returns an ErrorReporter that POSTs reports as JSON
+// to url.
+func NewWebhookErrorReporter(url string) ErrorReporter {
+	return webhookErrorReporter{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (r webhookErrorReporter) Report(ctx context.Context, report ErrorReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("error reporter %s returned %s", r.url, resp.Status)
+	}
+	return nil
+}
+
+// errorHistory is when each error was last reported and the last decision,
+// shared by every copy of the terminator.
+type errorHistory struct {
+	mu           sync.Mutex
+	reported     map[string]time.Time
+	repeats      map[string]int
+	lastDecision *Decision
+}
+
+// decided records the decision on pod as the last one.
+func (t terminator) decided(namespace, pod string, reason *Reason) {
+	if t.reporter == nil {
+		return
+	}
+
+	t.errorHistory.mu.Lock()
+	defer t.errorHistory.mu.Unlock()
+	t.errorHistory.lastDecision = &Decision{Time: t.clock.Now(), Namespace: namespace, Pod: pod, Reason: reason.snapshot()}
+}
+
+// operationalError logs an error of check c that didn't fail it, counting and
+// reporting it. Errors with the same format are reported once every
+// errorReportInterval.
+func (t terminator) operationalError(ctx context.Context, c *check, namespace, pod, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	t.log.Error(message)
+	c.countError()
+	t.reportError(ctx, format, ErrorReport{Level: LevelError, Message: message, Namespace: namespace, Pod: pod})
+}
+
+// reportError reports report unless an error of the same kind was reported
+// within the errorReportInterval, in which case it is counted as a repeat of
+// it. Failing to report it is logged.
+func (t terminator) reportError(ctx context.Context, kind string, report ErrorReport) {
+	if t.reporter == nil {
+		return
+	}
+
+	now := t.clock.Now()
+	t.errorHistory.mu.Lock()
+	if last, ok := t.errorHistory.reported[kind]; ok && now.Sub(last) < errorReportInterval {
+		t.errorHistory.repeats[kind]++
+		t.errorHistory.mu.Unlock()
+		return
+	}
+	report.Repeats = t.errorHistory.repeats[kind]
+	t.errorHistory.reported[kind] = now
+	t.errorHistory.repeats[kind] = 0
+	report.LastDecision = t.errorHistory.lastDecision
+	t.errorHistory.mu.Unlock()
+
+	report.Time = now
+	report.Cluster = t.options.Cluster
+	if err := t.reporter.Report(ctx, report); err != nil {
+		t.log.Errorf("could not report the error %q: %s", report.Message, err)
+	}
+}
+
+// reportPanic reports a panic of the goroutine it is deferred in and panics
+// again, so the terminator still crashes but the panic isn't lost with its
+// logs.
+func (t terminator) reportPanic() {
+	if t.reporter == nil {
+		return
+	}
+
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	report := ErrorReport{Level: LevelFatal, Message: fmt.Sprintf("panic: %v", recovered), Stack: string(debug.Stack())}
+	t.errorHistory.mu.Lock()
+	report.LastDecision = t.errorHistory.lastDecision
+	t.errorHistory.mu.Unlock()
+
+	report.Time = t.clock.Now()
+	report.Cluster = t.options.Cluster
+	if err := t.reporter.Report(ctx, report); err != nil {
+		t.log.Errorf("could not report the panic: %s", err)
+	}
+	panic(recovered)
+}
@@ -0,0 +1,74 @@
+package terminator
+
+import (
+	"context"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// rollingOut tells whether the deployment or statefulset owning pod has a
+// rollout in progress, so its pods are not killed while its controller is
+// replacing them. It is fetched once on each check.
+func (t terminator) rollingOut(ctx context.Context, c *check, pod *v1.Pod) (bool, error) {
+	kind, name, _ := strings.Cut(workloadName(pod), "/")
+	if kind != "deployment" && kind != "statefulset" {
+		return false, nil
+	}
+
+	rolling, err := c.rollouts.get(workloadKey(pod), func() (interface{}, error) {
+		var rolling bool
+		var err error
+		switch kind {
+		case "deployment":
+			var deployment *appsv1.Deployment
+			deployment, err = t.clientset.AppsV1().Deployments(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+			if err == nil {
+				rolling = deploymentRollingOut(deployment)
+			}
+		case "statefulset":
+			var statefulSet *appsv1.StatefulSet
+			statefulSet, err = t.clientset.AppsV1().StatefulSets(pod.Namespace).Get(ctx, name, metav1.GetOptions{})
+			if err == nil {
+				rolling = statefulSetRollingOut(statefulSet)
+			}
+		}
+		if err != nil && !errors.IsNotFound(err) {
+			return nil, err
+		}
+		return rolling, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return rolling.(bool), nil
+}
+
+func deploymentRollingOut(deployment *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+
+	status := deployment.Status
+	return status.ObservedGeneration < deployment.Generation ||
+		status.UpdatedReplicas < replicas ||
+		status.Replicas > status.UpdatedReplicas ||
+		status.AvailableReplicas < status.UpdatedReplicas
+}
+
+func statefulSetRollingOut(statefulSet *appsv1.StatefulSet) bool {
+	replicas := int32(1)
+	if statefulSet.Spec.Replicas != nil {
+		replicas = *statefulSet.Spec.Replicas
+	}
+
+	status := statefulSet.Status
+	return status.ObservedGeneration < statefulSet.Generation ||
+		(status.UpdateRevision != "" && status.CurrentRevision != status.UpdateRevision) ||
+		status.UpdatedReplicas < replicas ||
+		status.ReadyReplicas < replicas
+}
@@ -0,0 +1,99 @@
+package terminator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rule combines thresholds on the resources of pods, replacing the memory
+// limit of a policy. A rule is either a combination, matching when All or Any
+// of its rules match, or a single threshold. Durations are still counted by
+// kill-after, so
+//
+//	all:
+//	  - memory: 90
+//	  - cpu: 80
+//
+// with killAfter 5 kills pods over both for 5 checks.
+type Rule struct {
+	All []Rule `json:"all,omitempty"`
+	Any []Rule `json:"any,omitempty"`
+	// Memory, CPU, GPU and Pids are usage percentages of the limits of the
+	// pod. Pods without a CPU limit never match a CPU threshold
+	Memory *float64 `json:"memory,omitempty"`
+	CPU    *float64 `json:"cpu,omitempty"`
+	GPU    *float64 `json:"gpu,omitempty"`
+	Pids   *float64 `json:"pids,omitempty"`
+	// Restarts is the restart count of a container of the pod, telling
+	// another restart is likely
+	Restarts *int32 `json:"restarts,omitempty"`
+}
+
+func (r *Rule) validate() error {
+	set := 0
+	for _, ok := range []bool{r.All != nil, r.Any != nil, r.Memory != nil, r.CPU != nil, r.GPU != nil, r.Pids != nil, r.Restarts != nil} {
+		if ok {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("a rule needs exactly one of all, any, memory, cpu, gpu, pids or restarts")
+	}
+
+	for _, rules := range [][]Rule{r.All, r.Any} {
+		if rules != nil && len(rules) == 0 {
+			return errors.New("a rule combines no rules")
+		}
+		for i := range rules {
+			if err := rules[i].validate(); err != nil {
+				return fmt.Errorf("rule %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// usesCPU tells whether the CPU of pods is needed to match r.
+func (r *Rule) usesCPU() bool {
+	if r.CPU != nil {
+		return true
+	}
+	for _, rules := range [][]Rule{r.All, r.Any} {
+		for i := range rules {
+			if rules[i].usesCPU() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *Rule) matches(s sample) bool {
+	switch {
+	case r.All != nil:
+		for i := range r.All {
+			if !r.All[i].matches(s) {
+				return false
+			}
+		}
+		return true
+	case r.Any != nil:
+		for i := range r.Any {
+			if r.Any[i].matches(s) {
+				return true
+			}
+		}
+		return false
+	case r.Memory != nil:
+		return s.percentage >= *r.Memory
+	case r.CPU != nil:
+		return s.cpuPercentage > 0 && s.cpuPercentage >= *r.CPU
+	case r.GPU != nil:
+		return s.gpuPercentage > 0 && s.gpuPercentage >= *r.GPU
+	case r.Pids != nil:
+		return s.pidsLimit > 0 && float64(s.pids)/float64(s.pidsLimit)*100 >= *r.Pids
+	case r.Restarts != nil:
+		return s.restarts >= *r.Restarts
+	}
+	return false
+}
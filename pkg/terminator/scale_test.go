@@ -0,0 +1,164 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+const (
+	scalePods       = 10000
+	scaleNamespaces = 100
+)
+
+// scaleCluster returns a terminator over a fake cluster of pods spread over
+// namespaces, all of them under the limit, and the watcher of all of them.
+func scaleCluster(tb testing.TB, pods, namespaces int, options Options) (terminator, *targetWatcher) {
+	var objects []runtime.Object
+	podMetrics := make(map[string]*v1beta1.PodMetricsList, namespaces)
+	for i := 0; i < pods; i++ {
+		namespace := fmt.Sprintf("namespace-%d", i%namespaces)
+		name := fmt.Sprintf("pod-%d", i)
+		objects = append(objects, &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, UID: types.UID(name)},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name:      "app",
+				Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+			}}},
+			Status: v1.PodStatus{Phase: v1.PodRunning},
+		})
+
+		if podMetrics[namespace] == nil {
+			podMetrics[namespace] = new(v1beta1.PodMetricsList)
+		}
+		podMetrics[namespace].Items = append(podMetrics[namespace].Items, v1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Timestamp:  metav1.Now(),
+			Containers: []v1beta1.ContainerMetrics{{
+				Name:  "app",
+				Usage: v1.ResourceList{v1.ResourceMemory: resource.MustParse("512Mi")},
+			}},
+		})
+	}
+
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		list, ok := podMetrics[action.GetNamespace()]
+		if !ok {
+			list = new(v1beta1.PodMetricsList)
+		}
+		return true, list.DeepCopy(), nil
+	})
+
+	options.Workers = 10
+	options.Quiet = true
+	debug := logrus.New()
+	debug.SetOutput(io.Discard)
+	created, err := NewForClients(fake.NewSimpleClientset(objects...), mc, options, WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	t := created.(terminator)
+
+	watcher, err := t.watchTargets(context.Background(), Targets{})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return t, watcher
+}
+
+func benchmarkCheck(b *testing.B, options Options) {
+	t, watcher := scaleCluster(b, scalePods, scaleNamespaces, options)
+	state := newState()
+	defaults := t.defaultPolicy(95, 1)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := t.runCheck(context.Background(), watcher, Targets{}, state, defaults, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCheck(b *testing.B) {
+	benchmarkCheck(b, Options{})
+}
+
+func BenchmarkCheckWindow(b *testing.B) {
+	benchmarkCheck(b, Options{Aggregation: "p95", Window: 10 * time.Minute, MaxTrackedPods: scalePods / 2})
+}
+
+func BenchmarkCheckTwoPhase(b *testing.B) {
+	benchmarkCheck(b, Options{TwoPhase: true, CandidatePercentage: 80})
+}
+
+func BenchmarkGetPods(b *testing.B) {
+	t, watcher := scaleCluster(b, scalePods, scaleNamespaces, Options{})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := t.getPods(context.Background(), watcher, Targets{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLRU(b *testing.B) {
+	l := newLRU()
+	now := time.Now()
+	for i := 0; i < b.N; i++ {
+		l.touch(fmt.Sprintf("pod-%d", i%scalePods), now)
+		l.evict(scalePods / 2)
+	}
+}
+
+// TestCheckScale checks a full check of 10k pods completes within a few
+// seconds, with the pods from the cache and their metrics listed once per
+// namespace.
+func TestCheckScale(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping the check of 10k pods in short mode")
+	}
+
+	terminator, watcher := scaleCluster(t, scalePods, scaleNamespaces, Options{})
+	started := time.Now()
+	if err := terminator.runCheck(context.Background(), watcher, Targets{}, newState(), terminator.defaultPolicy(95, 1), 0); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("checking %d pods took %s, expected at most 5s", scalePods, elapsed)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	l := newLRU()
+	now := time.Now()
+	l.touch("a", now)
+	l.touch("b", now.Add(time.Second))
+	l.touch("c", now.Add(2*time.Second))
+	l.touch("a", now.Add(3*time.Second))
+
+	if evicted := l.evict(2); len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("expected b to be evicted, got %v", evicted)
+	}
+	if expired := l.expire(now.Add(3 * time.Second)); len(expired) != 1 || expired[0] != "c" {
+		t.Errorf("expected c to expire, got %v", expired)
+	}
+	if evicted := l.evict(0); len(evicted) != 0 {
+		t.Errorf("expected nothing to be evicted without a capacity, got %v", evicted)
+	}
+}
@@ -0,0 +1,170 @@
+package terminator
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+var (
+	// dailyWindow is like "Mon-Fri 08:00-20:00", hours on the given days
+	dailyWindow = regexp.MustCompile(`^(\S+) (\d{1,2}:\d{2})-(\d{1,2}:\d{2})$`)
+	// spanWindow is like "Sat 00:00-Sun 23:59", a continuous span of the week
+	spanWindow = regexp.MustCompile(`^(\w{3}) (\d{1,2}:\d{2})-(\w{3}) (\d{1,2}:\d{2})$`)
+
+	weekdays = map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+)
+
+// Schedule is a set of weekly recurring windows in a timezone. Windows are
+// either hours on some days, like "Mon-Fri 08:00-20:00" or "Sat,Sun 10:00-14:00",
+// or a span of the week, like "Fri 18:00-Mon 08:00". Both ends are inclusive.
+type Schedule struct {
+	location *time.Location
+	// spans are inclusive ranges of minutes of the week, starting on sunday
+	spans [][2]int
+}
+
+// ParseSchedule parses windows in location, returning nil when there is none.
+func ParseSchedule(windows []string, location *time.Location) (*Schedule, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	schedule := &Schedule{location: location}
+	for _, window := range windows {
+		spans, err := parseWindow(strings.TrimSpace(window))
+		if err != nil {
+			return nil, fmt.Errorf("invalid window %q: %w", window, err)
+		}
+		schedule.spans = append(schedule.spans, spans...)
+	}
+
+	return schedule, nil
+}
+
+func parseWindow(window string) ([][2]int, error) {
+	if match := spanWindow.FindStringSubmatch(window); match != nil {
+		start, err := minuteOfWeek(match[1], match[2])
+		if err != nil {
+			return nil, err
+		}
+		end, err := minuteOfWeek(match[3], match[4])
+		if err != nil {
+			return nil, err
+		}
+		return weekSpans(start, end), nil
+	}
+
+	match := dailyWindow.FindStringSubmatch(window)
+	if match == nil {
+		return nil, fmt.Errorf(`expected "<days> HH:MM-HH:MM" or "<day> HH:MM-<day> HH:MM"`)
+	}
+
+	days, err := parseDays(match[1])
+	if err != nil {
+		return nil, err
+	}
+	start, err := minuteOfDay(match[2])
+	if err != nil {
+		return nil, err
+	}
+	end, err := minuteOfDay(match[3])
+	if err != nil {
+		return nil, err
+	}
+
+	var spans [][2]int
+	for _, day := range days {
+		dayEnd := day*minutesPerDay + end
+		if end < start {
+			dayEnd += minutesPerDay
+		}
+		spans = append(spans, weekSpans(day*minutesPerDay+start, dayEnd%minutesPerWeek)...)
+	}
+	return spans, nil
+}
+
+// parseDays parses "*", a day, a range of days like "Mon-Fri" or a comma
+// separated list of those.
+func parseDays(days string) ([]int, error) {
+	if days == "*" {
+		return []int{0, 1, 2, 3, 4, 5, 6}, nil
+	}
+
+	var parsed []int
+	for _, part := range strings.Split(days, ",") {
+		bounds := strings.SplitN(part, "-", 2)
+		first, ok := weekdays[strings.ToLower(bounds[0])]
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", bounds[0])
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[strings.ToLower(bounds[1])]; !ok {
+				return nil, fmt.Errorf("unknown day %q", bounds[1])
+			}
+		}
+
+		for day := first; ; day = (day + 1) % 7 {
+			parsed = append(parsed, day)
+			if day == last {
+				break
+			}
+		}
+	}
+	return parsed, nil
+}
+
+func minuteOfWeek(day, clock string) (int, error) {
+	weekday, ok := weekdays[strings.ToLower(day)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q", day)
+	}
+
+	minute, err := minuteOfDay(clock)
+	if err != nil {
+		return 0, err
+	}
+	return weekday*minutesPerDay + minute, nil
+}
+
+func minuteOfDay(clock string) (int, error) {
+	parts := strings.SplitN(clock, ":", 2)
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour > 23 {
+		return 0, fmt.Errorf("invalid hour %q", clock)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute > 59 {
+		return 0, fmt.Errorf("invalid minute %q", clock)
+	}
+	return hour*60 + minute, nil
+}
+
+// weekSpans splits the span from start to end when it wraps around the week.
+func weekSpans(start, end int) [][2]int {
+	if end < start {
+		return [][2]int{{start, minutesPerWeek - 1}, {0, end}}
+	}
+	return [][2]int{{start, end}}
+}
+
+// Contains reports whether t is inside any of the windows.
+func (s *Schedule) Contains(t time.Time) bool {
+	t = t.In(s.location)
+	minute := int(t.Weekday())*minutesPerDay + t.Hour()*60 + t.Minute()
+	for _, span := range s.spans {
+		if minute >= span[0] && minute <= span[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package terminator
+
+import (
+	"fmt"
+)
+
+const (
+	ScopeCluster = "cluster"
+	// ScopeNamespace never calls cluster-scoped APIs, nor lists pods of all
+	// namespaces, so a namespaced Role is enough. Namespaces are not fetched,
+	// so their selector policies and blackout annotations don't apply
+	ScopeNamespace = "namespace"
+)
+
+// validateScope checks the options don't need cluster-scoped APIs, like the
+// nodes or their kubelets, in ScopeNamespace.
+func (o Options) validateScope() error {
+	switch o.Scope {
+	case "", ScopeCluster:
+		return nil
+	case ScopeNamespace:
+	default:
+		return fmt.Errorf("invalid scope %q, must be cluster or namespace", o.Scope)
+	}
+
+	needsNodes := []struct {
+		option string
+		needed bool
+	}{
+		{"metrics-source " + MetricsSourceCAdvisor, o.MetricsSource == MetricsSourceCAdvisor},
+		{"no-limit-basis " + NoLimitBasisNodeAllocatable, o.NoLimitBasis == NoLimitBasisNodeAllocatable},
+		{"memory-metric " + o.MemoryMetric, o.MemoryMetric != "" && o.MemoryMetric != MemoryMetricWorkingSet && (o.MetricsSource == "" || o.MetricsSource == MetricsSourceMetricsServer)},
+		{"pid-limit", o.PIDLimit > 0},
+		{"count-swap", o.CountSwap || o.SwapLimit != nil},
+		{"node-pressure", o.NodePressure != ""},
+		{"spread-kills", o.SpreadKills},
+	}
+	for _, needs := range needsNodes {
+		if needs.needed {
+			return fmt.Errorf("%s needs the nodes, which scope %s can't get", needs.option, o.Scope)
+		}
+	}
+	return nil
+}
+
+// validateTargets checks targets can be found in the Scope of the
+// terminator.
+func (t terminator) validateTargets(targets Targets) error {
+	if t.options.Scope != ScopeNamespace {
+		return nil
+	}
+
+	if targets.Namespace == "" {
+		return fmt.Errorf("scope %s needs a namespace", t.options.Scope)
+	}
+	if targets.NodeSelector != "" {
+		return fmt.Errorf("node-selector needs the nodes, which scope %s can't get", t.options.Scope)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package terminator
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// target is a service or deployment resolved to the selector of its pods.
+type target struct {
+	selector labels.Selector
+	// replicas is the desired amount of pods, nil for services
+	replicas  *int32
+	namespace string
+	fetched   time.Time
+}
+
+// selectorCache keeps resolved targets for ttl, so a check doesn't have to fetch
+// every service and deployment again just to build their selectors.
+type selectorCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*target
+}
+
+func newSelectorCache(ttl time.Duration) *selectorCache {
+	return &selectorCache{
+		ttl:     ttl,
+		entries: make(map[string]*target),
+	}
+}
+
+// get returns the cached target of kind, calling resolve when it is missing or
+// has expired. Errors are not cached.
+func (c *selectorCache) get(kind, namespace, name string, resolve func() (*target, error)) (*target, error) {
+	key := targetKey(kind, namespace, name)
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && time.Since(cached.fetched) < c.ttl {
+		return cached, nil
+	}
+
+	resolved, err := resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.namespace = namespace
+	resolved.fetched = time.Now()
+	c.mu.Lock()
+	c.entries[key] = resolved
+	c.mu.Unlock()
+	return resolved, nil
+}
+
+func (c *selectorCache) invalidate(kind, namespace, name string) {
+	c.mu.Lock()
+	delete(c.entries, targetKey(kind, namespace, name))
+	c.mu.Unlock()
+}
+
+// matches reports whether a pod in namespace with podLabels is selected by any
+// of the resolved targets.
+func (c *selectorCache) matches(namespace string, podLabels labels.Set) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, entry := range c.entries {
+		if entry.namespace == namespace && entry.selector.Matches(podLabels) {
+			return true
+		}
+	}
+	return false
+}
+
+func targetKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
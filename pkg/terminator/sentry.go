@@ -0,0 +1,120 @@
+package terminator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+type sentryReporter struct {
+	dsn      string
+	key      string
+	envelope string
+	client   *http.Client
+}
+
+// NewSentryReporter returns an ErrorReporter sending reports as events to
+// the Sentry project of dsn, like https://key@o0.ingest.sentry.io/42.
+func NewSentryReporter(dsn string) (ErrorReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sentry dsn: %w", err)
+	}
+	project := path.Base(parsed.Path)
+	if parsed.User == nil || parsed.User.Username() == "" || project == "." || project == "/" {
+		return nil, fmt.Errorf("invalid sentry dsn, must be like https://key@host/project")
+	}
+
+	envelope := url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: path.Join(path.Dir(parsed.Path), "api", project, "envelope") + "/"}
+	return sentryReporter{
+		dsn:      dsn,
+		key:      parsed.User.Username(),
+		envelope: envelope.String(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// sentryEvent is the part of a Sentry event set from an ErrorReport.
+type sentryEvent struct {
+	EventID   string                 `json:"event_id"`
+	Timestamp time.Time              `json:"timestamp"`
+	Level     string                 `json:"level"`
+	Platform  string                 `json:"platform"`
+	Logger    string                 `json:"logger"`
+	Message   sentryMessage          `json:"message"`
+	Tags      map[string]string      `json:"tags,omitempty"`
+	Extra     map[string]interface{} `json:"extra,omitempty"`
+}
+
+type sentryMessage struct {
+	Formatted string `json:"formatted"`
+}
+
+func newSentryEvent(report ErrorReport) sentryEvent {
+	event := sentryEvent{
+		EventID:   strings.ReplaceAll(string(uuid.NewUUID()), "-", ""),
+		Timestamp: report.Time,
+		Level:     report.Level,
+		Platform:  "go",
+		Logger:    "oomterminator",
+		Message:   sentryMessage{Formatted: report.Message},
+		Tags:      make(map[string]string),
+		Extra:     make(map[string]interface{}),
+	}
+	for tag, value := range map[string]string{"cluster": report.Cluster, "namespace": report.Namespace, "pod": report.Pod} {
+		if value != "" {
+			event.Tags[tag] = value
+		}
+	}
+	if report.Repeats > 0 {
+		event.Extra["repeats"] = report.Repeats
+	}
+	if report.LastDecision != nil {
+		event.Extra["lastDecision"] = report.LastDecision
+	}
+	if report.Stack != "" {
+		event.Extra["stack"] = report.Stack
+	}
+	return event
+}
+
+func (r sentryReporter) Report(ctx context.Context, report ErrorReport) error {
+	event := newSentryEvent(report)
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, item := range []interface{}{
+		map[string]string{"event_id": event.EventID, "dsn": r.dsn},
+		map[string]string{"type": "event"},
+		event,
+	} {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.envelope, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", "Sentry sentry_version=7, sentry_client=oomterminator, sentry_key="+r.key)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("sentry returned %s", resp.Status)
+	}
+	return nil
+}
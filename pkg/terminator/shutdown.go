@@ -0,0 +1,57 @@
+package terminator
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrMaxKills is returned by Terminate once MaxKills pods were killed.
+var ErrMaxKills = errors.New("reached the maximum amount of kills")
+
+// detachedContext keeps the values of its parent but is never done.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// drainContext returns a context that is only done timeout after ctx is, so
+// work that already started can finish.
+func drainContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	drain, cancel := context.WithCancel(detachedContext{parent: ctx})
+	go func() {
+		select {
+		case <-drain.Done():
+			return
+		case <-ctx.Done():
+		}
+
+		select {
+		case <-drain.Done():
+		case <-time.After(timeout):
+			cancel()
+		}
+	}()
+	return drain, cancel
+}
+
+// shutdown is called once the terminator stops because its context is done,
+// after the last check finished.
+func (t terminator) shutdown(state *state) {
+	t.out.Printf("Stopping with %d pods over the limit", len(state.podsToKill))
+}
@@ -0,0 +1,73 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// sidecars returns the native sidecars of pod, its init containers with the
+// Always restartPolicy. This version of the API has no restartPolicy for
+// containers, so they are told by still running once the pod is, since the
+// other init containers are done before it starts.
+func sidecars(pod *v1.Pod) []v1.Container {
+	if pod.Status.Phase != v1.PodRunning {
+		return nil
+	}
+
+	running := make(map[string]bool, len(pod.Status.InitContainerStatuses))
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.State.Running != nil {
+			running[status.Name] = true
+		}
+	}
+
+	var containers []v1.Container
+	for _, container := range pod.Spec.InitContainers {
+		if running[container.Name] {
+			containers = append(containers, container)
+		}
+	}
+	return containers
+}
+
+// sidecarPod returns a copy of pod with sidecar as its only container and a uid
+// of its own, so the sidecar has its own counter and is the container the
+// action restarts.
+func sidecarPod(pod *v1.Pod, sidecar v1.Container) *v1.Pod {
+	only := pod.DeepCopy()
+	only.UID = types.UID(fmt.Sprintf("%s/%s", pod.UID, sidecar.Name))
+	only.Spec.InitContainers = nil
+	only.Spec.Containers = []v1.Container{sidecar}
+	return only
+}
+
+// evaluateSidecars compares each native sidecar of pod against its own limit,
+// when Sidecars is set. They are left out of the pod otherwise.
+func (t terminator) evaluateSidecars(ctx context.Context, pod *v1.Pod, c *check) error {
+	if !t.options.Sidecars {
+		return nil
+	}
+	// restarting only a sidecar needs the exec action, which can't signal
+	// Windows containers
+	if _, ok := t.action.(execAction); ok {
+		windows, err := t.windowsPod(ctx, c, pod)
+		if err != nil {
+			return err
+		}
+		if windows {
+			t.log.Infof("skipping the sidecars of pod < %s >, its Windows containers can't be restarted alone", pod.Name)
+			return nil
+		}
+	}
+
+	for _, sidecar := range sidecars(pod) {
+		t.log.Infof("checking sidecar %s of pod < %s >", sidecar.Name, pod.Name)
+		if err := t.evaluatePod(ctx, sidecarPod(pod, sidecar), c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
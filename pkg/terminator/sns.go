@@ -0,0 +1,76 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+type snsNotifier struct {
+	client   *sns.Client
+	topicARN string
+}
+
+// NewSNSNotifier returns a Notifier publishing events as JSON to an SNS
+// topic, with credentials from the default AWS chain. Messages have a type
+// attribute, so subscriptions can filter the pod_killed events.
+func NewSNSNotifier(ctx context.Context, topicARN string) (Notifier, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snsNotifier{client: sns.NewFromConfig(cfg), topicARN: topicARN}, nil
+}
+
+func (n snsNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.client.Publish(ctx, &sns.PublishInput{
+		TopicArn: aws.String(n.topicARN),
+		Message:  aws.String(string(body)),
+		MessageAttributes: map[string]snstypes.MessageAttributeValue{
+			"type": {DataType: aws.String("String"), StringValue: aws.String(event.Type)},
+		},
+	})
+	return err
+}
+
+type sqsNotifier struct {
+	client   *sqs.Client
+	queueURL string
+}
+
+// NewSQSNotifier returns a Notifier sending events as JSON to an SQS queue,
+// with credentials from the default AWS chain and a type attribute.
+func NewSQSNotifier(ctx context.Context, queueURL string) (Notifier, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return sqsNotifier{client: sqs.NewFromConfig(cfg), queueURL: queueURL}, nil
+}
+
+func (n sqsNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = n.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(n.queueURL),
+		MessageBody: aws.String(string(body)),
+		MessageAttributes: map[string]sqstypes.MessageAttributeValue{
+			"type": {DataType: aws.String("String"), StringValue: aws.String(event.Type)},
+		},
+	})
+	return err
+}
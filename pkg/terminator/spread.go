@@ -0,0 +1,48 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// zoneLabel is the well-known label of the zone of nodes.
+const zoneLabel = "topology.kubernetes.io/zone"
+
+// failureDomain returns the zone of the node of pod, or the node itself when
+// it has no zone. Nodes are fetched once on each check.
+func (t terminator) failureDomain(ctx context.Context, c *check, pod *v1.Pod) (string, error) {
+	name := pod.Spec.NodeName
+	domain, err := c.failureDomains.get(name, func() (interface{}, error) {
+		node, err := t.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		if zone, ok := node.Labels[zoneLabel]; ok {
+			return zone, nil
+		}
+		return name, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return domain.(string), nil
+}
+
+// spreadDeferred tells whether the kill of pod in domain is deferred because
+// the last pod of its workload key was killed in the same failure domain,
+// while another one of its pods over the limit is in a different one.
+func spreadDeferred(c *check, pod *v1.Pod, key, domain string) bool {
+	if last, ok := c.state.lastKillDomains[key]; !ok || last != domain {
+		return false
+	}
+
+	for uid, over := range c.state.podsToKill {
+		if uid != pod.UID && over.workload == key && over.domain != "" && over.domain != domain {
+			return true
+		}
+	}
+	return false
+}
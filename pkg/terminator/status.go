@@ -0,0 +1,74 @@
+package terminator
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Status is the current state of a terminator, served by the status API.
+type Status struct {
+	Cluster string `json:"cluster,omitempty"`
+	Ready   bool   `json:"ready"`
+	// NamespaceKills are the kills of the last hour of every namespace with
+	// some
+	NamespaceKills []NamespaceKills `json:"namespaceKills"`
+}
+
+// NamespaceKills are the pods of a namespace killed in the last hour, out of
+// the MaxKillsPerNamespacePerHour limit.
+type NamespaceKills struct {
+	Namespace string `json:"namespace"`
+	Kills     int    `json:"kills"`
+	Limit     int    `json:"limit,omitempty"`
+}
+
+// namespaceKills are the kills of the last hour by namespace, shared by every
+// copy of the terminator so one namespace can't use the kills of the others.
+type namespaceKills struct {
+	mu    sync.Mutex
+	kills map[string][]time.Time
+}
+
+// count returns the kills of namespace in the hour before now.
+func (k *namespaceKills) count(namespace string, now time.Time) int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.kills[namespace] = recentKills(k.kills[namespace], time.Hour, now)
+	return len(k.kills[namespace])
+}
+
+// record counts a kill in namespace at now.
+func (k *namespaceKills) record(namespace string, now time.Time) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.kills[namespace] = append(recentKills(k.kills[namespace], time.Hour, now), now)
+}
+
+// namespaceRateLimited returns whether the pods of namespace were killed
+// MaxKillsPerNamespacePerHour times in the last hour.
+func (t terminator) namespaceRateLimited(namespace string, now time.Time) bool {
+	return t.options.MaxKillsPerNamespacePerHour > 0 && t.namespaceKills.count(namespace, now) >= t.options.MaxKillsPerNamespacePerHour
+}
+
+// Status returns the current state of the terminator.
+func (t terminator) Status() Status {
+	now := t.clock.Now()
+	status := Status{Cluster: t.options.Cluster, Ready: t.Ready(), NamespaceKills: []NamespaceKills{}}
+
+	t.namespaceKills.mu.Lock()
+	defer t.namespaceKills.mu.Unlock()
+	for namespace, kills := range t.namespaceKills.kills {
+		kills = recentKills(kills, time.Hour, now)
+		if len(kills) == 0 {
+			delete(t.namespaceKills.kills, namespace)
+			continue
+		}
+		t.namespaceKills.kills[namespace] = kills
+		status.NamespaceKills = append(status.NamespaceKills, NamespaceKills{Namespace: namespace, Kills: len(kills), Limit: t.options.MaxKillsPerNamespacePerHour})
+	}
+	sort.Slice(status.NamespaceKills, func(i, j int) bool {
+		return status.NamespaceKills[i].Namespace < status.NamespaceKills[j].Namespace
+	})
+	return status
+}
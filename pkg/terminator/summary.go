@@ -0,0 +1,45 @@
+package terminator
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// countError counts an error of the check that didn't fail it, like a call to
+// the API server that could not be made.
+func (c *check) countError() {
+	atomic.AddInt32(&c.errors, 1)
+}
+
+// countOver counts a pod over the limit on the check.
+func (c *check) countOver() {
+	atomic.AddInt32(&c.over, 1)
+}
+
+// summarize prints the single line summing up the check c of pods, which
+// started at started, and logs it with its fields.
+func (t terminator) summarize(c *check, pods int, started time.Time) {
+	kills := 0
+	if c.killed {
+		kills = 1
+	}
+	errors := int(atomic.LoadInt32(&c.errors)) + c.metricsFailures
+	over := atomic.LoadInt32(&c.over)
+	duration := t.clock.Now().Sub(started).Round(time.Millisecond)
+
+	dryRun := ""
+	if c.killed && c.killedDryRun {
+		dryRun = " (dry run)"
+	}
+	t.out.Printf("Check: %d pods, %d over the limit, %d killed%s, %d errors in %s", pods, over, kills, dryRun, errors, duration)
+	t.log.WithFields(logrus.Fields{
+		"pods":     pods,
+		"over":     over,
+		"kills":    kills,
+		"dryRun":   c.killedDryRun,
+		"errors":   errors,
+		"duration": duration.String(),
+	}).Info("check done")
+}
@@ -0,0 +1,26 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// podSwap returns the swap used by pod in bytes, read from the kubelet of its
+// node. It is zero on nodes without swap.
+func (t terminator) podSwap(ctx context.Context, c *check, pod *v1.Pod) (int64, error) {
+	if pod.Spec.NodeName == "" {
+		return 0, nil
+	}
+
+	stats, err := t.nodeStats(ctx, c, pod.Spec.NodeName)
+	if err != nil {
+		return 0, err
+	}
+
+	podStats, ok := stats[pod.Namespace+"/"+pod.Name]
+	if !ok || podStats.Swap == nil || podStats.Swap.SwapUsageBytes == nil {
+		return 0, nil
+	}
+	return int64(*podStats.Swap.SwapUsageBytes), nil
+}
@@ -0,0 +1,70 @@
+package terminator
+
+import "fmt"
+
+// Tenant is a team owning some namespaces, whose policies only apply to pods of
+// those namespaces, so the policies of the team can be contributed without
+// reviewing which pods they select.
+type Tenant struct {
+	Name       string            `json:"name"`
+	Namespaces []string          `json:"namespaces"`
+	Policies   []NamespacePolicy `json:"policies,omitempty"`
+	// Assignees are assigned the issues opened for the escalations of the
+	// workloads of the tenant, as GitHub logins or Jira account ids
+	Assignees []string `json:"assignees,omitempty"`
+}
+
+// addTenants appends the policies of the tenants of file to its namespace
+// policies, restricted to the namespaces of their tenant. A tenant can't own
+// a namespace of another tenant nor set the policy of a namespace by name
+// outside of its own.
+func (file *PolicyFile) addTenants(path string) error {
+	owners := make(map[string]string)
+	for _, tenant := range file.Tenants {
+		if tenant.Name == "" {
+			return fmt.Errorf("invalid policy file %s: tenant without name", path)
+		}
+		if len(tenant.Namespaces) == 0 {
+			return fmt.Errorf("invalid policy file %s: tenant %s has no namespaces", path, tenant.Name)
+		}
+
+		owned := make(map[string]bool, len(tenant.Namespaces))
+		for _, namespace := range tenant.Namespaces {
+			if owner, ok := owners[namespace]; ok {
+				return fmt.Errorf("invalid policy file %s: namespace %s is owned by tenants %s and %s", path, namespace, owner, tenant.Name)
+			}
+			owners[namespace] = tenant.Name
+			owned[namespace] = true
+		}
+
+		for _, namespacePolicy := range tenant.Policies {
+			if namespacePolicy.Name != "" && !owned[namespacePolicy.Name] {
+				return fmt.Errorf("invalid policy file %s: tenant %s can't set the policy of namespace %s", path, tenant.Name, namespacePolicy.Name)
+			}
+			namespacePolicy.tenant, namespacePolicy.owned = tenant.Name, owned
+			file.Namespaces = append(file.Namespaces, namespacePolicy)
+		}
+	}
+	return nil
+}
+
+// applies tells whether namespacePolicy can select pods of namespace, which is
+// any namespace unless it is the policy of a tenant.
+func (namespacePolicy *NamespacePolicy) applies(namespace string) bool {
+	return namespacePolicy.owned == nil || namespacePolicy.owned[namespace]
+}
+
+// tenant returns the tenant owning namespace, or nil when it has none.
+func (file *PolicyFile) tenant(namespace string) *Tenant {
+	if file == nil {
+		return nil
+	}
+	for i := range file.Tenants {
+		for _, owned := range file.Tenants[i].Namespaces {
+			if owned == namespace {
+				return &file.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1855 @@
+// Package terminator evaluates the memory usage of pods and kills the ones
+// over their limit, so other tools can embed it.
+package terminator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
+	metrics "k8s.io/metrics/pkg/client/clientset/versioned"
+	"k8s.io/metrics/pkg/client/custom_metrics"
+	"k8s.io/metrics/pkg/client/external_metrics"
+)
+
+// Terminator checks the pods of a cluster.
+type Terminator interface {
+	Terminate(ctx context.Context, targets Targets, memoryLimit, killAfter int, sleep, killSleep time.Duration) error
+	// TerminateNamespaces checks each namespace of intervals concurrently,
+	// sleeping its own interval between checks and sharing MaxKills
+	TerminateNamespaces(ctx context.Context, targets Targets, intervals map[string]time.Duration, memoryLimit, killAfter int, killSleep time.Duration) error
+	Analyze(ctx context.Context, targets Targets, analysis Analysis) error
+	// Record writes the targeted pods and their memory usage to w on every
+	// check, to be replayed by Replay
+	Record(ctx context.Context, targets Targets, sleep time.Duration, w io.Writer) error
+	// Explain returns how the pod namespace/name is evaluated, without
+	// killing it
+	Explain(ctx context.Context, targets Targets, namespace, name string, memoryLimit, killAfter int) (*Explanation, error)
+	// Ready tells whether the checks are not failing for more than the
+	// ErrorBudget
+	Ready() bool
+	// Status returns the current state of the terminator
+	Status() Status
+	// SetPolicies replaces the policy file from the next check on
+	SetPolicies(policies *PolicyFile)
+	// Dump logs the watched pods, the over limit counters and the effective
+	// configuration once the running check is done
+	Dump()
+}
+
+// Targets are where pods are looked for. Without services, deployments or
+// statefulsets every pod of Namespace is a target.
+type Targets struct {
+	Namespace    string
+	Services     []string
+	Deployments  []string
+	StatefulSets []string
+	// Pods are pods by name, like the one of the terminator itself
+	Pods []string
+	// Nodes and NodeSelector limit the pods to the ones on those nodes, the
+	// union of both when both are set
+	Nodes        []string
+	NodeSelector string
+}
+
+func (t Targets) explicit() bool {
+	return len(t.Services) > 0 || len(t.Deployments) > 0 || len(t.StatefulSets) > 0 || len(t.Pods) > 0
+}
+
+func (t Targets) String() string {
+	var s string
+	if t.Namespace != "" {
+		s += fmt.Sprintf(" at namespace %s", t.Namespace)
+	}
+	if len(t.Services) > 0 {
+		s += fmt.Sprintf(" by services: %s", t.Services)
+	}
+	if len(t.Deployments) > 0 {
+		s += fmt.Sprintf(" by deployments: %s", t.Deployments)
+	}
+	if len(t.StatefulSets) > 0 {
+		s += fmt.Sprintf(" by statefulsets: %s", t.StatefulSets)
+	}
+	if len(t.Pods) > 0 {
+		s += fmt.Sprintf(" by pods: %s", t.Pods)
+	}
+	if len(t.Nodes) > 0 {
+		s += fmt.Sprintf(" on nodes: %s", t.Nodes)
+	}
+	if t.NodeSelector != "" {
+		s += fmt.Sprintf(" on nodes matching: %s", t.NodeSelector)
+	}
+	return s
+}
+
+// Options configures how pods are evaluated and killed.
+type Options struct {
+	// Cluster is the name of the cluster in logs and metrics
+	Cluster string
+	DryRun  bool
+	// PlanFile is the path of a JSON Plan of the pods the dry run would have
+	// killed, rewritten on each of them
+	PlanFile string
+	// Workers is the amount of pods evaluated concurrently
+	Workers int
+	// SelectorTTL is how long resolved target selectors are cached
+	SelectorTTL time.Duration
+	// MetricsSource is where the memory usage of pods comes from, unless there
+	// is a metrics provider: metrics-server, the default, cadvisor, scraping
+	// the nodes matching CAdvisorNodeSelector, datadog, cloudwatch or gcm
+	MetricsSource        string
+	CAdvisorNodeSelector string
+	// MaxMetricsAge is the age from which the metrics of metrics-server are
+	// stale, skipping the pod, zero disables it
+	MaxMetricsAge time.Duration
+	Datadog       DatadogOptions
+	CloudWatch    CloudWatchOptions
+	GCM           GCMOptions
+	// MemoryMetric is the memory figure of pods compared against their limit:
+	// working_set, the default, rss or usage
+	MemoryMetric string
+	// NoLimitBasis is what pods without a memory limit are compared against,
+	// empty means nothing
+	NoLimitBasis string
+	// NoLimitAction is what happens to pods that still have no memory limit
+	// after NoLimitBasis
+	NoLimitAction string
+	// AbsoluteLimit is the limit used for pods without one when NoLimitAction
+	// is use-absolute
+	AbsoluteLimit *resource.Quantity
+	// MaxRestarts is the restart count from which a container restarted
+	// recently and not OOMKilled is considered crash looping, zero only
+	// considers CrashLoopBackOff
+	MaxRestarts int32
+	// ActiveHours are when pods can be killed, nil means always. Pods are still
+	// evaluated outside of them
+	ActiveHours *Schedule
+	// Blackout are when no pod is killed, on top of the blackouts annotated on
+	// namespaces. With ReportBlackout, the kills that were deferred by a
+	// blackout are notified once it is over
+	Blackout       *Schedule
+	ReportBlackout bool
+	// Location is the timezone of the annotated blackouts
+	Location *time.Location
+	// Cooldown is the minimum time between kills of pods of the same workload
+	Cooldown time.Duration
+	// RepeatWindow is how long the kills of a workload are remembered to back
+	// off: each of them doubles its kill-after and cooldown, where the cooldown
+	// is at least RepeatCooldown. Zero disables the backoff. Once there were
+	// MaxRepeatKills, its pods are not killed anymore and it is notified,
+	// unless MaxRepeatKills is zero
+	RepeatWindow   time.Duration
+	RepeatCooldown time.Duration
+	MaxRepeatKills int
+	// LimitHeadroom is the percentage added to the p99 of the usage of the
+	// pods of a repeat offender suggested as their limit
+	LimitHeadroom int
+	// GPULimit is the GPU memory usage percentage from which pods are over the
+	// limit too, zero disables it. It needs a GPU provider
+	GPULimit int
+	// GPUService is the namespace/name of the dcgm-exporter service the GPU
+	// memory of pods is read from, unless there is a GPU provider
+	GPUService string
+	// PIDLimit is the percentage of the pids limit of pods from which they
+	// are over the limit too, zero disables it. The amount of processes and
+	// the limit are read from the kubelets, PodPidsLimit being the limit when
+	// a kubelet can't tell
+	PIDLimit     int
+	PodPidsLimit int64
+	// CountSwap adds the swap used by pods to their memory usage, and pods
+	// using at least SwapLimit of swap are over the limit too, when set. Swap
+	// is read from the kubelets, on nodes with swap since Kubernetes 1.28
+	CountSwap bool
+	SwapLimit *resource.Quantity
+	// CustomMetrics are metrics of pods from the custom metrics API, from
+	// which pods are over the limit too
+	CustomMetrics []CustomMetric
+	// ExternalMetrics are metrics from the external metrics API available to
+	// conditions
+	ExternalMetrics []ExternalMetric
+	// StateConfigMap is the namespace/name of the ConfigMap keeping the over
+	// limit counters across restarts, unless there is a state store
+	StateConfigMap string
+	// ExportURL is where the samples and decisions of each check are written
+	// as CSV, unless there is an exporter, see NewExporter
+	ExportURL string
+	// Iterations is the amount of checks after which Terminate returns nil,
+	// zero never stops
+	Iterations int
+	// MaxKills is the amount of kills after which Terminate stops with
+	// ErrMaxKills, zero never stops
+	MaxKills int
+	// ShutdownTimeout is how long the check running when the context is done
+	// has to finish, zero stops it right away
+	ShutdownTimeout time.Duration
+	// Condition decides whether pods are killed instead of the limit and
+	// kill-after checks, when set
+	Condition *Condition
+	// Query is a PromQL expression evaluated for each pod by the Prometheus
+	// server at PrometheusURL, with $pod and $namespace replaced by its name
+	// and namespace. A pod is over the limit too when it has a non zero value
+	Query         string
+	PrometheusURL string
+	// Aggregation is how the memory usage percentages of the last Window are
+	// compared against the limit: last, avg, max or a percentile like p95.
+	// Zero Window only compares the last one
+	Aggregation string
+	Window      time.Duration
+	// MaxTrackedPods bounds the pods whose samples of the last Window are
+	// kept, forgetting the least recently sampled ones above it, zero doesn't
+	// bound them
+	MaxTrackedPods int
+	// MetadataOnly caches only the metadata of pods instead of the whole pods,
+	// fetching the ones using at least CandidatePercentage of their limit
+	// from the API server on each check, so the terminator uses much less
+	// memory on big clusters. OOMKilled containers aren't watched with it,
+	// crash loops are only told by the CrashLoopBackOff of the fetched pods
+	// and MaxRestarts can't be set
+	MetadataOnly bool
+	// TwoPhase only evaluates the pods using at least CandidatePercentage of
+	// their limit by their listed metrics, skipping the lookups of the
+	// policies, protections and owners of the others. Thresholds other than
+	// the memory limit only apply to them
+	TwoPhase            bool
+	CandidatePercentage int
+	// Containers are the containers of pods whose memory is compared against
+	// their limits, the ones of a pod without any of them are all compared.
+	// Empty compares all of them
+	Containers []string
+	// Scope is ScopeCluster, the default, or ScopeNamespace to only call
+	// namespaced APIs
+	Scope string
+	// MaxDisruptions caps the kills of pods of each workload within a window,
+	// independently of its PodDisruptionBudgets, nil doesn't cap them
+	MaxDisruptions *DisruptionBudget
+	// SpreadKills never kills two pods of a workload in the same zone, or node
+	// without zones, in a row while some other pod of it over the limit is in
+	// another one
+	SpreadKills bool
+	// NodePressure is how pods on nodes under memory pressure are handled,
+	// NodePressurePrioritize or NodePressurePause, empty ignores it
+	NodePressure string
+	// OpenCostURL is the address of an OpenCost server adding the cost of
+	// workloads to the notifications of kills and the leak reports
+	OpenCostURL string
+	// HeartbeatURL is pinged with a GET after each successful check, like a
+	// healthchecks.io check, with {cluster} replaced by the Cluster
+	HeartbeatURL string
+	// WorkloadMetrics publishes the pods of each workload and the ones over
+	// the limit on every check, for an HPA to scale out workloads before
+	// their pods are killed
+	WorkloadMetrics bool
+	// ScaleHPAs raises the minimum replicas of the HPA of the workload of a
+	// pod to one over its current replicas instead of killing it, unless the
+	// HPA is at its maximum replicas or was scaled up within ScaleCooldown.
+	// Pods of a workload whose scale up is still pending aren't killed. The
+	// minimum replicas are restored once ScaleCooldown passes, on the next
+	// check when zero
+	ScaleHPAs     bool
+	ScaleCooldown time.Duration
+	// KillAction is KillActionDelete, the default, or KillActionExec to send
+	// KillSignal, TERM, INT or QUIT, to the PID 1 of the container of the pod
+	// using the most of its limit, restarting only that container. A kill only
+	// succeeds once the container restarted. An action set by an option
+	// replaces it
+	KillAction string
+	KillSignal string
+	// Sidecars compares each native sidecar of pods against its own limit,
+	// restarting only the sidecar when it is killed, which needs an action
+	// killing single containers. They are never part of the pod
+	Sidecars bool
+	// Quiet only prints the kills and the errors
+	Quiet bool
+	// Color colors the kills, errors and pods over the limit in the output
+	Color bool
+	// OutputUnits are the UnitsBinary, the default, UnitsDecimal or UnitsBytes
+	// of the memory in the output
+	OutputUnits string
+	// PercentagePrecision are the decimals of the percentages in the output
+	PercentagePrecision int
+	// Degrade keeps checking when the metrics of pods can't be fetched,
+	// without killing them nor forgetting the ones over the limit, instead of
+	// failing the check
+	Degrade bool
+	// ErrorBudget are the checks that can fail in a row, being retried after
+	// the sleep, before it is notified and the terminator isn't ready, 0 stops
+	// on the first failure
+	ErrorBudget int
+	// AnnotateWorkloads records the kills of pods on their deployments and
+	// statefulsets
+	AnnotateWorkloads bool
+	// IncludeBarePods allows killing pods without controllers, which are not
+	// recreated
+	IncludeBarePods bool
+	// MaxPriority protects the pods with a higher priority from being killed,
+	// nil kills pods of any priority
+	MaxPriority *int32
+	// MaxKillsPerNamespacePerHour caps the kills of pods of each namespace in
+	// the last hour, so a misbehaving namespace can't use all the MaxKills,
+	// zero doesn't cap them
+	MaxKillsPerNamespacePerHour int
+	// QOSClasses are the QoS classes of the pods that can be killed, like
+	// BestEffort and Burstable to never kill Guaranteed pods. Empty kills
+	// pods of any class
+	QOSClasses []string
+	// SystemPolicies are applied below Policies, protecting the system
+	// namespaces by default
+	SystemPolicies *PolicyFile
+	// Policies override the thresholds of pods by namespace and workload
+	Policies *PolicyFile
+}
+
+const (
+	NoLimitBasisNodeAllocatable = "node-allocatable"
+
+	NoLimitActionSkip        = "skip"
+	NoLimitActionWarn        = "warn"
+	NoLimitActionUseAbsolute = "use-absolute"
+)
+
+func (o Options) validate() error {
+	if (o.MetadataOnly || o.TwoPhase) && (o.CandidatePercentage < 0 || o.CandidatePercentage > 100) {
+		return fmt.Errorf("candidate-percentage must be between 0 and 100, got %d", o.CandidatePercentage)
+	}
+
+	// the restarted replicas of a workload are rarely fetched, using little
+	// memory, so their siblings would be killed anyway
+	if o.MetadataOnly && o.MaxRestarts > 0 {
+		return fmt.Errorf("max-restarts can't be used with metadata-only, which only sees the restarts of the pods it fetches")
+	}
+
+	if o.MaxKillsPerNamespacePerHour < 0 {
+		return fmt.Errorf("max-kills-per-namespace-per-hour must be at least 0, got %d", o.MaxKillsPerNamespacePerHour)
+	}
+
+	if o.LimitHeadroom < 0 {
+		return fmt.Errorf("limit-headroom must be at least 0, got %d", o.LimitHeadroom)
+	}
+
+	if o.MaxTrackedPods < 0 {
+		return fmt.Errorf("max-tracked-pods must be at least 0, got %d", o.MaxTrackedPods)
+	}
+
+	if o.Workers < 1 {
+		return fmt.Errorf("workers must be at least 1, got %d", o.Workers)
+	}
+
+	for _, class := range o.QOSClasses {
+		switch v1.PodQOSClass(class) {
+		case v1.PodQOSGuaranteed, v1.PodQOSBurstable, v1.PodQOSBestEffort:
+		default:
+			return fmt.Errorf("invalid qos class %q, must be Guaranteed, Burstable or BestEffort", class)
+		}
+	}
+
+	switch o.KillAction {
+	case "", KillActionDelete:
+	case KillActionExec:
+		switch o.KillSignal {
+		case "TERM", "INT", "QUIT":
+		case "KILL":
+			return fmt.Errorf("invalid kill-signal KILL, the kernel ignores it on PID 1 from inside its container")
+		default:
+			return fmt.Errorf("invalid kill-signal %q, must be TERM, INT or QUIT", o.KillSignal)
+		}
+	default:
+		return fmt.Errorf("invalid kill-action %q, must be delete or exec", o.KillAction)
+	}
+
+	if err := validUnits(o.OutputUnits); err != nil {
+		return err
+	}
+	if o.PercentagePrecision < 0 {
+		return fmt.Errorf("percentage-precision can't be negative, got %d", o.PercentagePrecision)
+	}
+
+	if o.ErrorBudget < 0 {
+		return fmt.Errorf("error-budget can't be negative, got %d", o.ErrorBudget)
+	}
+
+	if err := validateAggregation(o.Aggregation); err != nil {
+		return err
+	}
+	if o.Aggregation != "" && o.Aggregation != AggregationLast && o.Window <= 0 {
+		return fmt.Errorf("aggregation %s needs a window", o.Aggregation)
+	}
+
+	if o.StateConfigMap != "" {
+		if namespace, name := splitNamespacedName(o.StateConfigMap); namespace == "" || name == "" {
+			return fmt.Errorf("invalid state-configmap %q, must be namespace/name", o.StateConfigMap)
+		}
+	}
+
+	if o.GPUService != "" {
+		if namespace, name := splitNamespacedName(o.GPUService); namespace == "" || name == "" {
+			return fmt.Errorf("invalid gpu-dcgm-service %q, must be namespace/name", o.GPUService)
+		}
+	}
+
+	if err := validMemoryMetric(o.MemoryMetric); err != nil {
+		return err
+	}
+
+	if err := validNodePressure(o.NodePressure); err != nil {
+		return err
+	}
+
+	if err := o.validateScope(); err != nil {
+		return err
+	}
+
+	switch o.MetricsSource {
+	case "", MetricsSourceMetricsServer:
+	case MetricsSourceCAdvisor:
+		if _, err := labels.Parse(o.CAdvisorNodeSelector); err != nil {
+			return fmt.Errorf("invalid cadvisor-node-selector %q: %w", o.CAdvisorNodeSelector, err)
+		}
+	case MetricsSourceDatadog:
+		if o.Datadog.APIKey == "" || o.Datadog.AppKey == "" {
+			return fmt.Errorf("metrics-source %s requires an API key and an application key", o.MetricsSource)
+		}
+	case MetricsSourceCloudWatch:
+		if o.CloudWatch.ClusterName == "" {
+			return fmt.Errorf("metrics-source %s requires a cluster name", o.MetricsSource)
+		}
+	case MetricsSourceGCM:
+		if o.GCM.Project == "" {
+			return fmt.Errorf("metrics-source %s requires a project", o.MetricsSource)
+		}
+	default:
+		return fmt.Errorf("invalid metrics-source %q", o.MetricsSource)
+	}
+
+	if o.NoLimitBasis != "" && o.NoLimitBasis != NoLimitBasisNodeAllocatable {
+		return fmt.Errorf("invalid no-limit-basis %q", o.NoLimitBasis)
+	}
+
+	switch o.NoLimitAction {
+	case "", NoLimitActionSkip, NoLimitActionWarn:
+	case NoLimitActionUseAbsolute:
+		if o.AbsoluteLimit == nil || o.AbsoluteLimit.IsZero() {
+			return fmt.Errorf("no-limit-action %s requires an absolute limit", o.NoLimitAction)
+		}
+	default:
+		return fmt.Errorf("invalid no-limit-action %q", o.NoLimitAction)
+	}
+
+	return nil
+}
+
+type terminator struct {
+	clientset kubernetes.Interface
+	provider  MetricsProvider
+	action    Action
+	notifier  Notifier
+	guard     Guard
+	clock     Clock
+	store     StateStore
+	exporter  Exporter
+	gpu       GPUProvider
+	// prometheus evaluates the queries of policies, when there is a
+	// PrometheusURL
+	prometheus promv1.API
+	opencost   *openCost
+	heartbeat  *heartbeat
+	plan       *planFile
+	selectors  *selectorCache
+	options    Options
+	live       *live
+	health     *health
+	format     formatter
+
+	// namespaceKills are the kills of the last hour by namespace, for
+	// MaxKillsPerNamespacePerHour and the status
+	namespaceKills *namespaceKills
+
+	// reporter gets the operational errors and panics, reported once every
+	// errorReportInterval by errorHistory
+	reporter     ErrorReporter
+	errorHistory *errorHistory
+
+	// customProvider tells whether provider was set by an option, so it reads
+	// the MemoryMetric by itself
+	customProvider        bool
+	customMetricsClient   custom_metrics.CustomMetricsClient
+	externalMetricsClient external_metrics.ExternalMetricsClient
+	metadata              metadata.Interface
+
+	// log is for debugging and out for what is always printed, both with the
+	// name of the cluster when there is one
+	log *logrus.Entry
+	out output
+}
+
+// New returns a Terminator for the cluster of config. Pods are deleted based on
+// their usage from metrics-server unless replaced by opts.
+func New(config *rest.Config, options Options, opts ...Option) (Terminator, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mc, err := metrics.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	em, err := external_metrics.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the options come last so they can still replace the clients
+	clients := []Option{
+		WithCustomMetricsClient(newCustomMetricsClient(config, clientset.Discovery())),
+		WithExternalMetricsClient(em),
+	}
+	if options.MetadataOnly {
+		mdc, err := metadata.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, WithMetadataClient(mdc))
+	}
+	if options.KillAction == KillActionExec {
+		clients = append(clients, WithAction(NewExecAction(config, clientset, mc, options.KillSignal)))
+	}
+	return NewForClients(clientset, mc, options, append(clients, opts...)...)
+}
+
+// NewForClients is like New with pre-built clients, like fakes or clients with
+// a wrapped transport.
+func NewForClients(clientset kubernetes.Interface, mc metrics.Interface, options Options, opts ...Option) (Terminator, error) {
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	if options.Location == nil {
+		options.Location = time.Local
+	}
+
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	out := log.Default()
+	if options.Cluster != "" {
+		logger = logger.WithField("cluster", options.Cluster)
+		out = log.New(log.Writer(), "["+options.Cluster+"] ", log.Flags())
+	}
+
+	t := &terminator{
+		clientset: clientset,
+		action:    NewDeleteAction(clientset),
+		clock:     realClock{},
+		selectors: newSelectorCache(options.SelectorTTL),
+		options:   options,
+		live:      &live{policies: options.Policies, dumps: make(chan struct{}, 1)},
+		health:    &health{},
+		format:    formatter{units: options.OutputUnits, precision: options.PercentagePrecision},
+		log:       logger,
+		out:       output{logger: out, quiet: options.Quiet, color: options.Color},
+	}
+	t.namespaceKills = &namespaceKills{kills: make(map[string][]time.Time)}
+	t.errorHistory = &errorHistory{reported: make(map[string]time.Time), repeats: make(map[string]int)}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	if options.MetadataOnly && t.metadata == nil {
+		return nil, fmt.Errorf("metadata-only needs a metadata client")
+	}
+
+	if _, ok := t.action.(ContainerAction); options.Sidecars && !ok {
+		return nil, fmt.Errorf("sidecars requires a kill-action restarting containers, like %s", KillActionExec)
+	}
+
+	if !t.customProvider {
+		// metrics-server is created once the clock is set, to tell stale
+		// metrics by it
+		provider, err := sourceProvider(context.Background(), options, clientset, NewMetricsServerProvider(mc, options.MaxMetricsAge, t.clock))
+		if err != nil {
+			return nil, err
+		}
+		t.provider = provider
+	}
+
+	if t.gpu == nil && options.GPUService != "" {
+		namespace, name := splitNamespacedName(options.GPUService)
+		t.gpu = NewDCGMProvider(clientset, namespace, name)
+	}
+
+	if t.exporter == nil && options.ExportURL != "" {
+		exporter, err := NewExporter(context.Background(), options.ExportURL)
+		if err != nil {
+			return nil, err
+		}
+		t.exporter = exporter
+	}
+
+	if options.OpenCostURL != "" {
+		t.opencost = newOpenCost(options.OpenCostURL)
+	}
+
+	if options.HeartbeatURL != "" {
+		t.heartbeat = newHeartbeat(options.HeartbeatURL, options.Cluster)
+	}
+
+	if options.PlanFile != "" {
+		plan, err := openPlan(options.PlanFile, t.clock.Now())
+		if err != nil {
+			return nil, err
+		}
+		t.plan = plan
+	}
+
+	if options.PrometheusURL != "" {
+		prometheus, err := newPrometheusAPI(options.PrometheusURL)
+		if err != nil {
+			return nil, err
+		}
+		t.prometheus = prometheus
+	}
+
+	if t.store == nil && options.StateConfigMap != "" {
+		namespace, name := splitNamespacedName(options.StateConfigMap)
+		key := options.Cluster
+		if key == "" {
+			key = "default"
+		}
+		t.store = NewConfigMapStore(clientset, namespace, name, key)
+	}
+	return *t, nil
+}
+
+type overLimit struct {
+	namespace string
+	name      string
+	// workload is the key of the workload of the pod
+	workload string
+	// domain is the failure domain of the pod, with SpreadKills
+	domain string
+	at     time.Time
+	count  int
+}
+
+// state is kept between checks.
+type state struct {
+	// podsToKill are the pods over the limit, by uid
+	podsToKill map[types.UID]*overLimit
+	// pausedWorkloads are the crash looping workloads already alerted about
+	pausedWorkloads map[string]bool
+	// deferredKills are the kills deferred by a blackout, by pod
+	deferredKills map[string]Event
+	// degradedSince is when the metrics became unavailable, zero while they
+	// are available
+	degradedSince time.Time
+	// lastKills are when a pod of each workload was last killed
+	lastKills map[string]time.Time
+	// disruptions are the kills of the window of MaxDisruptions by workload
+	disruptions map[string][]time.Time
+	// lastKillDomains are the failure domains where a pod of each workload
+	// was last killed
+	lastKillDomains map[string]string
+	// repeatKills are the recent kills by workload
+	repeatKills map[string]*repeatKills
+	// watched are the pods found on the last check
+	watched []string
+	// saved are the counters last saved to the state store
+	saved map[string]OverLimit
+	// samples are the memory usage percentages of the last Window, by pod uid,
+	// and sampled when they were last added to
+	samples map[string][]percentageSample
+	sampled *lru
+	// budget are the pods killed since Terminate started, shared by the
+	// namespaces of TerminateNamespaces
+	budget *budget
+	// outcomes are the OOMs prevented and missed by namespace and workload
+	outcomes map[string]*outcomes
+	// shapes are the containers and nodes of the pods listed by their
+	// metadata on the last check, by uid
+	shapes map[types.UID]*v1.Pod
+	// workloads are the workloads whose metrics were published on the last
+	// check, with WorkloadMetrics
+	workloads map[string]*workloadCount
+	// windowsNodes tell whether each node looked up runs Windows
+	windowsNodes map[string]bool
+	// unsignaled are the workloads of Windows pods already alerted about not
+	// being killed by the exec action
+	unsignaled map[string]bool
+	// scaledUp are the last scale ups of the HPA of each workload, with
+	// ScaleHPAs
+	scaledUp map[string]hpaScaleUp
+}
+
+func newState() *state {
+	return &state{
+		podsToKill:      make(map[types.UID]*overLimit),
+		pausedWorkloads: make(map[string]bool),
+		deferredKills:   make(map[string]Event),
+		lastKills:       make(map[string]time.Time),
+		lastKillDomains: make(map[string]string),
+		disruptions:     make(map[string][]time.Time),
+		repeatKills:     make(map[string]*repeatKills),
+		samples:         make(map[string][]percentageSample),
+		sampled:         newLRU(),
+		budget:          new(budget),
+		outcomes:        make(map[string]*outcomes),
+		windowsNodes:    make(map[string]bool),
+		unsignaled:      make(map[string]bool),
+		scaledUp:        make(map[string]hpaScaleUp),
+	}
+}
+
+// check holds the state shared by the workers evaluating the pods of a single
+// check. Decisions go through mu so only one pod is killed per check.
+type check struct {
+	mu     sync.Mutex
+	state  *state
+	killed bool
+	// killedUID is the pod killed on the check, killedDryRun telling whether
+	// it was only logged
+	killedUID    types.UID
+	killedDryRun bool
+	// records are the samples of the pods for the exporter
+	records      []Record
+	crashLooping map[string]bool
+	// defaults is the policy of pods not overridden by the policy file
+	defaults  policy
+	policies  *PolicyFile
+	killSleep time.Duration
+	// pressuredNodes are the nodes under memory pressure, with a NodePressure
+	// mode
+	pressuredNodes map[string]bool
+	// errors are the errors that didn't fail the check and over the pods over
+	// the limit, counted atomically for the summary
+	errors int32
+	over   int32
+	// metricsOK and metricsFailures are the pods whose metrics could and
+	// couldn't be fetched, with Degrade, and metricsErr the last failure
+	metricsOK       int
+	metricsFailures int
+	metricsErr      error
+	// workloads are the pods evaluated by workload, with WorkloadMetrics
+	workloads map[string]*workloadCount
+
+	// defaultsMu guards the counters of the metrics fetched and the os of
+	// the nodes kept in the state
+	defaultsMu sync.Mutex
+	// the lookups of the check are fetched once by key, each key with a lock
+	// of its own so the workers don't wait on the fetches of the others
+	defaultLimits   lookups
+	nodeAllocatable lookups
+	failureDomains  lookups
+	namespaces      lookups
+	rollouts        lookups
+	hpas            lookups
+	windowsNodes    lookups
+	nodeStats       lookups
+	nodePidsLimits  lookups
+	// customMetrics are the values of the custom metrics of the pods of a
+	// namespace, by namespace/metric and then by pod
+	customMetrics lookups
+	// externalMetrics are the values of the external metrics, by namespace
+	// and then by metric
+	externalMetrics lookups
+	// gpu is the GPU memory usage of pods, by namespace/name
+	gpu map[string]GPUUsage
+}
+
+func (t terminator) Terminate(ctx context.Context, targets Targets, memoryLimit, killAfter int, sleep, killSleep time.Duration) error {
+	return t.terminate(ctx, targets, memoryLimit, killAfter, sleep, killSleep, new(budget))
+}
+
+// terminate checks the pods of targets until ctx is done, with the kills
+// counted in budget.
+func (t terminator) terminate(ctx context.Context, targets Targets, memoryLimit, killAfter int, sleep, killSleep time.Duration, budget *budget) error {
+	defer t.reportPanic()
+	defaults := t.defaultPolicy(memoryLimit, killAfter)
+	watcher, err := t.watchTargets(ctx, targets)
+	if err != nil {
+		return err
+	}
+
+	state := newState()
+	state.budget = budget
+	if err := t.restoreState(ctx, state); err != nil {
+		return err
+	}
+
+	dump := func() {
+		t.dump(state, targets, defaults, sleep, killSleep)
+	}
+	for checks := 1; ; checks++ {
+		// a check that already started is finished even when ctx is done, so
+		// no pod is left half decided
+		checkCtx, cancel := drainContext(ctx, t.options.ShutdownTimeout)
+		err := t.runCheck(checkCtx, watcher, targets, state, defaults, killSleep)
+		if err == nil {
+			t.persistState(checkCtx, state)
+			t.checkSucceeded(checkCtx)
+			t.beat(checkCtx)
+		} else if ctx.Err() == nil {
+			t.reportError(checkCtx, "check failed", ErrorReport{Level: LevelError, Message: "check failed: " + err.Error()})
+			if t.checkFailed(checkCtx, err) {
+				err = nil
+			}
+		}
+		cancel()
+		if err == nil && t.options.MaxKills > 0 && state.budget.spent() >= t.options.MaxKills {
+			t.out.Printf("Stopping after %d kills", state.budget.spent())
+			t.shutdown(state)
+			return ErrMaxKills
+		}
+		if err == nil && checks == t.options.Iterations {
+			t.shutdown(state)
+			return nil
+		}
+		if err == nil {
+			err = t.wait(ctx, sleep, dump)
+		}
+
+		if ctx.Err() != nil {
+			t.shutdown(state)
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// defaultPolicy is the policy of pods not overridden by the policy file.
+func (t terminator) defaultPolicy(memoryLimit, killAfter int) policy {
+	defaults := policy{limit: memoryLimit, killAfter: killAfter, cooldown: t.options.Cooldown, condition: t.options.Condition, query: t.options.Query}
+	defaults.provenance = defaults.flagSources()
+	return defaults
+}
+
+// runCheck evaluates the targeted pods once.
+func (t terminator) runCheck(ctx context.Context, watcher *targetWatcher, targets Targets, state *state, defaults policy, killSleep time.Duration) error {
+	started := t.clock.Now()
+	pods, err := t.getPods(ctx, watcher, targets)
+	if err != nil {
+		return err
+	}
+
+	t.log.Infof("found %d pods", len(pods.Items))
+	pods.Items = t.skipTerminating(pods.Items, state)
+	state.watched = state.watched[:0]
+	for _, pod := range pods.Items {
+		state.watched = append(state.watched, pod.Namespace+"/"+pod.Name)
+	}
+
+	if p, ok := t.provider.(prefetcher); ok {
+		if err := p.prefetch(ctx, pods.Items, t.options.Workers); err != nil {
+			t.log.Errorf("could not list the metrics of pods, getting them one by one: %s", err)
+		}
+	}
+	// crash looping replicas just restarted use little memory, so they are
+	// counted before being dropped by the shortlist. Pods listed by their
+	// metadata have no status, so only the fetched ones are counted then
+	crashLooping := crashLoopingWorkloads(pods.Items, t.options.MaxRestarts, t.clock.Now())
+	if watcher.metadata != nil {
+		pods.Items, err = t.shortlist(ctx, state, targets, pods.Items)
+		crashLooping = crashLoopingWorkloads(pods.Items, t.options.MaxRestarts, t.clock.Now())
+	} else if t.options.TwoPhase {
+		pods.Items, err = t.shortlistCached(ctx, pods.Items)
+	}
+	if err != nil {
+		return err
+	}
+
+	for workload := range state.pausedWorkloads {
+		if !crashLooping[workload] {
+			t.out.Printf("Workload %s is not crash looping anymore, resuming kills", workload)
+			delete(state.pausedWorkloads, workload)
+		}
+	}
+
+	c, err := t.newCheck(ctx, state, crashLooping, defaults, killSleep)
+	if err != nil {
+		return err
+	}
+	lowerPriorityFirst(pods.Items)
+	if t.options.NodePressure == NodePressurePrioritize {
+		pressuredFirst(pods.Items, c.pressuredNodes)
+	}
+
+	err = t.evaluate(ctx, pods.Items, func(ctx context.Context, pod *v1.Pod) error {
+		if err := t.evaluatePod(ctx, pod, c); err != nil {
+			return err
+		}
+		return t.evaluateSidecars(ctx, pod, c)
+	})
+	if err != nil {
+		return err
+	}
+
+	t.export(ctx, c.records)
+	t.recordOOMKills(ctx, watcher.oomKills, state)
+	t.reportBlackout(ctx, c)
+	if t.options.ScaleHPAs && !t.options.DryRun {
+		t.restoreHPAs(ctx, c, targets.Namespace)
+	}
+
+	t.expireRepeatKills(state, t.clock.Now())
+	t.expireDisruptions(state, t.clock.Now())
+	t.expireSamples(state, t.clock.Now())
+
+	if t.options.Degrade && t.updateDegraded(ctx, c, t.clock.Now()) {
+		t.summarize(c, len(pods.Items), started)
+		return nil
+	}
+	t.publishWorkloads(c)
+
+	// expire old pods that were over limit, but arent anymore or were deleted
+	for uid, over := range state.podsToKill {
+		if t.clock.Now().Sub(over.at) > killSleep*time.Duration(over.count+1) {
+			t.log.Infof("Pod %s is not over limit anymore or has already terminated", over.name)
+			delete(state.podsToKill, uid)
+		}
+	}
+
+	t.summarize(c, len(pods.Items), started)
+	return nil
+}
+
+// newCheck returns a check of the pods with state, fetching what is shared by
+// all of them.
+func (t terminator) newCheck(ctx context.Context, state *state, crashLooping map[string]bool, defaults policy, killSleep time.Duration) (*check, error) {
+	c := &check{
+		state:        state,
+		crashLooping: crashLooping,
+		defaults:     defaults,
+		policies:     t.live.currentPolicies(),
+		killSleep:    killSleep,
+		gpu:          t.gpuUsage(ctx),
+		workloads:    make(map[string]*workloadCount),
+	}
+
+	var err error
+	c.pressuredNodes, err = t.pressuredNodes(ctx, c)
+	return c, err
+}
+
+// skipTerminating removes the pods already being deleted from pods and from
+// the ones over the limit, so they are not deleted twice.
+func (t terminator) skipTerminating(pods []v1.Pod, state *state) []v1.Pod {
+	running := pods[:0]
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			t.log.Infof("pod < %s > is terminating", pod.Name)
+			delete(state.podsToKill, pod.UID)
+			continue
+		}
+		running = append(running, pod)
+	}
+	return running
+}
+
+// sleep waits for d, unless ctx is done first.
+func (t terminator) sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.clock.After(d):
+		return nil
+	}
+}
+
+// evaluate calls evaluatePod for each of the pods using a pool of Workers
+// goroutines and stops at the first error.
+func (t terminator) evaluate(ctx context.Context, pods []v1.Pod, evaluatePod func(context.Context, *v1.Pod) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan *v1.Pod)
+	errs := make(chan error, t.options.Workers)
+	var wg sync.WaitGroup
+	for i := 0; i < t.options.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer t.reportPanic()
+			for pod := range jobs {
+				if err := evaluatePod(ctx, pod); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range pods {
+		select {
+		case jobs <- &pods[i]:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
+func (t terminator) evaluatePod(ctx context.Context, pod *v1.Pod, c *check) error {
+	pod = t.onlyContainers(pod)
+	if len(pod.Spec.Containers) == 0 || pod.Status.Phase != "Running" || c.hasKilled() {
+		return nil
+	}
+
+	policy, err := t.policyFor(ctx, c, pod)
+	if err != nil {
+		return err
+	}
+
+	if policy.protected {
+		t.log.Infof("pod < %s > is protected", pod.Name)
+		skippedPods.WithLabelValues(t.options.Cluster, skipReasonProtected).Inc()
+		return nil
+	}
+
+	limit, err := t.memoryLimit(ctx, c, pod)
+	if err != nil || limit == nil {
+		return err
+	}
+
+	using, err := t.podMemory(ctx, c, pod)
+	if err != nil && t.options.Degrade {
+		t.log.Errorf("could not get the metrics of pod %s: %s", pod.Name, err)
+		c.metricsFailed(err)
+		return nil
+	}
+	if err != nil || using == nil {
+		return err
+	}
+	c.metricsFetched()
+
+	var swap int64
+	if t.options.CountSwap || t.options.SwapLimit != nil {
+		// without the stats of its kubelet, only the memory of pod counts
+		swap, err = t.podSwap(ctx, c, pod)
+		if err != nil {
+			t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not get the swap of pod %s: %s", pod.Name, err)
+		}
+		if t.options.CountSwap && swap > 0 {
+			withSwap := using.DeepCopy()
+			withSwap.Add(*resource.NewQuantity(swap, resource.BinarySI))
+			using = &withSwap
+		}
+	}
+
+	s := sample{using: using, limit: limit, percentage: float64(using.Value()) / float64(limit.Value()) * 100, swap: swap}
+	t.log.Infof("pod < %s > (%s)", pod.Name, t.format.usage(using, limit, s.percentage))
+	limitUtilization.WithLabelValues(t.options.Cluster, pod.Namespace).Observe(s.percentage / 100)
+	t.recordUsage(c, pod, using, limit)
+	if t.options.Window > 0 {
+		s.percentage = t.aggregateUsage(c, pod, s.percentage)
+		t.log.Infof("pod < %s > %s of the last %s = %s", pod.Name, t.options.Aggregation, t.options.Window, t.format.percent(s.percentage))
+	}
+
+	if gpu, ok := c.gpu[pod.Namespace+"/"+pod.Name]; ok {
+		s.gpuPercentage = gpu.percentage()
+		t.log.Infof("pod < %s > GPU memory = %s", pod.Name, t.format.percent(s.gpuPercentage))
+	}
+
+	if t.options.PIDLimit > 0 {
+		// without the process count from its kubelet the pod has no pids and
+		// is never over the pid limit, and without the podPidsLimit of its
+		// kubelet the limit is PodPidsLimit, zero leaving the pids unchecked
+		s.pids, s.pidsLimit, err = t.podPids(ctx, c, pod)
+		if err != nil {
+			t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not get the processes of pod %s: %s", pod.Name, err)
+		}
+		t.log.Infof("pod < %s > has %d/%d processes", pod.Name, s.pids, s.pidsLimit)
+	}
+
+	s.custom = t.customMetrics(ctx, c, pod)
+	s.external = t.externalMetrics(ctx, c, pod.Namespace)
+	_, overCustom := t.overCustom(s)
+
+	s.restarts = restarts(pod)
+	overMemory := s.percentage >= float64(policy.limit)
+	if policy.rule != nil {
+		if policy.rule.usesCPU() {
+			s.cpuPercentage, err = t.cpuPercentage(ctx, pod)
+			if err != nil {
+				return err
+			}
+			t.log.Infof("pod < %s > CPU = %s", pod.Name, t.format.percent(s.cpuPercentage))
+		}
+		s.ruled = policy.rule.matches(s)
+		overMemory = s.ruled
+	}
+
+	if policy.query != "" {
+		s.queried, err = t.queryMatches(ctx, policy.query, pod)
+		if err != nil {
+			return err
+		}
+	}
+
+	over := s.queried || overMemory || s.overGPU(t.options.GPULimit) || s.overPids(t.options.PIDLimit) || s.overSwap(t.options.SwapLimit) || overCustom
+	t.countWorkload(c, pod, over)
+	decision := DecisionUnder
+	if over {
+		decision = DecisionOver
+	}
+	if over || policy.condition != nil {
+		if err := t.decide(ctx, c, pod, policy, s, over); err != nil {
+			return err
+		}
+	}
+	t.record(c, pod, s, decision)
+	return nil
+}
+
+// sample is the memory usage of a pod on a check.
+type sample struct {
+	using      *resource.Quantity
+	limit      *resource.Quantity
+	percentage float64
+	// gpuPercentage is the usage of the memory of the GPUs of the pod
+	gpuPercentage float64
+	// pids are the processes of the pod out of pidsLimit, zero when unknown
+	pids      int64
+	pidsLimit int64
+	// swap is the swap used by the pod, in bytes
+	swap int64
+	// custom are the values of the custom metrics of the pod, by name
+	custom map[string]float64
+	// external are the values of the external metrics for the namespace of
+	// the pod, by name
+	external map[string]float64
+	// cpuPercentage is the CPU usage of the pod out of its limit, only when
+	// the rule of its policy needs it
+	cpuPercentage float64
+	// restarts is the highest restart count of the containers of the pod
+	restarts int32
+	// ruled and queried tell whether the rule and the query of the policy of
+	// the pod matched
+	ruled   bool
+	queried bool
+}
+
+// overSwap tells whether the swap usage is over limit, nil disabling it.
+func (s sample) overSwap(limit *resource.Quantity) bool {
+	return limit != nil && s.swap > 0 && s.swap >= limit.Value()
+}
+
+// overPids tells whether the amount of processes is over limit percent of the
+// pids limit, zero disabling it.
+func (s sample) overPids(limit int) bool {
+	return limit > 0 && s.pidsLimit > 0 && float64(s.pids)/float64(s.pidsLimit)*100 >= float64(limit)
+}
+
+// overGPU tells whether the GPU memory usage is over limit, zero disabling it.
+func (s sample) overGPU(limit int) bool {
+	return limit > 0 && s.gpuPercentage >= float64(limit)
+}
+
+// decide counts pod as over the limit and kills it once it has been for
+// killAfter checks, or when the condition of its policy matches, unless a pod
+// was already killed on this check. Only the counters, the protections on the
+// state and the reservation of the kill hold c.mu, so the calls killing a pod
+// and the kill sleep don't hold up the other workers.
+func (t terminator) decide(ctx context.Context, c *check, pod *v1.Pod, policy policy, s sample, over bool) error {
+	var events []Event
+	kill, err := t.killable(ctx, c, pod, policy, s, over, &events)
+	for _, event := range events {
+		t.notify(ctx, event)
+	}
+	if kill == nil || err != nil {
+		return err
+	}
+
+	defer t.logDecision(pod, kill.reason)
+	return t.kill(ctx, c, pod, s, kill)
+}
+
+// killCandidate is a pod decide found past the protections on the state of the
+// check, with what killing it needs.
+type killCandidate struct {
+	workload  string
+	key       string
+	domain    string
+	policy    policy
+	reason    *Reason
+	overCount int
+	// firstOver is when the pod was first over the limit, zero when only its
+	// condition matches
+	firstOver time.Time
+	now       time.Time
+}
+
+// killable counts pod as over the limit holding c.mu, and returns it once it
+// should be killed and none of the protections on the state of the check keep
+// it from being killed. The events to notify are added to events, so they are
+// sent once c.mu is released.
+func (t terminator) killable(ctx context.Context, c *check, pod *v1.Pod, policy policy, s sample, over bool, events *[]Event) (kill *killCandidate, err error) {
+	var domain string
+	if t.options.SpreadKills && over {
+		domain, err = t.failureDomain(ctx, c, pod)
+		if err != nil {
+			t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not get the zone of pod %s: %s", pod.Name, err)
+		}
+	}
+
+	// a condition failing on pod is reported once unlocked, as not matching
+	var conditionErr error
+	defer func() {
+		if conditionErr != nil {
+			t.operationalError(ctx, c, pod.Namespace, pod.Name, "%s", conditionErr)
+		}
+	}()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.killed {
+		return nil, nil
+	}
+
+	workload := workloadName(pod)
+	key := workloadKey(pod)
+	now := t.clock.Now()
+	policy = t.backoff(c, key, policy, now)
+	if t.options.NodePressure == NodePressurePrioritize && c.pressuredNodes[pod.Spec.NodeName] {
+		policy.killAfter = 0
+	}
+
+	podsToKill := c.state.podsToKill
+	first := false
+	if over {
+		c.countOver()
+		// a new pod with the same name, like the ones of statefulsets, has a
+		// different uid and starts over
+		if over, ok := podsToKill[pod.UID]; ok {
+			over.count = over.count + 1
+		} else {
+			podsToKill[pod.UID] = &overLimit{namespace: pod.Namespace, name: pod.Name, workload: key, domain: domain, at: now}
+			first = true
+		}
+		switch matched := t.matched(s, over); {
+		case matched == matchedRule:
+			t.out.Warnf(" pod < %s > (%s, %s of CPU, matches the rule of its policy)", pod.Name, t.format.usage(s.using, s.limit, s.percentage), t.format.percent(s.cpuPercentage))
+		case matched == matchedQuery:
+			t.out.Warnf(" pod < %s > (matches the query %s)", pod.Name, policy.query)
+		case matched == matchedGPU:
+			t.out.Warnf(" pod < %s > (%s of GPU memory over the GPU limit)", pod.Name, t.format.percent(s.gpuPercentage))
+		case matched == matchedPids:
+			t.out.Warnf(" pod < %s > (%d/%d processes over the pid limit)", pod.Name, s.pids, s.pidsLimit)
+		case strings.HasPrefix(matched, matchedCustom):
+			metric := strings.TrimPrefix(matched, matchedCustom)
+			t.out.Warnf(" pod < %s > (%s = %g over its threshold)", pod.Name, metric, s.custom[metric])
+		case matched == matchedSwap:
+			t.out.Warnf(" pod < %s > (%s of swap over the swap limit)", pod.Name, t.format.bytesOf(s.swap))
+		default:
+			t.out.Warnf(" pod < %s > (%s over the memory limit)", pod.Name, t.format.usage(s.using, s.limit, s.percentage))
+		}
+	}
+
+	overCount := 0
+	var firstOver time.Time
+	if over, ok := podsToKill[pod.UID]; ok {
+		overCount = over.count + 1
+		firstOver = over.at
+	}
+
+	reason := newReason(s, t.matched(s, over), overCount, policy.killAfter)
+	reason.Provenance = policy.provenance.sources()
+	if first {
+		message := fmt.Sprintf("pod %s is over the limit, %s", pod.Name, t.format.usage(s.using, s.limit, s.percentage))
+		*events = append(*events, Event{Type: eventOverLimit, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Reason: reason.snapshot()})
+	}
+
+	if policy.condition != nil {
+		matches, err := policy.condition.matches(pod, s, overCount)
+		if err != nil {
+			conditionErr = fmt.Errorf("pod %s: %w", pod.Name, err)
+			return nil, nil
+		}
+		if !matches {
+			return nil, nil
+		}
+		reason.MatchedRule = matchedCondition
+		t.out.Warnf(" pod < %s > matches the condition %s", pod.Name, policy.condition)
+	} else if overCount <= policy.killAfter {
+		if overCount > 0 {
+			t.logDecision(pod, reason)
+		}
+		return nil, nil
+	}
+	// the decision on a candidate is logged once it is killed or skipped
+	defer func() {
+		if kill == nil {
+			t.logDecision(pod, reason)
+		}
+	}()
+
+	reason.check(protectionBarePod)
+	if len(pod.OwnerReferences) == 0 && !t.options.IncludeBarePods {
+		reason.skip(protectionBarePod)
+		t.out.Printf("unmanaged over-limit pod < %s >, not deleting it as it would not be recreated", pod.Name)
+		return nil, nil
+	}
+
+	if t.options.MaxPriority != nil {
+		reason.check(protectionPriority)
+		if priority(pod) > *t.options.MaxPriority {
+			reason.skip(protectionPriority)
+			t.out.Printf("not deleting pod < %s >, its priority %d is over the max priority %d", pod.Name, priority(pod), *t.options.MaxPriority)
+			return nil, nil
+		}
+	}
+
+	if len(t.options.QOSClasses) > 0 {
+		reason.check(protectionQOS)
+		if !t.options.eligibleQOS(pod) {
+			reason.skip(protectionQOS)
+			t.out.Printf("not deleting pod < %s >, its QoS class %s is not one of %s", pod.Name, pod.Status.QOSClass, strings.Join(t.options.QOSClasses, ", "))
+			return nil, nil
+		}
+	}
+
+	reason.check(protectionCrashLoop)
+	if c.crashLooping[key] {
+		reason.skip(protectionCrashLoop)
+		t.log.Infof("not deleting pod < %s >, workload %s is crash looping", pod.Name, workload)
+		if !c.state.pausedWorkloads[key] {
+			c.state.pausedWorkloads[key] = true
+			message := fmt.Sprintf("pausing kills of %s, its pods are crash looping", workload)
+			t.out.Print(message)
+			*events = append(*events, Event{Type: eventKillPaused, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Reason: reason.snapshot()})
+		}
+		return nil, nil
+	}
+
+	reason.check(protectionRepeatOffender)
+	if t.escalate(c, pod, key, reason, events) {
+		return nil, nil
+	}
+
+	reason.check(protectionCooldown)
+	if lastKill, ok := c.state.lastKills[key]; ok && now.Sub(lastKill) < policy.cooldown {
+		reason.skip(protectionCooldown)
+		t.out.Printf("not deleting pod < %s >, a pod of %s was deleted %s ago", pod.Name, workload, now.Sub(lastKill).Round(time.Second))
+		return nil, nil
+	}
+
+	if t.options.MaxDisruptions != nil {
+		reason.check(protectionMaxDisruptions)
+		if t.disrupted(c, key, now) {
+			reason.skip(protectionMaxDisruptions)
+			t.out.Printf("not deleting pod < %s >, %s already had %s disruptions", pod.Name, workload, t.options.MaxDisruptions)
+			return nil, nil
+		}
+	}
+
+	if t.options.SpreadKills {
+		reason.check(protectionSpread)
+		if spreadDeferred(c, pod, key, domain) {
+			reason.skip(protectionSpread)
+			t.out.Printf("not deleting pod < %s >, the last pod of %s was deleted in %s too", pod.Name, workload, domain)
+			return nil, nil
+		}
+	}
+
+	if t.options.NodePressure == NodePressurePause {
+		reason.check(protectionNodePressure)
+		if len(c.pressuredNodes) > 0 {
+			reason.skip(protectionNodePressure)
+			t.out.Printf("not deleting pod < %s >, nodes are under memory pressure", pod.Name)
+			return nil, nil
+		}
+	}
+
+	if t.options.ActiveHours != nil {
+		reason.check(protectionActiveHours)
+		if !t.options.ActiveHours.Contains(now) {
+			reason.skip(protectionActiveHours)
+			t.out.Printf("not deleting pod < %s >, outside of active hours", pod.Name)
+			return nil, nil
+		}
+	}
+
+	return &killCandidate{workload: workload, key: key, domain: domain, policy: policy, reason: reason, overCount: overCount, firstOver: firstOver, now: now}, nil
+}
+
+// kill runs the protections of kill that call the API and kills pod once it
+// reserved the kill of the check, releasing it when the kill fails.
+func (t terminator) kill(ctx context.Context, c *check, pod *v1.Pod, s sample, kill *killCandidate) error {
+	reason, workload, now := kill.reason, kill.workload, kill.now
+
+	reason.check(protectionRollout)
+	rolling, err := t.rollingOut(ctx, c, pod)
+	if err != nil {
+		return err
+	}
+	if rolling {
+		reason.skip(protectionRollout)
+		t.out.Printf("not deleting pod < %s >, %s is rolling out", pod.Name, workload)
+		return nil
+	}
+
+	reason.check(protectionBlackout)
+	blackout, err := t.inBlackout(ctx, c, pod.Namespace, now)
+	if err != nil {
+		return err
+	}
+	if blackout {
+		reason.skip(protectionBlackout)
+		t.out.Printf("not deleting pod < %s >, in blackout", pod.Name)
+		c.mu.Lock()
+		c.state.deferredKills[pod.Namespace+"/"+pod.Name] = Event{Time: now, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Reason: reason.snapshot()}
+		c.mu.Unlock()
+		return nil
+	}
+
+	if _, ok := t.action.(execAction); ok {
+		reason.check(protectionWindows)
+		if t.unsignalable(ctx, c, pod) {
+			reason.skip(protectionWindows)
+			t.out.Printf("not deleting pod < %s >, its Windows containers can't be signaled by the exec kill-action", pod.Name)
+			c.mu.Lock()
+			alerted := c.state.unsignaled[kill.key]
+			c.state.unsignaled[kill.key] = true
+			c.mu.Unlock()
+			if !alerted {
+				message := fmt.Sprintf("not killing the pods of %s, its Windows containers can't be signaled by the exec kill-action", workload)
+				t.notify(ctx, Event{Type: eventKillPaused, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Reason: reason.snapshot()})
+			}
+			return nil
+		}
+	}
+
+	dryRun := t.options.DryRun
+	gracePeriod := pod.DeletionGracePeriodSeconds
+	if t.guard != nil {
+		reason.check(protectionGuard)
+		verdict, err := t.guard.Review(ctx, KillRequest{
+			Cluster:     t.options.Cluster,
+			Namespace:   pod.Namespace,
+			Pod:         pod.Name,
+			Workload:    workload,
+			Labels:      pod.Labels,
+			Usage:       s.using.Value(),
+			Limit:       s.limit.Value(),
+			Percentage:  s.percentage,
+			OverCount:   kill.overCount,
+			DryRun:      dryRun,
+			GracePeriod: gracePeriod,
+		})
+		if err != nil {
+			reason.skip(protectionGuard)
+			t.out.Printf("not deleting pod < %s >, could not review the kill: %s", pod.Name, err)
+			return nil
+		}
+		if !verdict.Allow {
+			reason.skip(protectionGuard)
+			t.out.Printf("not deleting pod < %s >, denied by policy: %s", pod.Name, verdict.Reason)
+			return nil
+		}
+		if verdict.Reason != "" {
+			t.log.Infof("kill of pod < %s > allowed by policy: %s", pod.Name, verdict.Reason)
+		}
+		dryRun = dryRun || verdict.DryRun
+		if verdict.GracePeriod != nil {
+			gracePeriod = verdict.GracePeriod
+		}
+	}
+
+	if t.options.ScaleHPAs {
+		scaled, err := t.scaleUp(ctx, c, pod, dryRun, reason)
+		if err != nil {
+			return err
+		}
+		if scaled {
+			reason.Action = ActionScaleUp
+			c.mu.Lock()
+			delete(c.state.podsToKill, pod.UID)
+			c.mu.Unlock()
+			return nil
+		}
+	}
+
+	if !t.reserve(c, pod, reason, now) {
+		return nil
+	}
+
+	reason.Action = ActionKill
+	if dryRun {
+		reason.Action = ActionDryRun
+	}
+	// a pod whose container is restarted goes on, so it isn't marked
+	_, restart := t.action.(ContainerAction)
+	if restart {
+		t.out.Killf("Restarting a container of pod < %s > (has exceeded memory limit for %d checks)", pod.Name, kill.overCount)
+	} else {
+		t.out.Killf("Deleting pod < %s > (has exceeded memory limit for %d checks)", pod.Name, kill.overCount)
+	}
+	cause := fmt.Sprintf("over the limit for %d checks (%s)", kill.overCount, t.format.usage(s.using, s.limit, s.percentage))
+	if kill.policy.condition != nil {
+		cause = fmt.Sprintf("matches the condition %s", kill.policy.condition)
+	}
+	if dryRun {
+		t.planKill(PlannedKill{Time: now, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Cause: cause, Reason: reason.snapshot()})
+	} else {
+		disrupted, labeled := false, false
+		if !restart {
+			if err := t.markDisrupted(ctx, pod, cause); err != nil {
+				t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not set the disruption condition of pod %s: %s", pod.Name, err)
+			} else {
+				disrupted = true
+			}
+			if err := t.markKilled(ctx, pod, cause); err != nil {
+				t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not label pod %s as killed: %s", pod.Name, err)
+			} else {
+				labeled = true
+			}
+		}
+		if err := t.action.Kill(ctx, pod, gracePeriod); err != nil {
+			t.release(c)
+			// the pod keeps running, so it isn't left marked as killed
+			if disrupted {
+				if err := t.unmarkDisrupted(ctx, pod, fmt.Sprintf("could not be killed: %s", err)); err != nil {
+					t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not unset the disruption condition of pod %s: %s", pod.Name, err)
+				}
+			}
+			if labeled {
+				if err := t.unmarkKilled(ctx, pod); err != nil {
+					t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not unlabel pod %s as killed: %s", pod.Name, err)
+				}
+			}
+			return err
+		}
+		message := fmt.Sprintf("pod %s was killed after being over the limit for %d checks", pod.Name, kill.overCount)
+		cost := t.workloadCost(ctx, pod.Namespace, workload)
+		if cost != nil {
+			message = fmt.Sprintf("%s, %s costs %s", message, workload, cost)
+		}
+		t.notify(ctx, Event{Type: eventPodKilled, Namespace: pod.Namespace, Pod: pod.Name, Workload: workload, Message: message, Cost: cost, Reason: reason.snapshot()})
+		if t.options.AnnotateWorkloads {
+			if err := t.annotateWorkload(ctx, pod, s, now); err != nil {
+				t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not annotate the workload of pod %s: %s", pod.Name, err)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	if !dryRun && !kill.firstOver.IsZero() {
+		t.recordPrevented(c.state, pod.Namespace, workload, kill.firstOver, now)
+	}
+	c.state.lastKills[kill.key] = now
+	if kill.domain != "" {
+		c.state.lastKillDomains[kill.key] = kill.domain
+	}
+	t.recordRepeatKill(c, kill.key, KillRecord{Time: now, Pod: pod.Name, Usage: s.using.Value(), Limit: s.limit.Value(), Percentage: s.percentage, OverCount: kill.overCount})
+	t.recordDisruption(c, kill.key, now)
+	t.namespaceKills.record(pod.Namespace, now)
+	delete(c.state.podsToKill, pod.UID)
+	c.killedUID = pod.UID
+	c.killedDryRun = dryRun
+	c.mu.Unlock()
+
+	// a done ctx stops the loop right after this check
+	_ = t.sleep(ctx, c.killSleep)
+	return nil
+}
+
+// reserve reserves the kill of c for pod, and one of the budget shared by the
+// namespaces, unless a pod was already killed on c, the budget is spent or the
+// namespace of pod is at its kills of the last hour.
+func (t terminator) reserve(c *check, pod *v1.Pod, reason *Reason, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.killed {
+		return false
+	}
+
+	// the namespaces sharing the budget stop at MaxKills together
+	c.state.budget.mu.Lock()
+	defer c.state.budget.mu.Unlock()
+	if t.options.MaxKills > 0 && c.state.budget.kills >= t.options.MaxKills {
+		reason.Action = ActionWait
+		return false
+	}
+	if t.options.MaxKillsPerNamespacePerHour > 0 {
+		reason.check(protectionNamespaceRate)
+		if t.namespaceRateLimited(pod.Namespace, now) {
+			reason.skip(protectionNamespaceRate)
+			t.out.Printf("not deleting pod < %s >, %d pods of namespace %s were killed in the last hour", pod.Name, t.options.MaxKillsPerNamespacePerHour, pod.Namespace)
+			return false
+		}
+	}
+
+	c.killed = true
+	c.state.budget.kills++
+	return true
+}
+
+// release gives back the kill reserved on c when it failed.
+func (t terminator) release(c *check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.killed = false
+
+	c.state.budget.mu.Lock()
+	defer c.state.budget.mu.Unlock()
+	c.state.budget.kills--
+}
+
+// podUsage returns the memory usage of pod, or nil when it has no metrics yet.
+func (t terminator) podUsage(ctx context.Context, pod *v1.Pod) (*resource.Quantity, error) {
+	usage, err := t.provider.PodUsage(ctx, pod)
+	if stderrors.Is(err, ErrStaleMetrics) {
+		t.log.Infof("skipping pod %s: %s", pod.Name, err)
+		skippedPods.WithLabelValues(t.options.Cluster, skipReasonStaleMetrics).Inc()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		t.log.Infof("Pod %s has no metrics", pod.Name)
+	}
+	return usage, nil
+}
+
+// podMemory returns the usage of pod by the MemoryMetric, or nil when it has
+// no metrics yet. The working set comes from the metrics provider and the
+// others from the kubelets, except for Windows pods, whose kubelets report no
+// rss and the committed memory as usage, so they are always compared by their
+// working set.
+func (t terminator) podMemory(ctx context.Context, c *check, pod *v1.Pod) (*resource.Quantity, error) {
+	switch t.options.MemoryMetric {
+	case MemoryMetricRSS, MemoryMetricUsage:
+		windows, err := t.windowsPod(ctx, c, pod)
+		if err != nil {
+			return nil, err
+		}
+		if windows {
+			t.log.Infof("pod < %s > runs Windows containers, using its working set", pod.Name)
+			return t.podUsage(ctx, pod)
+		}
+		if t.customProvider || (t.options.MetricsSource != "" && t.options.MetricsSource != MetricsSourceMetricsServer) {
+			return t.podUsage(ctx, pod)
+		}
+		return t.kubeletUsage(ctx, c, pod)
+	default:
+		return t.podUsage(ctx, pod)
+	}
+}
+
+func (c *check) hasKilled() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.killed
+}
+
+func (t terminator) getPods(ctx context.Context, watcher *targetWatcher, targets Targets) (*v1.PodList, error) {
+	namespace := targets.Namespace
+	if !targets.explicit() {
+		pods, err := cachedPods(watcher, namespace, labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		if watcher.metadata != nil {
+			return pods, nil
+		}
+		pods.Items, err = t.onTargetNodes(ctx, targets, pods.Items)
+		return pods, err
+	}
+
+	pods := new(v1.PodList)
+	for _, name := range targets.Services {
+		service, err := t.selectors.get("service", namespace, name, func() (*target, error) {
+			service, err := watcher.services.Services(namespace).Get(name)
+			if err != nil {
+				return nil, err
+			}
+			return &target{selector: labels.Set(service.Spec.Selector).AsSelector()}, nil
+		})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				t.log.Errorf("service %s not found", name)
+				continue
+			}
+			return nil, err
+		}
+
+		servicePods, err := cachedPods(watcher, namespace, service.selector)
+		if err != nil {
+			return nil, err
+		}
+
+		t.log.Infof("service %s has %d pods", name, len(servicePods.Items))
+		pods.Items = append(pods.Items, servicePods.Items...)
+	}
+
+	for _, name := range targets.Deployments {
+		deployment, err := t.selectors.get("deployment", namespace, name, func() (*target, error) {
+			deployment, err := watcher.deployments.Deployments(namespace).Get(name)
+			if err != nil {
+				return nil, err
+			}
+			return newWorkloadTarget(deployment.Spec.Selector, deployment.Spec.Replicas)
+		})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				t.log.Errorf("deployment %s not found", name)
+				continue
+			}
+			return nil, err
+		}
+
+		if err := t.appendWorkloadPods(ctx, watcher, pods, "deployment", namespace, name, deployment); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range targets.StatefulSets {
+		statefulSet, err := t.selectors.get("statefulset", namespace, name, func() (*target, error) {
+			statefulSet, err := watcher.statefulSets.StatefulSets(namespace).Get(name)
+			if err != nil {
+				return nil, err
+			}
+			return newWorkloadTarget(statefulSet.Spec.Selector, statefulSet.Spec.Replicas)
+		})
+		if err != nil {
+			if errors.IsNotFound(err) {
+				t.log.Errorf("statefulset %s not found", name)
+				continue
+			}
+			return nil, err
+		}
+
+		if err := t.appendWorkloadPods(ctx, watcher, pods, "statefulset", namespace, name, statefulSet); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range targets.Pods {
+		pod, err := t.targetPod(ctx, watcher, namespace, name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				t.log.Errorf("pod %s not found", name)
+				continue
+			}
+			return nil, err
+		}
+		pods.Items = append(pods.Items, *pod)
+	}
+
+	// the nodes of pods listed by their metadata are only known once they
+	// are shortlisted
+	if watcher.metadata != nil {
+		pods.Items = uniquePods(pods.Items)
+		return pods, nil
+	}
+	var err error
+	pods.Items, err = t.onTargetNodes(ctx, targets, uniquePods(pods.Items))
+	return pods, err
+}
+
+// targetPod gets the pod namespace/name from the cache of watcher, or from the
+// API server when only the metadata of pods is cached.
+func (t terminator) targetPod(ctx context.Context, watcher *targetWatcher, namespace, name string) (*v1.Pod, error) {
+	if watcher.metadata != nil {
+		return t.clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	}
+	return watcher.pods.Pods(namespace).Get(name)
+}
+
+// cachedPods lists the pods of namespace matching selector from the cache of
+// watcher. They are copies, the cached ones are shared with the informer.
+func cachedPods(watcher *targetWatcher, namespace string, selector labels.Selector) (*v1.PodList, error) {
+	if watcher.metadata != nil {
+		return metadataPods(watcher, namespace, selector)
+	}
+
+	cached, err := watcher.pods.Pods(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := &v1.PodList{Items: make([]v1.Pod, len(cached))}
+	for i, pod := range cached {
+		pods.Items[i] = *pod
+	}
+	return pods, nil
+}
+
+// uniquePods removes the repeated pods of pods, selected by more than one
+// target, so they are only checked once.
+func uniquePods(pods []v1.Pod) []v1.Pod {
+	seen := make(map[types.UID]bool, len(pods))
+	unique := pods[:0]
+	for _, pod := range pods {
+		if seen[pod.UID] {
+			continue
+		}
+		seen[pod.UID] = true
+		unique = append(unique, pod)
+	}
+	return unique
+}
+
+func newWorkloadTarget(selector *metav1.LabelSelector, replicas *int32) (*target, error) {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	return &target{selector: s, replicas: replicas}, nil
+}
+
+// appendWorkloadPods adds the pods of a deployment or statefulset to pods, as
+// long as all of its replicas are running.
+func (t terminator) appendWorkloadPods(ctx context.Context, watcher *targetWatcher, pods *v1.PodList, kind, namespace, name string, workload *target) error {
+	// all the pods of the workload count towards its replicas, not only the
+	// cached ones of the targeted nodes
+	var workloadPods *v1.PodList
+	var err error
+	if watcher.nodeFiltered || watcher.metadata != nil {
+		workloadPods, err = t.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: workload.selector.String()})
+	} else {
+		workloadPods, err = cachedPods(watcher, namespace, workload.selector)
+	}
+	if err != nil {
+		return err
+	}
+
+	running := 0
+	for _, pod := range workloadPods.Items {
+		if pod.Status.Phase == "Running" {
+			running = running + 1
+		}
+	}
+
+	if workload.replicas != nil && running < int(*workload.replicas) {
+		t.log.Infof("skipping %s, not all pods are running", name)
+		return nil
+	}
+
+	t.log.Infof("%s %s has %d pods", kind, name, len(workloadPods.Items))
+	pods.Items = append(pods.Items, workloadPods.Items...)
+	return nil
+}
@@ -0,0 +1,137 @@
+package terminator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// checkCluster returns a terminator over fake clientsets with pod in its
+// namespace, metrics-server reporting usage for it.
+func checkCluster(t *testing.T, pod *v1.Pod, usage string, options Options, opts ...Option) (terminator, *targetWatcher, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: pod.Namespace}}, pod)
+
+	podMetrics := v1beta1.PodMetrics{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		Timestamp:  metav1.Now(),
+		Containers: []v1beta1.ContainerMetrics{{
+			Name:  pod.Spec.Containers[0].Name,
+			Usage: v1.ResourceList{v1.ResourceMemory: resource.MustParse(usage)},
+		}},
+	}
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, podMetrics.DeepCopy(), nil
+	})
+	mc.PrependReactor("list", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &v1beta1.PodMetricsList{Items: []v1beta1.PodMetrics{*podMetrics.DeepCopy()}}, nil
+	})
+
+	options.Workers = 1
+	options.Quiet = true
+	options.IncludeBarePods = true
+	debug := logrus.New()
+	debug.SetOutput(io.Discard)
+	created, err := NewForClients(clientset, mc, options, append(opts, WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminator := created.(terminator)
+
+	watcher, err := terminator.watchTargets(context.Background(), Targets{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return terminator, watcher, clientset
+}
+
+func checkClusterPod(namespace string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: namespace, UID: types.UID("api")},
+		Spec: v1.PodSpec{Containers: []v1.Container{{
+			Name:      "app",
+			Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+		}}},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+// TestRunCheck checks a check against fake clientsets deletes the pods over
+// the limit through the clientset, unless they are protected or it is a dry
+// run.
+func TestRunCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		namespace string
+		usage     string
+		options   Options
+		deleted   bool
+	}{
+		{"over the limit", "web", "1000Mi", Options{}, true},
+		{"under the limit", "web", "512Mi", Options{}, false},
+		{"dry run", "web", "1000Mi", Options{DryRun: true}, false},
+		{"system namespace", "kube-system", "1000Mi", Options{SystemPolicies: SystemPolicies()}, false},
+		{"system namespaces allowed", "kube-system", "1000Mi", Options{}, true},
+	}
+	for _, test := range tests {
+		terminator, watcher, clientset := checkCluster(t, checkClusterPod(test.namespace), test.usage, test.options)
+		if err := terminator.runCheck(context.Background(), watcher, Targets{}, newState(), terminator.defaultPolicy(95, 0), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		deleted := false
+		for _, action := range clientset.Actions() {
+			if action.Matches("delete", "pods") {
+				deleted = true
+			}
+		}
+		if deleted != test.deleted {
+			t.Errorf("%s: expected deleted %v, got %v", test.name, test.deleted, deleted)
+		}
+	}
+}
+
+// failingAction fails to kill pods.
+type failingAction struct{}
+
+func (failingAction) Kill(ctx context.Context, pod *v1.Pod, gracePeriod *int64) error {
+	return errors.New("admission webhook denied the request")
+}
+
+// TestRunCheckKillFailure checks a pod that couldn't be killed isn't left
+// labeled as killed nor as a disruption target.
+func TestRunCheckKillFailure(t *testing.T) {
+	terminator, watcher, clientset := checkCluster(t, checkClusterPod("web"), "1000Mi", Options{}, WithAction(failingAction{}))
+	if err := terminator.runCheck(context.Background(), watcher, Targets{}, newState(), terminator.defaultPolicy(95, 0), 0); err == nil {
+		t.Fatal("expected the failed kill to fail the check")
+	}
+
+	pod, err := clientset.CoreV1().Pods("web").Get(context.Background(), "api", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := pod.Labels[killedLabel]; ok {
+		t.Errorf("expected the pod not to be labeled as killed, got %v", pod.Labels)
+	}
+	if _, ok := pod.Annotations[killReasonAnnotation]; ok {
+		t.Errorf("expected the pod not to have a kill reason, got %v", pod.Annotations)
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == disruptionTarget && condition.Status != v1.ConditionFalse {
+			t.Errorf("expected the disruption condition to be off, got %s", condition.Status)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package terminator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/metadata/metadatalister"
+	"k8s.io/client-go/tools/cache"
+)
+
+// targetWatcher keeps informers on the targeted services, deployments and
+// statefulsets. Selectors are resolved from its listers, and any change to a
+// selector or to the replicas of a target invalidates its cached entry. Pods
+// are watched too, to report the ones that get OOMKilled to oomKills and to
+// list the pods of each check from their cache.
+type targetWatcher struct {
+	// pods are the cached pods of the targeted namespace and nodes, so the
+	// pods of a check are listed without calling the API server. With
+	// nodeFiltered, the ones of the other nodes are not cached
+	pods         corelisters.PodLister
+	nodeFiltered bool
+	// metadata are the cached metadata of the pods instead, with MetadataOnly
+	metadata     metadatalister.Lister
+	services     corelisters.ServiceLister
+	deployments  appslisters.DeploymentLister
+	statefulSets appslisters.StatefulSetLister
+	oomKills     chan Event
+}
+
+func (t terminator) watchTargets(ctx context.Context, targets Targets) (*targetWatcher, error) {
+	if err := t.validateTargets(targets); err != nil {
+		return nil, err
+	}
+
+	// only the pods are selected by node, the other informers share the
+	// factory
+	factory := informers.NewSharedInformerFactoryWithOptions(t.clientset, 10*time.Minute, informers.WithNamespace(targets.Namespace))
+	podFactory := factory
+	if selector := targets.nodeFieldSelector(); selector != "" {
+		podFactory = informers.NewSharedInformerFactoryWithOptions(t.clientset, 10*time.Minute, informers.WithNamespace(targets.Namespace), informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = selector
+		}))
+	}
+	watcher := &targetWatcher{oomKills: make(chan Event, 100)}
+	if t.options.MetadataOnly {
+		if err := t.watchMetadata(ctx, watcher, targets); err != nil {
+			return nil, err
+		}
+		podFactory = factory
+	} else {
+		podFactory.Core().V1().Pods().Informer().AddEventHandler(t.oomKillHandler(targets.explicit(), watcher.oomKills))
+		watcher.pods = podFactory.Core().V1().Pods().Lister()
+		watcher.nodeFiltered = podFactory != factory
+	}
+
+	if len(targets.Services) > 0 {
+		informer := factory.Core().V1().Services()
+		informer.Informer().AddEventHandler(t.targetHandler("service", targets.Services, func(old, new interface{}) bool {
+			return !reflect.DeepEqual(old.(*v1.Service).Spec.Selector, new.(*v1.Service).Spec.Selector)
+		}))
+		watcher.services = informer.Lister()
+	}
+
+	if len(targets.Deployments) > 0 {
+		informer := factory.Apps().V1().Deployments()
+		informer.Informer().AddEventHandler(t.targetHandler("deployment", targets.Deployments, func(old, new interface{}) bool {
+			o, n := old.(*appsv1.Deployment), new.(*appsv1.Deployment)
+			return !reflect.DeepEqual(o.Spec.Selector, n.Spec.Selector) || !reflect.DeepEqual(o.Spec.Replicas, n.Spec.Replicas)
+		}))
+		watcher.deployments = informer.Lister()
+	}
+
+	if len(targets.StatefulSets) > 0 {
+		informer := factory.Apps().V1().StatefulSets()
+		informer.Informer().AddEventHandler(t.targetHandler("statefulset", targets.StatefulSets, func(old, new interface{}) bool {
+			o, n := old.(*appsv1.StatefulSet), new.(*appsv1.StatefulSet)
+			return !reflect.DeepEqual(o.Spec.Selector, n.Spec.Selector) || !reflect.DeepEqual(o.Spec.Replicas, n.Spec.Replicas)
+		}))
+		watcher.statefulSets = informer.Lister()
+	}
+
+	factories := []informers.SharedInformerFactory{factory}
+	if podFactory != factory {
+		factories = append(factories, podFactory)
+	}
+	for _, factory := range factories {
+		factory.Start(ctx.Done())
+		for informer, synced := range factory.WaitForCacheSync(ctx.Done()) {
+			if !synced {
+				return nil, fmt.Errorf("could not sync informer for %s", informer)
+			}
+		}
+	}
+
+	return watcher, nil
+}
+
+// watchMetadata caches the metadata of the targeted pods into watcher.
+func (t terminator) watchMetadata(ctx context.Context, watcher *targetWatcher, targets Targets) error {
+	factory := metadatainformer.NewFilteredSharedInformerFactory(t.metadata, 10*time.Minute, targets.Namespace, func(options *metav1.ListOptions) {
+		options.FieldSelector = targets.nodeFieldSelector()
+	})
+	resource := v1.SchemeGroupVersion.WithResource("pods")
+	watcher.metadata = metadatalister.New(factory.ForResource(resource).Informer().GetIndexer(), resource)
+
+	factory.Start(ctx.Done())
+	for informer, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			return fmt.Errorf("could not sync informer for %s", informer)
+		}
+	}
+	return nil
+}
+
+// targetHandler invalidates the cached selector of the targeted objects of kind
+// when changed reports a relevant update or when they are deleted.
+func (t terminator) targetHandler(kind string, names []string, changed func(old, new interface{}) bool) cache.ResourceEventHandler {
+	targeted := make(map[string]bool, len(names))
+	for _, name := range names {
+		targeted[name] = true
+	}
+
+	invalidate := func(obj interface{}, reason string) {
+		key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+		if err != nil {
+			return
+		}
+
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil || !targeted[name] {
+			return
+		}
+
+		t.log.Infof("%s %s %s, refreshing its selector", kind, name, reason)
+		t.selectors.invalidate(kind, namespace, name)
+	}
+
+	return cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, new interface{}) {
+			if changed(old, new) {
+				invalidate(new, "changed")
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			invalidate(obj, "was deleted")
+		},
+	}
+}
@@ -0,0 +1,114 @@
+package terminator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	AggregationLast = "last"
+	AggregationAvg  = "avg"
+	AggregationMax  = "max"
+)
+
+// percentageSample is the memory usage percentage of a pod on a check.
+type percentageSample struct {
+	at         time.Time
+	percentage float64
+}
+
+// validateAggregation checks aggregation is last, avg, max or a percentile
+// like p95.
+func validateAggregation(aggregation string) error {
+	switch aggregation {
+	case "", AggregationLast, AggregationAvg, AggregationMax:
+		return nil
+	}
+
+	_, err := percentile(aggregation)
+	return err
+}
+
+func percentile(aggregation string) (float64, error) {
+	if !strings.HasPrefix(aggregation, "p") {
+		return 0, fmt.Errorf("invalid aggregation %q, must be last, avg, max or a percentile like p95", aggregation)
+	}
+	p, err := strconv.ParseFloat(strings.TrimPrefix(aggregation, "p"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid aggregation %q, must be last, avg, max or a percentile like p95", aggregation)
+	}
+	return p, nil
+}
+
+// aggregateUsage records the memory usage percentage of pod and returns the
+// Aggregation of the ones of the last Window, so spiky allocators are judged
+// by a percentile or an average instead of a single sample.
+func (t terminator) aggregateUsage(c *check, pod *v1.Pod, percentage float64) float64 {
+	if t.options.Window <= 0 {
+		return percentage
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := t.clock.Now()
+	key := string(pod.UID)
+	samples := append(c.state.samples[key], percentageSample{at: now, percentage: percentage})
+	for len(samples) > 0 && now.Sub(samples[0].at) > t.options.Window {
+		samples = samples[1:]
+	}
+	c.state.samples[key] = samples
+	c.state.sampled.touch(key, now)
+	for _, evicted := range c.state.sampled.evict(t.options.MaxTrackedPods) {
+		delete(c.state.samples, evicted)
+	}
+
+	percentages := make([]float64, len(samples))
+	for i, sample := range samples {
+		percentages[i] = sample.percentage
+	}
+	return aggregate(t.options.Aggregation, percentages)
+}
+
+func aggregate(aggregation string, values []float64) float64 {
+	switch aggregation {
+	case "", AggregationLast:
+		return values[len(values)-1]
+	case AggregationAvg:
+		sum := 0.0
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	case AggregationMax:
+		highest := values[0]
+		for _, value := range values[1:] {
+			highest = math.Max(highest, value)
+		}
+		return highest
+	}
+
+	// nearest rank, validated by the options
+	p, _ := percentile(aggregation)
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// expireSamples forgets the pods without samples in the last Window, the ones
+// that were deleted.
+func (t terminator) expireSamples(state *state, now time.Time) {
+	for _, key := range state.sampled.expire(now.Add(-t.options.Window)) {
+		delete(state.samples, key)
+	}
+}
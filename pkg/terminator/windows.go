@@ -0,0 +1,69 @@
+package terminator
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// windowsSpec tells whether the spec of pod says it runs Windows containers, by
+// its os or its node selector.
+func windowsSpec(pod *v1.Pod) bool {
+	if pod.Spec.OS != nil {
+		return pod.Spec.OS.Name == v1.Windows
+	}
+	return pod.Spec.NodeSelector[v1.LabelOSStable] == string(v1.Windows)
+}
+
+// windowsPod tells whether pod runs Windows containers, by its spec or by the
+// os label of its node. The os of a node is looked up once, as it can't
+// change, and never in ScopeNamespace, where nodes can't be read.
+func (t terminator) windowsPod(ctx context.Context, c *check, pod *v1.Pod) (bool, error) {
+	if windowsSpec(pod) {
+		return true, nil
+	}
+	if pod.Spec.OS != nil || pod.Spec.NodeName == "" || t.options.Scope == ScopeNamespace {
+		return false, nil
+	}
+
+	name := pod.Spec.NodeName
+	windows, err := c.windowsNodes.get(name, func() (interface{}, error) {
+		c.defaultsMu.Lock()
+		windows, ok := c.state.windowsNodes[name]
+		c.defaultsMu.Unlock()
+		if ok {
+			return windows, nil
+		}
+
+		node, err := t.clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		windows = node.Labels[v1.LabelOSStable] == string(v1.Windows)
+		c.defaultsMu.Lock()
+		c.state.windowsNodes[name] = windows
+		c.defaultsMu.Unlock()
+		return windows, nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return windows.(bool), nil
+}
+
+// unsignalable tells whether pod runs Windows containers with the exec action,
+// which has no kill to signal their process with. Those pods are not deleted
+// instead, as the exec action was chosen to keep them.
+func (t terminator) unsignalable(ctx context.Context, c *check, pod *v1.Pod) bool {
+	if _, ok := t.action.(execAction); !ok {
+		return false
+	}
+
+	windows, err := t.windowsPod(ctx, c, pod)
+	if err != nil {
+		t.operationalError(ctx, c, pod.Namespace, pod.Name, "could not get the os of pod %s: %s", pod.Name, err)
+	}
+	return windows
+}
@@ -0,0 +1,235 @@
+package terminator
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsfake "k8s.io/metrics/pkg/client/clientset/versioned/fake"
+)
+
+// windowsSummary is the /stats/summary of a Windows kubelet, with the private
+// working set and the committed memory as usage, but no rss.
+const windowsSummary = `{
+  "node": {"nodeName": "win-1"},
+  "pods": [{
+    "podRef": {"name": "iis", "namespace": "web", "uid": "iis"},
+    "containers": [{
+      "name": "iis",
+      "memory": {"time": "2026-10-14T06:00:00Z", "availableBytes": 130023424, "usageBytes": 1395864371, "workingSetBytes": 943718400, "pageFaults": 0, "majorPageFaults": 0}
+    }]
+  }]
+}`
+
+// linuxSummary is the /stats/summary of a Linux kubelet.
+const linuxSummary = `{
+  "node": {"nodeName": "linux-1"},
+  "pods": [{
+    "podRef": {"name": "api", "namespace": "web", "uid": "api"},
+    "containers": [{
+      "name": "api",
+      "memory": {"time": "2026-10-14T06:00:00Z", "usageBytes": 734003200, "workingSetBytes": 629145600, "rssBytes": 524288000}
+    }]
+  }]
+}`
+
+func windowsNode(name, os string) *v1.Node {
+	return &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{v1.LabelOSStable: os}}}
+}
+
+func windowsTestPod(name, node string) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "web"},
+		Spec: v1.PodSpec{
+			NodeName: node,
+			Containers: []v1.Container{{
+				Name:      name,
+				Resources: v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}},
+			}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+}
+
+// windowsCluster returns a terminator over a Windows and a Linux node, with the
+// metrics-server reporting the working set of the iis pod, and a check with
+// the summaries of both kubelets.
+func windowsCluster(t *testing.T, options Options, opts ...Option) (terminator, *check, *fake.Clientset) {
+	clientset := fake.NewSimpleClientset(windowsNode("win-1", "windows"), windowsNode("linux-1", "linux"))
+
+	mc := metricsfake.NewSimpleClientset()
+	mc.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.GetAction).GetName() != "iis" {
+			return false, nil, nil
+		}
+		return true, &v1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: "iis", Namespace: "web"},
+			Timestamp:  metav1.Now(),
+			Containers: []v1beta1.ContainerMetrics{{
+				Name:  "iis",
+				Usage: v1.ResourceList{v1.ResourceMemory: resource.MustParse("900Mi")},
+			}},
+		}, nil
+	})
+
+	options.Workers = 1
+	options.Quiet = true
+	debug := logrus.New()
+	debug.SetOutput(io.Discard)
+	created, err := NewForClients(clientset, mc, options, append(opts, WithLogger(logrus.NewEntry(debug), log.New(io.Discard, "", 0)))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminator := created.(terminator)
+
+	c, err := terminator.newCheck(context.Background(), newState(), nil, terminator.defaultPolicy(95, 1), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for node, payload := range map[string]string{"win-1": windowsSummary, "linux-1": linuxSummary} {
+		var summary kubeletSummary
+		if err := json.Unmarshal([]byte(payload), &summary); err != nil {
+			t.Fatal(err)
+		}
+		c.nodeStats.put(node, map[string]kubeletPodStats{"web/" + summary.Pods[0].PodRef.Name: summary.Pods[0]})
+	}
+	return terminator, c, clientset
+}
+
+func TestWindowsSpec(t *testing.T) {
+	windows := &v1.PodOS{Name: v1.Windows}
+	linux := &v1.PodOS{Name: v1.Linux}
+	tests := []struct {
+		name     string
+		spec     v1.PodSpec
+		expected bool
+	}{
+		{"os", v1.PodSpec{OS: windows}, true},
+		{"node selector", v1.PodSpec{NodeSelector: map[string]string{v1.LabelOSStable: "windows"}}, true},
+		{"os over node selector", v1.PodSpec{OS: linux, NodeSelector: map[string]string{v1.LabelOSStable: "windows"}}, false},
+		{"linux node selector", v1.PodSpec{NodeSelector: map[string]string{v1.LabelOSStable: "linux"}}, false},
+		{"unset", v1.PodSpec{}, false},
+	}
+	for _, test := range tests {
+		if windows := windowsSpec(&v1.Pod{Spec: test.spec}); windows != test.expected {
+			t.Errorf("%s: expected windows %v, got %v", test.name, test.expected, windows)
+		}
+	}
+}
+
+// TestWindowsSummary checks the summary of a Windows kubelet decodes without
+// an rss, which the rss memory metric would count as nothing.
+func TestWindowsSummary(t *testing.T) {
+	var summary kubeletSummary
+	if err := json.Unmarshal([]byte(windowsSummary), &summary); err != nil {
+		t.Fatal(err)
+	}
+
+	memory := summary.Pods[0].Containers[0].Memory
+	if memory == nil || memory.UsageBytes == nil {
+		t.Fatalf("expected the usage of the iis container, got %+v", memory)
+	}
+	if memory.RSSBytes != nil {
+		t.Errorf("expected no rss on Windows, got %d", *memory.RSSBytes)
+	}
+}
+
+// TestWindowsPodMemory checks pods of Windows nodes are compared by the
+// working set from metrics-server with the rss memory metric, while Linux pods
+// still read their rss from the kubelet.
+func TestWindowsPodMemory(t *testing.T) {
+	terminator, c, clientset := windowsCluster(t, Options{MemoryMetric: MemoryMetricRSS})
+
+	using, err := terminator.podMemory(context.Background(), c, windowsTestPod("iis", "win-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := resource.MustParse("900Mi"); using == nil || using.Cmp(expected) != 0 {
+		t.Errorf("expected the working set of the Windows pod, 900Mi, got %v", using)
+	}
+
+	using, err = terminator.podMemory(context.Background(), c, windowsTestPod("api", "linux-1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if using == nil || using.Value() != 524288000 {
+		t.Errorf("expected the rss of the Linux pod, 524288000, got %v", using)
+	}
+
+	// the os of the nodes is looked up once
+	if _, err := terminator.podMemory(context.Background(), c, windowsTestPod("iis", "win-1")); err != nil {
+		t.Fatal(err)
+	}
+	gets := 0
+	for _, action := range clientset.Actions() {
+		if action.Matches("get", "nodes") {
+			gets++
+		}
+	}
+	if gets != 2 {
+		t.Errorf("expected each node to be looked up once, got %d lookups", gets)
+	}
+}
+
+// TestWindowsExecAction checks Windows pods are not killed by the exec action,
+// which can't signal them.
+func TestWindowsExecAction(t *testing.T) {
+	terminator, c, _ := windowsCluster(t, Options{}, WithAction(execAction{}))
+
+	windowsPod := windowsTestPod("iis", "")
+	windowsPod.Spec.OS = &v1.PodOS{Name: v1.Windows}
+	if !terminator.unsignalable(context.Background(), c, windowsPod) {
+		t.Error("expected the Windows pod not to be signaled")
+	}
+	if terminator.unsignalable(context.Background(), c, windowsTestPod("api", "linux-1")) {
+		t.Error("expected the exec action for the Linux pod")
+	}
+
+	terminator, c, _ = windowsCluster(t, Options{})
+	if terminator.unsignalable(context.Background(), c, windowsPod) {
+		t.Error("expected the Windows pod to be deleted by the delete action")
+	}
+}
+
+// TestWindowsNamespaceScope checks the os of pods is only told by their spec
+// in ScopeNamespace, without reading their nodes.
+func TestWindowsNamespaceScope(t *testing.T) {
+	terminator, c, clientset := windowsCluster(t, Options{Scope: ScopeNamespace})
+
+	selected := windowsTestPod("iis", "win-1")
+	selected.Spec.NodeSelector = map[string]string{v1.LabelOSStable: "windows"}
+	tests := []struct {
+		name     string
+		pod      *v1.Pod
+		expected bool
+	}{
+		{"node selector", selected, true},
+		{"node label", windowsTestPod("iis", "win-1"), false},
+		{"linux", windowsTestPod("api", "linux-1"), false},
+	}
+	for _, test := range tests {
+		windows, err := terminator.windowsPod(context.Background(), c, test.pod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if windows != test.expected {
+			t.Errorf("%s: expected windows %v, got %v", test.name, test.expected, windows)
+		}
+	}
+
+	for _, action := range clientset.Actions() {
+		if action.Matches("get", "nodes") {
+			t.Errorf("expected no node lookups in namespace scope, got %s", action.GetResource().Resource)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package terminator
+
+import (
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// workloadName returns the kind and name of the workload that owns pod, like
+// deployment/api. Pods owned by a ReplicaSet are attributed to its deployment by
+// trimming the pod template hash, and pods without an owner to themselves.
+func workloadName(pod *v1.Pod) string {
+	for _, owner := range pod.OwnerReferences {
+		if owner.Controller == nil || !*owner.Controller {
+			continue
+		}
+
+		if owner.Kind == "ReplicaSet" {
+			if hash, ok := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+				return "deployment/" + strings.TrimSuffix(owner.Name, "-"+hash)
+			}
+		}
+		return strings.ToLower(owner.Kind) + "/" + owner.Name
+	}
+
+	return "pod/" + pod.Name
+}
+
+// workloadKey identifies the workload of pod across namespaces.
+func workloadKey(pod *v1.Pod) string {
+	return pod.Namespace + "/" + workloadName(pod)
+}
+
+// splitNamespacedName splits a reference like namespace/name, returning empty
+// strings when it is not one.
+func splitNamespacedName(reference string) (namespace, name string) {
+	parts := strings.SplitN(reference, "/", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
+
+// onlyContainers returns a copy of pod with only its Containers, so the memory
+// of the others doesn't count, or pod itself when it has none of them.
+func (t terminator) onlyContainers(pod *v1.Pod) *v1.Pod {
+	if len(t.options.Containers) == 0 {
+		return pod
+	}
+
+	var containers []v1.Container
+	for _, container := range pod.Spec.Containers {
+		for _, name := range t.options.Containers {
+			if container.Name == name {
+				containers = append(containers, container)
+			}
+		}
+	}
+	if len(containers) == 0 || len(containers) == len(pod.Spec.Containers) {
+		return pod
+	}
+
+	only := pod.DeepCopy()
+	only.Spec.Containers = containers
+	return only
+}
@@ -0,0 +1,75 @@
+package terminator
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	v1 "k8s.io/api/core/v1"
+)
+
+var workloadPods = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "terminator_workload_pods",
+	Help: "Pods of the workload evaluated on the last check, with WorkloadMetrics",
+}, []string{"cluster", "namespace", "kind", "name"})
+
+var workloadPodsOverLimit = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "terminator_workload_pods_over_limit",
+	Help: "Pods of the workload over the limit on the last check, with WorkloadMetrics",
+}, []string{"cluster", "namespace", "kind", "name"})
+
+// workloadCount are the pods of a workload evaluated on a check, and the ones
+// over the limit. The kind and name of the workload are separate labels, as
+// label selectors of the external metrics of HPAs can't match a workload like
+// deployment/api.
+type workloadCount struct {
+	namespace string
+	kind      string
+	name      string
+	pods      int
+	over      int
+}
+
+// countWorkload counts pod as evaluated on c for the metrics of its workload,
+// and whether it is over the limit.
+func (t terminator) countWorkload(c *check, pod *v1.Pod, over bool) {
+	if !t.options.WorkloadMetrics {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := workloadKey(pod)
+	count, ok := c.workloads[key]
+	if !ok {
+		kind, name, _ := strings.Cut(workloadName(pod), "/")
+		count = &workloadCount{namespace: pod.Namespace, kind: kind, name: name}
+		c.workloads[key] = count
+	}
+	count.pods++
+	if over {
+		count.over++
+	}
+}
+
+// publishWorkloads sets the metrics of the workloads evaluated on c, deleting
+// the ones of the workloads that had no pods on it, so an HPA scaling on them
+// doesn't keep reading the last value of a workload that is gone.
+func (t terminator) publishWorkloads(c *check) {
+	if !t.options.WorkloadMetrics {
+		return
+	}
+
+	for key, count := range c.state.workloads {
+		if _, ok := c.workloads[key]; !ok {
+			workloadPods.DeleteLabelValues(t.options.Cluster, count.namespace, count.kind, count.name)
+			workloadPodsOverLimit.DeleteLabelValues(t.options.Cluster, count.namespace, count.kind, count.name)
+		}
+	}
+	for _, count := range c.workloads {
+		workloadPods.WithLabelValues(t.options.Cluster, count.namespace, count.kind, count.name).Set(float64(count.pods))
+		workloadPodsOverLimit.WithLabelValues(t.options.Cluster, count.namespace, count.kind, count.name).Set(float64(count.over))
+	}
+	c.state.workloads = c.workloads
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/urfave/cli/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// outputFlag is the output format flag of the commands printing listings and
+// reports, defaulting to value.
+func outputFlag(value string) cli.Flag {
+	return &cli.StringFlag{Name: "output", Aliases: []string{"o"}, Value: value, Usage: "output format: table, json or yaml"}
+}
+
+// printer writes the listings and reports of a command to stdout in its output
+// format, so the CLI can be scripted. Values are written as a whole by
+// concurrent clusters.
+type printer struct {
+	format string
+	mu     *sync.Mutex
+	w      io.Writer
+}
+
+func newPrinter(ctx *cli.Context) (printer, error) {
+	format := ctx.String("output")
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+	default:
+		return printer{}, fmt.Errorf("invalid output %q, must be table, json or yaml", format)
+	}
+	return printer{format: format, mu: new(sync.Mutex), w: os.Stdout}, nil
+}
+
+// print writes value as JSON or as a YAML document, or its rows under header
+// as a table, without a header when it is nil.
+func (p printer) print(value interface{}, header []string, rows [][]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.format {
+	case outputJSON:
+		data, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", data)
+		return err
+	case outputYAML:
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "---\n%s", data)
+		return err
+	}
+
+	w := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"oomterminator/pkg/terminator"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	recommendFormatPatch = "patch"
+	recommendFormatVPA   = "vpa"
+)
+
+// recommend prints the suggested memory of the workloads of a recording, as
+// patches of the workloads or as VerticalPodAutoscalers.
+func recommend(ctx *cli.Context) error {
+	format := ctx.String("format")
+	// --output vpa is short for --format vpa, printed as YAML documents
+	if ctx.String("output") == recommendFormatVPA {
+		if ctx.IsSet("format") && format != recommendFormatVPA {
+			return fmt.Errorf("output vpa can't be used with format %s", format)
+		}
+		if err := ctx.Set("output", outputYAML); err != nil {
+			return err
+		}
+		format = recommendFormatVPA
+	}
+	if format != recommendFormatPatch && format != recommendFormatVPA {
+		return fmt.Errorf("invalid format %q, must be patch or vpa", format)
+	}
+
+	printer, err := newPrinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(ctx.String("file"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	recommendations, err := terminator.Recommend(file, ctx.String("aggregation"), ctx.String("limit-aggregation"), ctx.Int("headroom"))
+	if err != nil {
+		return err
+	}
+
+	var objects []map[string]interface{}
+	var rows [][]string
+	for _, recommendation := range recommendations {
+		kind, name, _ := strings.Cut(recommendation.Workload, "/")
+		apiVersionKind, ok := terminator.WorkloadKinds[kind]
+		if !ok {
+			log.Printf("skipping %s, can't recommend the memory of a %s", recommendation.Workload, kind)
+			continue
+		}
+
+		var object map[string]interface{}
+		if format == recommendFormatVPA {
+			object = vpaObject(recommendation, apiVersionKind, name)
+		} else {
+			object = patchObject(recommendation, apiVersionKind, name)
+		}
+
+		// the YAML documents keep the amount of samples as a comment, so
+		// they can be applied as they are
+		if printer.format == outputYAML {
+			data, err := yaml.Marshal(object)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("# %d samples\n%s---\n", recommendation.Samples, data)
+			continue
+		}
+
+		objects = append(objects, object)
+		for _, container := range recommendation.Containers {
+			rows = append(rows, []string{recommendation.Namespace, recommendation.Workload, container.Name, container.Request.String(), container.Limit.String(), strconv.Itoa(recommendation.Samples)})
+		}
+	}
+	if printer.format == outputYAML {
+		return nil
+	}
+	return printer.print(objects, []string{"NAMESPACE", "WORKLOAD", "CONTAINER", "REQUEST", "LIMIT", "SAMPLES"}, rows)
+}
+
+// patchObject is a patch of the workload setting the recommended memory of its
+// containers.
+func patchObject(recommendation terminator.Recommendation, apiVersionKind [2]string, name string) map[string]interface{} {
+	containers := make([]interface{}, 0, len(recommendation.Containers))
+	for _, container := range recommendation.Containers {
+		containers = append(containers, map[string]interface{}{
+			"name": container.Name,
+			"resources": map[string]interface{}{
+				"requests": map[string]interface{}{"memory": container.Request.String()},
+				"limits":   map[string]interface{}{"memory": container.Limit.String()},
+			},
+		})
+	}
+
+	return map[string]interface{}{
+		"apiVersion": apiVersionKind[0],
+		"kind":       apiVersionKind[1],
+		"metadata":   map[string]interface{}{"name": name, "namespace": recommendation.Namespace},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": containers},
+			},
+		},
+	}
+}
+
+// vpaObject is a VerticalPodAutoscaler of the workload bounding the memory of
+// its containers by the recommendation, not updating its pods until its mode
+// is changed.
+func vpaObject(recommendation terminator.Recommendation, apiVersionKind [2]string, name string) map[string]interface{} {
+	policies := make([]interface{}, 0, len(recommendation.Containers))
+	for _, container := range recommendation.Containers {
+		policies = append(policies, map[string]interface{}{
+			"containerName":       container.Name,
+			"controlledResources": []string{"memory"},
+			"minAllowed":          map[string]interface{}{"memory": container.Request.String()},
+			"maxAllowed":          map[string]interface{}{"memory": container.Limit.String()},
+		})
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "autoscaling.k8s.io/v1",
+		"kind":       "VerticalPodAutoscaler",
+		"metadata":   map[string]interface{}{"name": name, "namespace": recommendation.Namespace},
+		"spec": map[string]interface{}{
+			"targetRef":      map[string]interface{}{"apiVersion": apiVersionKind[0], "kind": apiVersionKind[1], "name": name},
+			"updatePolicy":   map[string]interface{}{"updateMode": "Off"},
+			"resourcePolicy": map[string]interface{}{"containerPolicies": policies},
+		},
+	}
+}
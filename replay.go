@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+	"oomterminator/pkg/terminator"
+)
+
+// record writes the targeted pods and their memory usage to the file of each
+// cluster, named after its context when there are several.
+func record(ctx *cli.Context) error {
+	terminators, err := setup(ctx, terminator.Options{})
+	if err != nil {
+		return err
+	}
+
+	recorders := make(map[string]terminator.Terminator, len(terminators))
+	for cluster, t := range terminators {
+		path := ctx.String("file")
+		if len(terminators) > 1 {
+			path = cluster + "-" + path
+		}
+		recorders[cluster] = recorder{Terminator: t, path: path}
+	}
+
+	sleep := time.Millisecond * time.Duration(ctx.Int("sleep"))
+	targets, err := targetsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+	info("Recording pods%s", targets)
+	return runClusters(recorders, func(t terminator.Terminator) error {
+		file, err := os.Create(t.(recorder).path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return t.Record(ctx.Context, targets, sleep, file)
+	})
+}
+
+// recorder is the Terminator of a cluster and the file it records to.
+type recorder struct {
+	terminator.Terminator
+	path string
+}
+
+// replay runs the decisions of the decision flags against a recording.
+func replay(ctx *cli.Context) error {
+	printer, err := newPrinter(ctx)
+	if err != nil {
+		return err
+	}
+
+	options := terminator.Options{Workers: ctx.Int("workers"), Quiet: ctx.Bool("quiet"), Color: colored(ctx)}
+	if err := decisionOptions(ctx, &options); err != nil {
+		return err
+	}
+
+	file, err := os.Open(ctx.String("file"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	setupOutput(ctx)
+	killSleep := time.Millisecond * time.Duration(ctx.Int("kill-sleep"))
+	report, err := terminator.Replay(ctx.Context, file, options, ctx.Int("limit"), ctx.Int("kill-after"), killSleep)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, 0, len(report.Kills))
+	for _, kill := range report.Kills {
+		rows = append(rows, []string{kill.Time.Format(time.RFC3339), kill.Namespace, kill.Pod})
+	}
+	if err := printer.print(report, []string{"TIME", "NAMESPACE", "POD"}, rows); err != nil {
+		return err
+	}
+	info("replayed %d checks, %d pods would have been killed", report.Checks, len(report.Kills))
+	return nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"oomterminator/pkg/terminator"
+)
+
+// selfTargets are the targets of the self mode, only the pod the terminator
+// runs in, by the POD_NAME and POD_NAMESPACE environment variables set from
+// the Downward API.
+func selfTargets() (terminator.Targets, error) {
+	name, namespace := os.Getenv("POD_NAME"), os.Getenv("POD_NAMESPACE")
+	if name == "" || namespace == "" {
+		return terminator.Targets{}, errors.New("self needs the POD_NAME and POD_NAMESPACE environment variables, set from the Downward API")
+	}
+	return terminator.Targets{Namespace: namespace, Pods: []string{name}}, nil
+}
@@ -0,0 +1,57 @@
+//go:build !windows
+
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"oomterminator/pkg/terminator"
+)
+
+// handleSignals reloads the policy file of terminators on SIGHUP and dumps
+// their state on SIGUSR1, until ctx is done.
+func handleSignals(ctx context.Context, policyFile string, terminators []terminator.Terminator) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGUSR1)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case sig := <-signals:
+			switch sig {
+			case syscall.SIGHUP:
+				reloadPolicies(policyFile, terminators)
+			case syscall.SIGUSR1:
+				for _, t := range terminators {
+					t.Dump()
+				}
+			}
+		}
+	}
+}
+
+// reloadPolicies re-reads the policy file. An invalid file is logged and the
+// previous policies are kept.
+func reloadPolicies(policyFile string, terminators []terminator.Terminator) {
+	if policyFile == "" {
+		log.Print("Nothing to reload, no policy-file is set")
+		return
+	}
+
+	policies, err := terminator.LoadPolicyFile(policyFile)
+	if err != nil {
+		log.Printf("Could not reload the policy file, keeping the previous one: %s", err)
+		return
+	}
+
+	for _, t := range terminators {
+		t.SetPolicies(policies)
+	}
+	log.Printf("Reloaded the policy file %s", policyFile)
+}
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"context"
+
+	"oomterminator/pkg/terminator"
+)
+
+// handleSignals does nothing, there is no SIGHUP nor SIGUSR1 on windows.
+func handleSignals(ctx context.Context, policyFile string, terminators []terminator.Terminator) {}